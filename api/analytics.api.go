@@ -0,0 +1,57 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskAnalytics computes the time a task spent in each status and its
+// lead and cycle times
+func GetTaskAnalytics(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionView)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	analytics, err := store.TaskAnalytics(storeContext(c), task)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error computing task analytics", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task analytics retrieved successfully", fiber.StatusOK, analytics))
+}
+
+// GetTasksAnalytics aggregates the analytics of the logged-in user's tasks
+// and of the tasks shared with them. ?group_by=category (default) or
+// ?group_by=user groups them per category or per owner.
+func GetTasksAnalytics(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	groupBy := c.Query("group_by", types.AnalyticsByCategory)
+	if groupBy != types.AnalyticsByCategory && groupBy != types.AnalyticsByUser {
+		apiError := types.ErrBadRequest("group_by must be category or user")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	report, err := store.TaskAnalyticsReport(storeContext(c), userId, groupBy)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error computing task analytics", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task analytics retrieved successfully", fiber.StatusOK, report))
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskAssignees lists the users a task is assigned to
+func GetTaskAssignees(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionView)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	users, err := store.ListAssignees(storeContext(c), task)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching assignees", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Assignees retrieved successfully", fiber.StatusOK, users))
+}
+
+// AssignTask assigns a task to the user owning the email in the body, who is
+// notified unless they assigned it to themselves. Anyone who can edit the
+// task can assign it.
+func AssignTask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	var request types.AssignRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	user, err := store.User.FindByEmail(strings.TrimSpace(request.Email))
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("User")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error finding user", http.StatusInternalServerError, nil))
+	}
+	if !task.HasAssignee(user.Id) && len(task.Assignees) >= types.MaxTaskAssignees {
+		apiError := types.ErrBadRequest(fmt.Sprintf("a task can have at most %d assignees", types.MaxTaskAssignees))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	assigned, err := store.AssignTask(storeContext(c), task, user.Id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error assigning task", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task assigned successfully", fiber.StatusOK, assigned))
+}
+
+// UnassignTask takes a user off a task. Besides the users who can edit the
+// task, assignees can take themselves off it.
+func UnassignTask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	access := types.PermissionEdit
+	if isLoggedInUser(c, userId) {
+		access = types.PermissionView
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, access); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	task, err := store.Tasks.Unassign(storeContext(c), id, userId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Assignee")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error unassigning task", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task unassigned successfully", fiber.StatusOK, task))
+}
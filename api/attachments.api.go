@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/storage"
+	"golang-auth/types"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxFilenameLen is the longest file name kept for an attachment, in bytes.
+const maxFilenameLen = 255
+
+// GetNoteAttachments lists the files attached to a note
+func GetNoteAttachments(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listAttachments(c, store, types.ShareNote, id)
+}
+
+// UploadNoteAttachment attaches the file sent in the "file" field of a
+// multipart form to a note
+func UploadNoteAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return uploadAttachment(c, store, types.ShareNote, id)
+}
+
+// DownloadNoteAttachment sends the contents of a file attached to a note
+func DownloadNoteAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return downloadAttachment(c, store, types.ShareNote, id)
+}
+
+// DeleteNoteAttachment removes a file attached to a note
+func DeleteNoteAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return deleteAttachment(c, store, types.ShareNote, id)
+}
+
+// GetTaskAttachments lists the files attached to a task
+func GetTaskAttachments(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listAttachments(c, store, types.ShareTask, id)
+}
+
+// UploadTaskAttachment attaches the file sent in the "file" field of a
+// multipart form to a task
+func UploadTaskAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return uploadAttachment(c, store, types.ShareTask, id)
+}
+
+// DownloadTaskAttachment sends the contents of a file attached to a task
+func DownloadTaskAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return downloadAttachment(c, store, types.ShareTask, id)
+}
+
+// DeleteTaskAttachment removes a file attached to a task
+func DeleteTaskAttachment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return deleteAttachment(c, store, types.ShareTask, id)
+}
+
+func listAttachments(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	attachments, err := store.Attachments.ListByItem(storeContext(c), itemType, itemId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching attachments", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Attachments retrieved successfully", fiber.StatusOK, attachments))
+}
+
+// uploadAttachment checks the size of the uploaded file and detects its type
+// from the first bytes of its contents; the type claimed by the client is
+// ignored.
+func uploadAttachment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		apiError := types.ErrBadRequest("file is required")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if file.Size == 0 {
+		apiError := types.ErrBadRequest("file is empty")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if file.Size > store.AttachmentMaxSize {
+		apiError := types.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d bytes", store.AttachmentMaxSize))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	body, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error reading file", http.StatusInternalServerError, nil))
+	}
+	defer body.Close()
+
+	// http.DetectContentType looks at no more than 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error reading file", http.StatusInternalServerError, nil))
+	}
+	head = head[:n]
+	contentType := sniffContentType(head)
+	if !slices.Contains(store.AttachmentTypes, contentType) {
+		apiError := types.NewError(fiber.StatusUnsupportedMediaType, fmt.Sprintf("file type %s is not allowed", contentType))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	attachment, err := store.AddAttachment(storeContext(c), &types.Attachment{
+		ItemType:    itemType,
+		ItemID:      itemId,
+		Filename:    attachmentFilename(file.Filename),
+		ContentType: contentType,
+		Size:        file.Size,
+	}, io.MultiReader(bytes.NewReader(head), body))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error saving attachment", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Attachment uploaded successfully", fiber.StatusCreated, attachment))
+}
+
+// downloadAttachment always sends the file as a download so browsers never
+// render uploaded content in the API's origin.
+func downloadAttachment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	attachmentId, err := primitive.ObjectIDFromHex(c.Params("attachmentId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	attachment, err := store.Attachments.Get(storeContext(c), itemType, itemId, attachmentId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Attachment")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching attachment", http.StatusInternalServerError, nil))
+	}
+
+	body, err := store.OpenAttachment(storeContext(c), attachment)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Attachment contents")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error reading attachment", http.StatusInternalServerError, nil))
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderContentDisposition, disposition)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, "sandbox")
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	return c.Status(fiber.StatusOK).SendStream(body, int(attachment.Size))
+}
+
+func deleteAttachment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	attachmentId, err := primitive.ObjectIDFromHex(c.Params("attachmentId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := store.RemoveAttachment(storeContext(c), itemType, itemId, attachmentId); err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Attachment")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error deleting attachment", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Attachment deleted successfully", fiber.StatusOK, nil))
+}
+
+// sniffContentType detects the media type of a file from its first bytes,
+// without parameters such as the charset.
+func sniffContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// attachmentFilename keeps the last path element of the name sent by the
+// client, without control characters and at most maxFilenameLen bytes long.
+func attachmentFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	for len(name) > maxFilenameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
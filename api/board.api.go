@@ -0,0 +1,101 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskBoard retrieves the tasks of the logged-in user as a board, one
+// column per status in the order of their workflow
+func GetTaskBoard(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	board, err := store.TaskBoard(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching board", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Board retrieved successfully", fiber.StatusOK, board))
+}
+
+// MoveTaskOnBoard moves a task within its column of the board, or to another
+// column along with its status. Moves between columns follow the same rules
+// as status changes, and the column must be under its WIP limit.
+func MoveTaskOnBoard(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.BoardMoveRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if request.AfterID != nil && request.BeforeID != nil {
+		apiError := types.ErrBadRequest("give either after_id or before_id")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	workflow, err := store.TaskWorkflow(storeContext(c), task.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching workflow", http.StatusInternalServerError, nil))
+	}
+
+	var newStatus *types.Status
+	if request.Status != "" && request.Status != task.CurrentStatus {
+		if apiError := checkStatusChange(c, store, workflow, task, request.Status); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		comment, apiError := statusComment(request.StatusComment)
+		if apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		newStatus = &types.Status{
+			Status:         request.Status,
+			PreviousStatus: task.CurrentStatus,
+			Comment:        comment,
+			UserId:         c.Locals("userId").(string),
+		}
+	} else if request.StatusComment != "" {
+		apiError := types.ErrBadRequest("status_comment can only be given with a status change")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	moved, err := store.MoveOnBoard(storeContext(c), task, newStatus, request.AfterID, request.BeforeID)
+	if err != nil {
+		switch err {
+		case db.ErrWIPLimit:
+			apiError := types.ErrBadRequest(wipLimitMessage(workflow, request.Status))
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrBoardNeighbor:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrNotFound:
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error moving task", http.StatusInternalServerError, nil))
+	}
+
+	// Completing a recurring task schedules its next occurrence, and the
+	// move may complete the task's parents
+	if newStatus != nil && workflow.IsDone(newStatus.Status) && !workflow.IsDone(task.CurrentStatus) {
+		moved = store.Recur(storeContext(c), moved)
+	}
+	moved = store.AutoComplete(storeContext(c), moved)
+	return sendTaskWithBlocked(c, store, moved, "Task moved successfully")
+}
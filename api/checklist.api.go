@@ -0,0 +1,190 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddChecklistItem appends an item to the checklist of a task
+func AddChecklistItem(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	var request types.TaskChecklistItemRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if request.Text == nil {
+		apiError := types.ErrBadRequest("text is required")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	text, apiError := checklistText(*request.Text)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	item := &types.TaskChecklistItem{Id: primitive.NewObjectID(), Text: text}
+	if request.Done != nil && *request.Done {
+		markChecklistItem(c, item, true)
+	}
+	return saveChecklist(c, store, task, func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error) {
+		if len(checklist) >= types.MaxChecklistItems {
+			return nil, errChecklistFull
+		}
+		return append(slices.Clone(checklist), item), nil
+	}, fiber.StatusCreated, "Checklist item added successfully")
+}
+
+// UpdateChecklistItem changes the text of a checklist item or whether it is
+// done. Assignees who cannot edit the task can only tick items off and back.
+func UpdateChecklistItem(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	itemId, err := primitive.ObjectIDFromHex(c.Params("itemId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	var request types.TaskChecklistItemRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil && err.Error() == "unauthorized access to the task" {
+		task, err = checkTaskAssignee(c, store, id)
+		if err == nil && request.Text != nil {
+			err = fmt.Errorf("assignees can only tick off checklist items")
+		}
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	var text string
+	if request.Text != nil {
+		var apiError *types.Error
+		if text, apiError = checklistText(*request.Text); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+	}
+	return saveChecklist(c, store, task, func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error) {
+		index := slices.IndexFunc(checklist, func(item *types.TaskChecklistItem) bool { return item.Id == itemId })
+		if index < 0 {
+			return nil, errChecklistItemNotFound
+		}
+		item := *checklist[index]
+		if request.Text != nil {
+			item.Text = text
+		}
+		if request.Done != nil && *request.Done != item.Done {
+			markChecklistItem(c, &item, *request.Done)
+		}
+		checklist = slices.Clone(checklist)
+		checklist[index] = &item
+		return checklist, nil
+	}, fiber.StatusOK, "Checklist item updated successfully")
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task
+func DeleteChecklistItem(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	itemId, err := primitive.ObjectIDFromHex(c.Params("itemId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	return saveChecklist(c, store, task, func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error) {
+		kept := slices.DeleteFunc(slices.Clone(checklist), func(item *types.TaskChecklistItem) bool { return item.Id == itemId })
+		if len(kept) == len(checklist) {
+			return nil, errChecklistItemNotFound
+		}
+		return kept, nil
+	}, fiber.StatusOK, "Checklist item deleted successfully")
+}
+
+var (
+	errChecklistFull         = fmt.Errorf("a task can have at most %d checklist items", types.MaxChecklistItems)
+	errChecklistItemNotFound = errors.New("checklist item not found")
+)
+
+// saveChecklist applies change to the current checklist of a task, which may
+// complete it, and sends the task with its progress
+func saveChecklist(c *fiber.Ctx, store *db.Store, task *types.Tasks, change func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error), status int, message string) error {
+	updated, err := store.Tasks.UpdateChecklist(storeContext(c), task.Id, change)
+	if err != nil {
+		switch err {
+		case db.ErrNotFound:
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		case errChecklistItemNotFound:
+			apiError := types.ErrResourceNotFound("Checklist item")
+			return c.Status(apiError.Code).JSON(apiError)
+		case errChecklistFull:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrConcurrentUpdate:
+			return c.Status(fiber.StatusConflict).JSON(types.CreateErrorResponse(err.Error(), http.StatusConflict, nil))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error updating checklist", http.StatusInternalServerError, nil))
+	}
+	updated = store.AutoComplete(storeContext(c), updated)
+	if _, err := store.LoadSubtasks(storeContext(c), updated); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching subtasks", http.StatusInternalServerError, nil))
+	}
+	return c.Status(status).JSON(types.CreateSuccessResponse(message, status, updated))
+}
+
+// checklistText trims the text of a checklist item and checks its length
+func checklistText(text string) (string, *types.Error) {
+	text = strings.TrimSpace(text)
+	if text == "" || len([]rune(text)) > types.MaxChecklistTextLen {
+		apiError := types.ErrBadRequest(fmt.Sprintf("text must be 1 to %d characters", types.MaxChecklistTextLen))
+		return "", &apiError
+	}
+	return text, nil
+}
+
+// markChecklistItem ticks a checklist item off as done by the logged-in user,
+// or back
+func markChecklistItem(c *fiber.Ctx, item *types.TaskChecklistItem, done bool) {
+	item.Done = done
+	item.DoneAt, item.DoneBy = nil, nil
+	if !done {
+		return
+	}
+	doneAt := time.Now().UTC().Truncate(time.Millisecond)
+	item.DoneAt = &doneAt
+	if userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string)); err == nil {
+		item.DoneBy = &userId
+	}
+}
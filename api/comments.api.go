@@ -0,0 +1,235 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetNoteComments lists the comments on a note, each with its replies
+func GetNoteComments(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listComments(c, store, types.ShareNote, id)
+}
+
+// AddNoteComment comments on a note, or replies to one of its comments.
+// Anyone who can read the note can comment on it.
+func AddNoteComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return addComment(c, store, types.ShareNote, id)
+}
+
+// UpdateNoteComment edits a comment on a note. Only its author can edit it.
+func UpdateNoteComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return updateComment(c, store, types.ShareNote, id)
+}
+
+// DeleteNoteComment removes a comment on a note and the replies to it. Its
+// author can remove it, and so can the owner of the note.
+func DeleteNoteComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return deleteComment(c, store, types.ShareNote, id, func() error {
+		_, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner)
+		return err
+	})
+}
+
+// GetTaskComments lists the comments on a task, each with its replies
+func GetTaskComments(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listComments(c, store, types.ShareTask, id)
+}
+
+// AddTaskComment comments on a task, or replies to one of its comments.
+// Anyone who can read the task, assignees included, can comment on it.
+func AddTaskComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return addComment(c, store, types.ShareTask, id)
+}
+
+// UpdateTaskComment edits a comment on a task. Only its author can edit it.
+func UpdateTaskComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return updateComment(c, store, types.ShareTask, id)
+}
+
+// DeleteTaskComment removes a comment on a task and the replies to it. Its
+// author can remove it, and so can the owner of the task.
+func DeleteTaskComment(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return deleteComment(c, store, types.ShareTask, id, func() error {
+		_, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner)
+		return err
+	})
+}
+
+func listComments(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	comments, err := store.CommentThreads(storeContext(c), itemType, itemId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching comments", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Comments retrieved successfully", fiber.StatusOK, comments))
+}
+
+func addComment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.CommentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if err := request.Validate(); err != nil {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	comment, err := store.AddComment(storeContext(c), &types.Comment{
+		ItemType: itemType,
+		ItemID:   itemId,
+		ParentID: request.ParentID,
+		UserID:   userId,
+		Body:     request.Body,
+	})
+	if err != nil {
+		if err == db.ErrCommentParent {
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error adding comment", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Comment added successfully", fiber.StatusCreated, comment))
+}
+
+func updateComment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	comment, apiError := findComment(c, store, itemType, itemId)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if !isLoggedInUser(c, comment.UserID) {
+		apiError := types.ErrBadRequest("only the author of a comment can edit it")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.CommentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if err := request.Validate(); err != nil {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	updated, err := store.EditComment(storeContext(c), comment, request.Body)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Comment")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error updating comment", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Comment updated successfully", fiber.StatusOK, updated))
+}
+
+// deleteComment removes a comment when the logged-in user wrote it or
+// checkOwner passes.
+func deleteComment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID, checkOwner func() error) error {
+	comment, apiError := findComment(c, store, itemType, itemId)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if !isLoggedInUser(c, comment.UserID) {
+		if err := checkOwner(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+		}
+	}
+
+	if _, err := store.Comments.Delete(storeContext(c), itemType, itemId, comment.Id); err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Comment")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error deleting comment", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Comment deleted successfully", fiber.StatusOK, nil))
+}
+
+// findComment retrieves the comment named in the route from an item
+func findComment(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) (*types.Comment, *types.Error) {
+	commentId, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return nil, &apiError
+	}
+	comment, err := store.Comments.Get(storeContext(c), itemType, itemId, commentId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Comment")
+			return nil, &apiError
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error fetching comment")
+		return nil, &apiError
+	}
+	return comment, nil
+}
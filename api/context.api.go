@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+	"golang-auth/db"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storeContext returns the request context annotated with the logged-in user,
+// so the store can record who created or updated a document.
+func storeContext(c *fiber.Ctx) context.Context {
+	ctx := context.Context(c.Context())
+	if idParam, ok := c.Locals("userId").(string); ok {
+		if userId, err := primitive.ObjectIDFromHex(idParam); err == nil {
+			ctx = db.WithActor(ctx, userId)
+		}
+	}
+	return ctx
+}
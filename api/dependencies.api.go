@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskDependencies lists the tasks a task waits for and the tasks waiting
+// for it
+func GetTaskDependencies(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionView)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	dependencies, err := store.TaskDependencies(storeContext(c), task)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching dependencies", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Dependencies retrieved successfully", fiber.StatusOK, dependencies))
+}
+
+// AddTaskDependency makes a task wait for the task in blocker_id. The user
+// must be able to edit the task and to see the blocker, which must belong to
+// the same owner.
+func AddTaskDependency(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.DependencyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	blocker, err := CheckTaskAuthorization(c, store, request.BlockerID, types.PermissionView)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Blocker task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	if len(task.BlockedBy) >= types.MaxTaskBlockers {
+		apiError := types.ErrBadRequest(fmt.Sprintf("a task can be blocked by at most %d tasks", types.MaxTaskBlockers))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	updated, err := store.AddDependency(storeContext(c), task, blocker)
+	if err != nil {
+		switch err {
+		case db.ErrDependencyCycle, db.ErrDependencyOwner:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrNotFound:
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error adding dependency", http.StatusInternalServerError, nil))
+	}
+	return sendTaskWithBlocked(c, store, updated, "Dependency added successfully")
+}
+
+// RemoveTaskDependency stops a task from waiting for another one
+func RemoveTaskDependency(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	blockerId, err := primitive.ObjectIDFromHex(c.Params("blockerId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	updated, err := store.Tasks.Unblock(storeContext(c), id, blockerId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Dependency")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error removing dependency", http.StatusInternalServerError, nil))
+	}
+	return sendTaskWithBlocked(c, store, updated, "Dependency removed successfully")
+}
+
+// GetDependencyGraph sends the dependency graph of the logged-in user's
+// tasks, blockers before the tasks they block
+func GetDependencyGraph(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID format",
+		})
+	}
+	graph, err := store.DependencyGraph(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching dependency graph", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Dependency graph retrieved successfully", fiber.StatusOK, graph))
+}
+
+// sendTaskWithBlocked sends a task along with whether it is blocked
+func sendTaskWithBlocked(c *fiber.Ctx, store *db.Store, task *types.Tasks, message string) error {
+	if err := store.LoadBlocked(storeContext(c), task); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching dependencies", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse(message, fiber.StatusOK, task))
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// minLinkPasswordLen is the shortest password accepted for a public link.
+const minLinkPasswordLen = 8
+
+// publicNotePage is the standalone page served for ?format=html. The body is
+// rendered by utils.RenderNote, which escapes plain notes and sanitizes the
+// HTML of markdown notes.
+var publicNotePage = template.Must(template.New("note").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+</head>
+<body>
+<article>
+<h1>{{.Title}}</h1>
+{{if .Category}}<p><small>{{.Category}}</small></p>{{end}}
+{{.Body}}
+</article>
+</body>
+</html>
+`))
+
+// GetNoteLinks lists the public links of a note
+func GetNoteLinks(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	links, err := store.NoteLinks.ListByNote(storeContext(c), id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching links", http.StatusInternalServerError, nil))
+	}
+	hideLinkPasswords(links...)
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Links retrieved successfully", fiber.StatusOK, links))
+}
+
+// CreateNoteLink publishes a note under a new unguessable link, optionally
+// protected by a password and with an expiry time
+func CreateNoteLink(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.NoteLinkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		apiError := types.ErrBadRequest("expires_at must be in the future")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if request.Password != "" && len(request.Password) < minLinkPasswordLen {
+		apiError := types.ErrBadRequest(fmt.Sprintf("password must be at least %d characters", minLinkPasswordLen))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	link := &types.NoteLink{NoteID: id}
+	if request.ExpiresAt != nil {
+		expiresAt := request.ExpiresAt.UTC().Truncate(time.Millisecond)
+		link.ExpiresAt = &expiresAt
+	}
+	if request.Password != "" {
+		link.PasswordHash, err = utils.HashPassword(request.Password)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error creating link", http.StatusInternalServerError, nil))
+		}
+	}
+	link, err = store.NoteLinks.Create(storeContext(c), link)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error creating link", http.StatusInternalServerError, nil))
+	}
+	hideLinkPasswords(link)
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Link created successfully", fiber.StatusCreated, link))
+}
+
+// DeleteNoteLink revokes a public link; it stops working right away
+func DeleteNoteLink(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	linkId, err := primitive.ObjectIDFromHex(c.Params("linkId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	if _, err := store.NoteLinks.Delete(storeContext(c), id, linkId); err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Link")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error revoking link", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Link revoked successfully", fiber.StatusOK, nil))
+}
+
+// hideLinkPasswords clears the password hashes of links before they are
+// sent to a client
+func hideLinkPasswords(links ...*types.NoteLink) {
+	for _, link := range links {
+		link.PasswordHash = ""
+	}
+}
+
+// GetPublicNote shows the note behind a public link to anyone holding the
+// token. Password protected links expect the password in the X-Link-Password
+// header; after db.MaxLinkPasswordAttempts wrong ones in a row the link
+// refuses every password for db.LinkLockout. The note is returned as JSON, or
+// as an HTML page with ?format=html. Each successful request counts as a view.
+func GetPublicNote(c *fiber.Ctx, store *db.Store) error {
+	ctx := storeContext(c)
+	link, err := store.NoteLinks.GetByToken(ctx, c.Params("token"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error retrieving note", http.StatusInternalServerError, nil))
+	}
+	now := store.Now()
+	if link.Expired(now) {
+		apiError := types.NewError(fiber.StatusGone, "link has expired")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if link.Protected {
+		if apiError := checkLinkPassword(c, store, link, now); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+	}
+
+	note, err := store.Notes.Get(ctx, link.NoteID)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error retrieving note", http.StatusInternalServerError, nil))
+	}
+	link, err = store.NoteLinks.RecordView(ctx, link.Id)
+	if err != nil {
+		// The link was revoked in the meantime
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error retrieving note", http.StatusInternalServerError, nil))
+	}
+
+	public := types.PublicNote{
+		Title:     note.Title,
+		Category:  note.Category,
+		Note:      note.Note,
+		Format:    note.Format,
+		HTML:      utils.RenderNote(note.Format, note.Note).HTML,
+		UpdatedAt: note.UpdatedAt,
+		Views:     link.Views,
+	}
+	c.Set("Cache-Control", "no-store")
+	c.Set("X-Robots-Tag", "noindex")
+	if strings.EqualFold(c.Query("format"), "html") {
+		c.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+		c.Type("html", "utf-8")
+		return publicNotePage.Execute(c.Response().BodyWriter(), map[string]any{
+			"Title":    public.Title,
+			"Category": public.Category,
+			"Body":     template.HTML(public.HTML),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note retrieved successfully", fiber.StatusOK, public))
+}
+
+// checkLinkPassword returns an error unless the X-Link-Password header holds
+// the password of a link that is not locked. Wrong passwords are counted, and
+// the one that locks the link is answered as if the link was already locked.
+func checkLinkPassword(c *fiber.Ctx, store *db.Store, link *types.NoteLink, now time.Time) *types.Error {
+	if !link.Locked(now) {
+		if utils.CheckPassword(c.Get("X-Link-Password"), link.PasswordHash) {
+			return nil
+		}
+		var err error
+		link, err = store.NoteLinks.RecordFailedAttempt(storeContext(c), link.Id, now)
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note")
+			return &apiError
+		}
+		if err != nil {
+			apiError := types.NewError(fiber.StatusInternalServerError, "Error checking the password")
+			return &apiError
+		}
+		if !link.Locked(now) {
+			apiError := types.NewError(fiber.StatusUnauthorized, "a valid password is required to open this link")
+			return &apiError
+		}
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(link.LockedUntil.Sub(now).Seconds()))))
+	apiError := types.NewError(fiber.StatusTooManyRequests, "too many wrong passwords, try again later")
+	return &apiError
+}
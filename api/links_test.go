@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"golang-auth/db"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetPublicNoteLocksOutWrongPasswords(t *testing.T) {
+	ctx := context.Background()
+	store, err := db.NewSQLStore("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	store.Clock = func() time.Time { return now }
+	note, err := store.Notes.Create(ctx, &types.NotesCreate{Title: "Shared", UserID: primitive.NewObjectID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := utils.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := store.NoteLinks.Create(ctx, &types.NoteLink{NoteID: note.Id, PasswordHash: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/public/notes/:token", func(c *fiber.Ctx) error {
+		return GetPublicNote(c, store)
+	})
+	open := func(password string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodGet, "/public/notes/"+link.Token, nil)
+		req.Header.Set("X-Link-Password", password)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter)
+	}
+
+	for i := 1; i < db.MaxLinkPasswordAttempts; i++ {
+		if status, _ := open("wrong"); status != fiber.StatusUnauthorized {
+			t.Fatalf("wrong password %d returned %d, want 401", i, status)
+		}
+	}
+	if status, retryAfter := open("wrong"); status != fiber.StatusTooManyRequests || retryAfter != "900" {
+		t.Errorf("the last wrong password returned %d retrying after %q, want 429 after 900", status, retryAfter)
+	}
+	if status, _ := open("correct horse"); status != fiber.StatusTooManyRequests {
+		t.Errorf("the right password of a locked link returned %d, want 429", status)
+	}
+
+	now = now.Add(db.LinkLockout)
+	if status, _ := open("correct horse"); status != fiber.StatusOK {
+		t.Errorf("the right password after the lockout returned %d, want 200", status)
+	}
+}
+
+func TestCreateNoteLinkRejectsShortPasswords(t *testing.T) {
+	app := fiber.New()
+	// The password is checked before the store is used
+	app.Post("/notes/:id/links", func(c *fiber.Ctx) error {
+		return CreateNoteLink(c, nil)
+	})
+	req := httptest.NewRequest(fiber.MethodPost, "/notes/"+primitive.NewObjectID().Hex()+"/links", strings.NewReader(`{"password":"1234567"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("a 7 character password returned %d, want 400", resp.StatusCode)
+	}
+}
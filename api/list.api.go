@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseListQuery reads the pagination, sorting and filter parameters shared by
+// the list endpoints:
+//
+//	?limit=20&cursor=<next_cursor>&sort=-created_at&category=work&status=done&from=2024-01-01&to=2024-02-01&tags=<id>,<id>
+//
+// A leading "-" on sort means descending. from and to accept RFC 3339 times or
+// plain dates and filter on the creation time. tags keeps the items carrying
+// every listed tag.
+func parseListQuery(c *fiber.Ctx) (*types.ListQuery, error) {
+	query := &types.ListQuery{
+		Cursor:   c.Query("cursor"),
+		Category: c.Query("category"),
+		Status:   c.Query("status"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, types.ErrBadRequest("limit must be a positive number")
+		}
+		query.Limit = n
+	}
+
+	if tags := c.Query("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			id, err := primitive.ObjectIDFromHex(strings.TrimSpace(tag))
+			if err != nil {
+				return nil, types.ErrBadRequest("tags must be a comma separated list of tag IDs")
+			}
+			query.Tags = append(query.Tags, id)
+		}
+	}
+
+	sort := c.Query("sort")
+	if strings.HasPrefix(sort, "-") {
+		query.Desc = true
+		sort = strings.TrimPrefix(sort, "-")
+	}
+	query.SortBy = sort
+
+	var err error
+	if query.From, err = parseQueryTime(c.Query("from")); err != nil {
+		return nil, types.ErrBadRequest("from must be a date or an RFC 3339 time")
+	}
+	if query.To, err = parseQueryTime(c.Query("to")); err != nil {
+		return nil, types.ErrBadRequest("to must be a date or an RFC 3339 time")
+	}
+	return query, nil
+}
+
+func parseQueryTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// sendListError answers a failed list request, reporting invalid sort fields
+// and cursors as bad requests.
+func sendListError(c *fiber.Ctx, err error, message string) error {
+	if apiError, ok := err.(types.Error); ok {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if errors.Is(err, db.ErrInvalidListQuery) {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse(message, http.StatusInternalServerError, nil))
+}
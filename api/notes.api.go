@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"golang-auth/db"
 	"golang-auth/types"
+	"golang-auth/utils"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,11 +22,7 @@ func GetAllNotesForLoginUser(c *fiber.Ctx, store *db.Store) error {
 		})
 	}
 
-	notes, err := store.Notes.List(c.Context(), userId)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes", http.StatusInternalServerError, nil))
-	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Notes retrieved successfully", fiber.StatusOK, notes))
+	return listUserNotes(c, store, userId)
 }
 func GetAllNotesForUserById(c *fiber.Ctx, store *db.Store) error {
 	idParam := c.Params("id")
@@ -35,13 +32,39 @@ func GetAllNotesForUserById(c *fiber.Ctx, store *db.Store) error {
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	notes, err := store.Notes.List(c.Context(), userId)
+	return listUserNotes(c, store, userId)
+}
+
+// listUserNotes sends one page of the user's notes, see parseListQuery, each
+// with its comment count. ?render=html adds the rendered body to every note.
+func listUserNotes(c *fiber.Ctx, store *db.Store, userId primitive.ObjectID) error {
+	render, apiError := parseRender(c)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	query, err := parseListQuery(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes", http.StatusInternalServerError, nil))
+		return sendListError(c, err, "Error fetching notes")
 	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Notes retrieved successfully", fiber.StatusOK, notes))
+	notes, page, err := store.Notes.ListPage(storeContext(c), userId, query)
+	if err != nil {
+		return sendListError(c, err, "Error fetching notes")
+	}
+	if err := store.LoadNoteCommentCounts(storeContext(c), notes...); err != nil {
+		return sendListError(c, err, "Error fetching comments")
+	}
+	if render {
+		rendered := make([]*types.RenderedNote, len(notes))
+		for i, note := range notes {
+			rendered[i] = renderNote(note)
+		}
+		return c.Status(fiber.StatusOK).JSON(types.CreatePaginatedResponse("Notes retrieved successfully", fiber.StatusOK, rendered, page))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreatePaginatedResponse("Notes retrieved successfully", fiber.StatusOK, notes, page))
 }
 
+// GetSingleNote retrieves a note. ?render=html adds the body rendered as
+// sanitized HTML, its table of contents and checklist progress.
 func GetSingleNote(c *fiber.Ctx, store *db.Store) error {
 	idParam := c.Params("id")
 	id, err := primitive.ObjectIDFromHex(idParam)
@@ -49,15 +72,24 @@ func GetSingleNote(c *fiber.Ctx, store *db.Store) error {
 		apiError := types.ErrInvalidID()
 		return c.Status(apiError.Code).JSON(apiError)
 	}
+	render, apiError := parseRender(c)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
 
-	note, err := store.Notes.Get(c.Context(), id)
+	note, err := CheckNoteAuthorization(c, store, id, types.PermissionView)
 	if err != nil {
-		if err.Error() == "mongo: no documents in result" {
+		if err.Error() == "note not found" {
 			apiError := types.ErrResourceNotFound("Note")
 			return c.Status(apiError.Code).JSON(apiError)
 		}
-		apiError := types.NewError(fiber.StatusInternalServerError, "Error retrieving note")
-		return c.Status(apiError.Code).JSON(apiError)
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	if err := store.LoadNoteCommentCounts(storeContext(c), note); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching comments", http.StatusInternalServerError, nil))
+	}
+	if render {
+		return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note retrieved successfully", fiber.StatusOK, renderNote(note)))
 	}
 	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note retrieved successfully", fiber.StatusOK, note))
 }
@@ -74,13 +106,21 @@ func CreateNote(c *fiber.Ctx, store *db.Store) error {
 			"error": "Invalid user ID format",
 		})
 	}
+	if apiError := checkNoteFormat(note.Format); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if apiError := checkItemTags(c, store, userId, note.Tags); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
 	createNote := types.NotesCreate{
 		Title:    note.Title,
 		Category: note.Category,
 		Note:     note.Note,
+		Format:   note.Format,
+		Tags:     note.Tags,
 		UserID:   userId,
 	}
-	newNote, err := store.Notes.Create(c.Context(), &createNote)
+	newNote, err := store.Notes.Create(storeContext(c), &createNote)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error creating note", http.StatusInternalServerError, nil))
 	}
@@ -96,13 +136,13 @@ func DeleteNote(c *fiber.Ctx, store *db.Store) error {
 	}
 
 	// Check authorization
-	_, err = CheckNoteAuthorization(c, store, id)
+	_, err = CheckNoteAuthorization(c, store, id, types.PermissionOwner)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
 	}
 
-	// deletedNote, err := store.Notes.Delete(c.Context(), id)
-	_, err = store.Notes.Delete(c.Context(), id)
+	// deletedNote, err := store.Notes.Delete(storeContext(c), id)
+	_, err = store.Notes.Delete(storeContext(c), id)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			apiError := types.ErrResourceNotFound("Note")
@@ -111,7 +151,7 @@ func DeleteNote(c *fiber.Ctx, store *db.Store) error {
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error deleting note")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note deleted successfully", fiber.StatusOK, nil))
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note moved to trash", fiber.StatusOK, nil))
 }
 
 func UpdateNote(c *fiber.Ctx, store *db.Store) error {
@@ -129,7 +169,7 @@ func UpdateNote(c *fiber.Ctx, store *db.Store) error {
 	}
 
 	// Check authorization and retrieve the existing note
-	existingNote, err := CheckNoteAuthorization(c, store, id)
+	existingNote, err := CheckNoteAuthorization(c, store, id, types.PermissionEdit)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
 	}
@@ -139,6 +179,8 @@ func UpdateNote(c *fiber.Ctx, store *db.Store) error {
 		Title:    existingNote.Title,
 		Category: existingNote.Category,
 		Note:     existingNote.Note,
+		Format:   existingNote.Format,
+		Tags:     existingNote.Tags,
 	}
 
 	if updatedNote.Title != "" {
@@ -150,14 +192,30 @@ func UpdateNote(c *fiber.Ctx, store *db.Store) error {
 	if updatedNote.Note != "" {
 		modifiedNote.Note = updatedNote.Note
 	}
+	if updatedNote.Format != "" {
+		if apiError := checkNoteFormat(updatedNote.Format); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		modifiedNote.Format = updatedNote.Format
+	}
+	if updatedNote.Tags != nil {
+		if apiError := checkItemTags(c, store, existingNote.UserID, updatedNote.Tags); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		modifiedNote.Tags = updatedNote.Tags
+	}
 
-	// Update the note in the database
-	updatedNoteResult, err := store.Notes.Update(c.Context(), id, &modifiedNote)
+	// Update the note, keeping the previous version in its history
+	updatedNoteResult, err := store.UpdateNote(storeContext(c), id, &modifiedNote)
 	if err != nil {
-		if err.Error() == "no note found" {
+		if err == db.ErrNotFound || err.Error() == "no note found" {
 			apiError := types.ErrResourceNotFound("Note")
 			return c.Status(apiError.Code).JSON(apiError)
 		}
+		if err == db.ErrNoteVersionConflict {
+			apiError := types.NewError(fiber.StatusConflict, err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error updating note")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
@@ -167,9 +225,10 @@ func UpdateNote(c *fiber.Ctx, store *db.Store) error {
 }
 
 // for checking purpose  that only logged in user or user who's role is
-// admin can only delete not other user like if he using postman or swagger
+// admin can only delete not other user like if he using postman or swagger.
+// Users the note is shared with pass when their share grants the access asked for.
 
-func CheckNoteAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.ObjectID) (*types.Notes, error) {
+func CheckNoteAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.ObjectID, access types.Permission) (*types.Notes, error) {
 	// Retrieve the logged-in user's ID from the request context
 	// userId, ok := c.Locals("userId").(primitive.ObjectID)
 	// if !ok {
@@ -183,7 +242,7 @@ func CheckNoteAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.Obje
 	}
 
 	// Get the note by ID
-	note, err := store.Notes.Get(c.Context(), noteId)
+	note, err := store.Notes.Get(storeContext(c), noteId)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			return nil, fmt.Errorf("note not found")
@@ -191,12 +250,134 @@ func CheckNoteAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.Obje
 		return nil, fmt.Errorf("error retrieving note: %w", err)
 	}
 
-	// Check if the logged-in user is the owner of the note or an admin
+	// Check if the logged-in user is the owner of the note, an admin or shared the note
 	if c.Locals("role") != "admin" && note.UserID != userId {
-		return nil, fmt.Errorf("unauthorized access to the note")
+		shared, err := hasShareAccess(c, store, types.ShareNote, noteId, userId, access)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving note: %w", err)
+		}
+		if !shared {
+			return nil, fmt.Errorf("unauthorized access to the note")
+		}
 	}
 
 	// If all checks pass, return the note without an error
 	return note, nil
 
 }
+
+// GetNotesTrash lists the logged-in user's trashed notes
+func GetNotesTrash(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID format",
+		})
+	}
+
+	notes, err := store.Notes.ListTrash(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching trashed notes", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Trashed notes retrieved successfully", fiber.StatusOK, notes))
+}
+
+// RestoreNote takes a note out of the trash
+func RestoreNote(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	_, err = checkTrashedNoteAuthorization(c, store, id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	note, err := store.Notes.Restore(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed note")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error restoring note")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note restored successfully", fiber.StatusOK, note))
+}
+
+// PurgeNote permanently removes a trashed note
+func PurgeNote(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	_, err = checkTrashedNoteAuthorization(c, store, id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	_, err = store.PurgeNote(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed note")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error purging note")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note deleted permanently", fiber.StatusOK, nil))
+}
+
+// checkTrashedNoteAuthorization is the trash counterpart of CheckNoteAuthorization
+func checkTrashedNoteAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.ObjectID) (*types.Notes, error) {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID format")
+	}
+
+	note, err := store.Notes.GetTrashed(storeContext(c), noteId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, fmt.Errorf("note not found in trash")
+		}
+		return nil, fmt.Errorf("error retrieving note: %w", err)
+	}
+
+	if c.Locals("role") != "admin" && note.UserID != userId {
+		return nil, fmt.Errorf("unauthorized access to the note")
+	}
+	return note, nil
+}
+
+// checkNoteFormat rejects formats other than plain and markdown. An empty
+// format is allowed and means plain text.
+func checkNoteFormat(format string) *types.Error {
+	switch format {
+	case "", types.NoteFormatPlain, types.NoteFormatMarkdown:
+		return nil
+	}
+	apiError := types.ErrBadRequest("format must be plain or markdown")
+	return &apiError
+}
+
+// parseRender reads the optional ?render=html flag
+func parseRender(c *fiber.Ctx) (bool, *types.Error) {
+	switch c.Query("render") {
+	case "":
+		return false, nil
+	case "html":
+		return true, nil
+	}
+	apiError := types.ErrBadRequest("render must be html")
+	return false, &apiError
+}
+
+func renderNote(note *types.Notes) *types.RenderedNote {
+	return &types.RenderedNote{Notes: note, NoteRendering: utils.RenderNote(note.Format, note.Note)}
+}
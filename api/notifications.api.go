@@ -0,0 +1,77 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetNotifications lists the latest notifications of the logged-in user,
+// newest first. ?unread=true keeps the unread ones and ?limit caps how many
+// are returned.
+func GetNotifications(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	limit := db.DefaultListLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			apiError := types.ErrBadRequest("limit must be a positive number")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		limit = min(n, db.MaxListLimit)
+	}
+
+	notifications, err := store.Notifications.ListByUser(storeContext(c), userId, c.QueryBool("unread"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notifications", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Notifications retrieved successfully", fiber.StatusOK, notifications))
+}
+
+// ReadNotification marks a notification of the logged-in user as read
+func ReadNotification(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	notification, err := store.Notifications.MarkRead(storeContext(c), userId, id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Notification")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error updating notification", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Notification marked as read", fiber.StatusOK, notification))
+}
+
+// ReadAllNotifications marks every notification of the logged-in user as read
+func ReadAllNotifications(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	count, err := store.Notifications.MarkAllRead(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error updating notifications", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Notifications marked as read", fiber.StatusOK, fiber.Map{"read": count}))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SkipOccurrence moves an occurrence of a recurring task to the trash and
+// returns the next occurrence of the series, creating it when needed
+func SkipOccurrence(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	next, err := store.SkipOccurrence(storeContext(c), task)
+	if err != nil {
+		switch err {
+		case db.ErrNotRecurring:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrNotFound:
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error skipping occurrence", http.StatusInternalServerError, nil))
+	}
+	if next == nil {
+		return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Occurrence skipped; the series has ended", fiber.StatusOK, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Occurrence skipped", fiber.StatusOK, next))
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetNoteRevisions lists the earlier versions of a note, newest first
+func GetNoteRevisions(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	revisions, err := store.NoteRevisions.List(storeContext(c), id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching note revisions", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note revisions retrieved successfully", fiber.StatusOK, revisions))
+}
+
+// GetNoteRevision retrieves a single earlier version of a note
+func GetNoteRevision(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	version, err := c.ParamsInt("version")
+	if err != nil || version < 1 {
+		apiError := types.ErrBadRequest("version must be a positive number")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	revision, err := store.NoteRevisions.Get(storeContext(c), id, version)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note revision")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching note revision", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note revision retrieved successfully", fiber.StatusOK, revision))
+}
+
+// GetNoteDiff compares two versions of a note line by line:
+//
+//	?from=3&to=5
+//
+// to defaults to the current version and from to the version before to.
+func GetNoteDiff(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	from, err := parseVersion(c.Query("from"))
+	if err != nil {
+		apiError := types.ErrBadRequest("from must be a positive number")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	to, err := parseVersion(c.Query("to"))
+	if err != nil {
+		apiError := types.ErrBadRequest("to must be a positive number")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	note, err := CheckNoteAuthorization(c, store, id, types.PermissionView)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	if to == 0 {
+		to = note.Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	older, err := store.NoteVersion(storeContext(c), note, from)
+	if err != nil {
+		return sendNoteDiffError(c, err)
+	}
+	newer, err := store.NoteVersion(storeContext(c), note, to)
+	if err != nil {
+		return sendNoteDiffError(c, err)
+	}
+	diff := utils.DiffNote(older, newer)
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note diff retrieved successfully", fiber.StatusOK, diff))
+}
+
+// RestoreNoteRevision brings back an earlier version of a note as its newest version
+func RestoreNoteRevision(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	version, err := c.ParamsInt("version")
+	if err != nil || version < 1 {
+		apiError := types.ErrBadRequest("version must be a positive number")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionEdit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	note, err := store.RestoreNoteRevision(storeContext(c), id, version)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Note revision")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		if err == db.ErrNoteVersionConflict {
+			apiError := types.NewError(fiber.StatusConflict, err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error restoring note revision")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Note revision restored successfully", fiber.StatusOK, note))
+}
+
+// parseVersion reads an optional version number from the query string; an
+// empty value returns 0.
+func parseVersion(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, strconv.ErrSyntax
+	}
+	return version, nil
+}
+
+// sendNoteDiffError answers a diff request for a version that cannot be read
+func sendNoteDiffError(c *fiber.Ctx, err error) error {
+	if err == db.ErrNotFound {
+		apiError := types.ErrResourceNotFound("Note revision")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error comparing note versions", http.StatusInternalServerError, nil))
+}
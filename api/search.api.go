@@ -0,0 +1,66 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Search looks through the logged-in user's notes and tasks and the ones
+// shared with them:
+//
+//	/search?q=groceries&type=notes&category=home&from=2024-01-01&to=2024-02-01&limit=20
+//
+// Results are ranked by relevance and carry highlighted snippets of the
+// fields that matched.
+func Search(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	query := &types.SearchQuery{
+		Text:     c.Query("q"),
+		Type:     c.Query("type"),
+		Category: c.Query("category"),
+	}
+	if query.Text == "" {
+		apiError := types.ErrBadRequest("q is required")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if query.Type != "" && query.Type != "notes" && query.Type != "tasks" {
+		apiError := types.ErrBadRequest("type must be notes or tasks")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			apiError := types.ErrBadRequest("limit must be a positive number")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		query.Limit = n
+	}
+	if query.From, err = parseQueryTime(c.Query("from")); err != nil {
+		apiError := types.ErrBadRequest("from must be a date or an RFC 3339 time")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if query.To, err = parseQueryTime(c.Query("to")); err != nil {
+		apiError := types.ErrBadRequest("to must be a date or an RFC 3339 time")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	results, err := store.Search(storeContext(c), userId, query)
+	if err == db.ErrEmptySearch {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error searching notes and tasks", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Search completed successfully", fiber.StatusOK, results))
+}
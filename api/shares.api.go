@@ -0,0 +1,211 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetSharedWithMe lists the notes and tasks other users shared with the
+// logged-in user. ?type=notes or ?type=tasks keeps one kind of item.
+func GetSharedWithMe(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	var itemType string
+	switch c.Query("type") {
+	case "":
+	case "notes":
+		itemType = types.ShareNote
+	case "tasks":
+		itemType = types.ShareTask
+	default:
+		apiError := types.ErrBadRequest("type must be notes or tasks")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	items, err := store.SharedWith(storeContext(c), userId, itemType)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching shared items", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Shared items retrieved successfully", fiber.StatusOK, items))
+}
+
+// GetNoteShares lists the users a note is shared with
+func GetNoteShares(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listShares(c, store, types.ShareNote, id)
+}
+
+// ShareNote shares a note with the user owning the email in the body
+func ShareNote(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	note, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return createShare(c, store, types.ShareNote, id, note.UserID)
+}
+
+// UnshareNote stops sharing a note with a user. Besides the owner, users can
+// remove a note shared with themselves.
+func UnshareNote(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if !isLoggedInUser(c, userId) {
+		if _, err := CheckNoteAuthorization(c, store, id, types.PermissionOwner); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+		}
+	}
+	return deleteShare(c, store, types.ShareNote, id, userId)
+}
+
+// GetTaskShares lists the users a task is shared with
+func GetTaskShares(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return listShares(c, store, types.ShareTask, id)
+}
+
+// ShareTask shares a task with the user owning the email in the body
+func ShareTask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return createShare(c, store, types.ShareTask, id, task.UserID)
+}
+
+// UnshareTask stops sharing a task with a user. Besides the owner, users can
+// remove a task shared with themselves.
+func UnshareTask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if !isLoggedInUser(c, userId) {
+		if _, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+		}
+	}
+	return deleteShare(c, store, types.ShareTask, id, userId)
+}
+
+func listShares(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID) error {
+	shares, err := store.ListShares(storeContext(c), itemType, itemId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching shares", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Shares retrieved successfully", fiber.StatusOK, shares))
+}
+
+func createShare(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID, ownerId primitive.ObjectID) error {
+	var request types.ShareRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if request.Permission != types.PermissionView && request.Permission != types.PermissionEdit {
+		apiError := types.ErrBadRequest("permission must be view or edit")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	user, err := store.User.FindByEmail(strings.TrimSpace(request.Email))
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("User")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error finding user", http.StatusInternalServerError, nil))
+	}
+	if user.Id == ownerId {
+		apiError := types.ErrBadRequest("cannot share an item with its owner")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	share, err := store.Shares.Upsert(storeContext(c), &types.Share{
+		ItemType:   itemType,
+		ItemID:     itemId,
+		UserID:     user.Id,
+		Permission: request.Permission,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error sharing item", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Item shared successfully", fiber.StatusOK, share))
+}
+
+func deleteShare(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) error {
+	_, err := store.Shares.Delete(storeContext(c), itemType, itemId, userId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Share")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error removing share", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Share removed successfully", fiber.StatusOK, nil))
+}
+
+// hasShareAccess reports whether an item is shared with the user with at
+// least the given permission
+func hasShareAccess(c *fiber.Ctx, store *db.Store, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID, access types.Permission) (bool, error) {
+	if access == types.PermissionOwner {
+		return false, nil
+	}
+	share, err := store.Shares.Get(storeContext(c), itemType, itemId, userId)
+	if err == db.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return share.Permission.Allows(access), nil
+}
+
+func isLoggedInUser(c *fiber.Ctx, userId primitive.ObjectID) bool {
+	loggedIn, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	return err == nil && loggedIn == userId
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shareTest is an app serving the share endpoints, plus one route per item
+// type that reports whether the caller passes the authorization check for
+// the access in the path. The caller is the user in the X-User header.
+type shareTest struct {
+	t     *testing.T
+	store *db.Store
+	app   *fiber.App
+}
+
+func newShareTest(t *testing.T) *shareTest {
+	store, err := db.NewSQLStore("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userId", c.Get("X-User"))
+		c.Locals("role", "user")
+		return c.Next()
+	})
+	check := func(c *fiber.Ctx, authorize func(primitive.ObjectID, types.Permission) error) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		if err := authorize(id, types.Permission(c.Params("access"))); err != nil {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+	app.Get("/notes/:id/access/:access", func(c *fiber.Ctx) error {
+		return check(c, func(id primitive.ObjectID, access types.Permission) error {
+			_, err := CheckNoteAuthorization(c, store, id, access)
+			return err
+		})
+	})
+	app.Get("/tasks/:id/access/:access", func(c *fiber.Ctx) error {
+		return check(c, func(id primitive.ObjectID, access types.Permission) error {
+			_, err := CheckTaskAuthorization(c, store, id, access)
+			return err
+		})
+	})
+	app.Post("/notes/:id/shares", func(c *fiber.Ctx) error { return ShareNote(c, store) })
+	app.Delete("/notes/:id/shares/:userId", func(c *fiber.Ctx) error { return UnshareNote(c, store) })
+	app.Post("/tasks/:id/shares", func(c *fiber.Ctx) error { return ShareTask(c, store) })
+	app.Delete("/tasks/:id/shares/:userId", func(c *fiber.Ctx) error { return UnshareTask(c, store) })
+	return &shareTest{t: t, store: store, app: app}
+}
+
+func (s *shareTest) user(name string) primitive.ObjectID {
+	s.t.Helper()
+	user, err := s.store.User.Create(context.Background(), &types.UserCreate{Name: name, Email: name + "@example.com", Password: "password"})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return user.Id
+}
+
+// do sends a request as the given user and returns the status code.
+func (s *shareTest) do(as primitive.ObjectID, method, path, body string) int {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User", as.Hex())
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+// share shares an item as its owner.
+func (s *shareTest) share(owner primitive.ObjectID, items string, itemId primitive.ObjectID, with string, permission types.Permission) {
+	s.t.Helper()
+	body := fmt.Sprintf(`{"email":"%s@example.com","permission":"%s"}`, with, permission)
+	if status := s.do(owner, fiber.MethodPost, "/"+items+"/"+itemId.Hex()+"/shares", body); status != fiber.StatusOK {
+		s.t.Fatalf("sharing %s %s with %s returned %d", items, itemId.Hex(), with, status)
+	}
+}
+
+// checkAccess fails unless user passes the checks for exactly the accesses
+// in allowed.
+func (s *shareTest) checkAccess(who string, user primitive.ObjectID, items string, itemId primitive.ObjectID, allowed ...types.Permission) {
+	s.t.Helper()
+	for _, access := range []types.Permission{types.PermissionView, types.PermissionEdit, types.PermissionOwner} {
+		want := fiber.StatusForbidden
+		for _, a := range allowed {
+			if a == access {
+				want = fiber.StatusOK
+			}
+		}
+		if got := s.do(user, fiber.MethodGet, "/"+items+"/"+itemId.Hex()+"/access/"+string(access), ""); got != want {
+			s.t.Errorf("%s asking %s access to %s returned %d, want %d", who, access, items, got, want)
+		}
+	}
+}
+
+func TestShareAuthorization(t *testing.T) {
+	ctx := context.Background()
+	s := newShareTest(t)
+	owner, viewer, editor, stranger := s.user("owner"), s.user("viewer"), s.user("editor"), s.user("stranger")
+
+	note, err := s.store.Notes.Create(ctx, &types.NotesCreate{Title: "Plan", UserID: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.store.Tasks.Create(ctx, &types.TasksCreate{
+		Title:         "Project",
+		UserID:        owner,
+		StatusHistory: []*types.Status{{Status: "todo", UserId: owner.Hex()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtask, err := s.store.CreateSubtask(ctx, task, &types.TasksCreate{
+		Title:         "Step",
+		UserID:        owner,
+		StatusHistory: []*types.Status{{Status: "todo", UserId: owner.Hex()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := map[string]primitive.ObjectID{"notes": note.Id, "tasks": task.Id}
+	for kind, itemId := range items {
+		s.share(owner, kind, itemId, "viewer", types.PermissionView)
+		s.share(owner, kind, itemId, "editor", types.PermissionEdit)
+	}
+
+	t.Run("matrix", func(t *testing.T) {
+		for kind, itemId := range items {
+			s.checkAccess("the owner", owner, kind, itemId, types.PermissionView, types.PermissionEdit, types.PermissionOwner)
+			s.checkAccess("a viewer", viewer, kind, itemId, types.PermissionView)
+			s.checkAccess("an editor", editor, kind, itemId, types.PermissionView, types.PermissionEdit)
+			s.checkAccess("a stranger", stranger, kind, itemId)
+		}
+	})
+
+	t.Run("subtasks", func(t *testing.T) {
+		s.checkAccess("a viewer of the parent", viewer, "tasks", subtask.Id, types.PermissionView)
+		s.checkAccess("an editor of the parent", editor, "tasks", subtask.Id, types.PermissionView, types.PermissionEdit)
+		s.checkAccess("a stranger", stranger, "tasks", subtask.Id)
+	})
+
+	t.Run("only owners share", func(t *testing.T) {
+		for kind, itemId := range items {
+			body := `{"email":"stranger@example.com","permission":"view"}`
+			for who, users := range map[string][2]primitive.ObjectID{"a viewer": {viewer, editor}, "an editor": {editor, viewer}} {
+				user, other := users[0], users[1]
+				if status := s.do(user, fiber.MethodPost, "/"+kind+"/"+itemId.Hex()+"/shares", body); status == fiber.StatusOK {
+					t.Errorf("%s could share %s", who, kind)
+				}
+				if status := s.do(user, fiber.MethodDelete, "/"+kind+"/"+itemId.Hex()+"/shares/"+other.Hex(), ""); status == fiber.StatusOK {
+					t.Errorf("%s could revoke the share of another user on %s", who, kind)
+				}
+			}
+			if status := s.do(editor, fiber.MethodPost, "/"+kind+"/"+itemId.Hex()+"/shares", `{"email":"editor@example.com","permission":"edit"}`); status == fiber.StatusOK {
+				t.Errorf("an editor could share %s again with themselves", kind)
+			}
+			s.checkAccess("a stranger the others tried to share with", stranger, kind, itemId)
+			s.checkAccess("a viewer after the others tried to revoke it", viewer, kind, itemId, types.PermissionView)
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		for kind, itemId := range items {
+			if status := s.do(owner, fiber.MethodDelete, "/"+kind+"/"+itemId.Hex()+"/shares/"+editor.Hex(), ""); status != fiber.StatusOK {
+				t.Fatalf("revoking the editor's share on %s returned %d", kind, status)
+			}
+			s.checkAccess("a revoked editor", editor, kind, itemId)
+		}
+		s.checkAccess("a revoked editor of the parent", editor, "tasks", subtask.Id)
+
+		// Users can leave an item shared with them
+		if status := s.do(viewer, fiber.MethodDelete, "/notes/"+note.Id.Hex()+"/shares/"+viewer.Hex(), ""); status != fiber.StatusOK {
+			t.Fatalf("the viewer leaving the note returned %d", status)
+		}
+		s.checkAccess("a viewer who left", viewer, "notes", note.Id)
+		s.checkAccess("a viewer of another item", viewer, "tasks", task.Id, types.PermissionView)
+	})
+}
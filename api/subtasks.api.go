@@ -0,0 +1,113 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetSubtasks lists the direct subtasks of a task with their progress
+func GetSubtasks(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionView)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	subtasks, err := store.LoadSubtasks(storeContext(c), task)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching subtasks", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Subtasks retrieved successfully", fiber.StatusOK, subtasks))
+}
+
+// CreateSubtask creates a task under another one. Anyone who can edit the
+// parent can add subtasks to it; they belong to the owner of the parent.
+func CreateSubtask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	parent, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	return createTask(c, store, parent)
+}
+
+// MoveTask moves a task and its subtasks under the task in parent_id, or to
+// the top level when it is null. The user must be able to edit both tasks.
+func MoveTask(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.MoveTaskRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil {
+		if err.Error() == "task not found" {
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	var parent *types.Tasks
+	if request.ParentID != nil {
+		parent, err = CheckTaskAuthorization(c, store, *request.ParentID, types.PermissionEdit)
+		if err != nil {
+			if err.Error() == "task not found" {
+				apiError := types.ErrResourceNotFound("Parent task")
+				return c.Status(apiError.Code).JSON(apiError)
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+		}
+	}
+
+	moved, err := store.MoveTask(storeContext(c), task, parent)
+	if err != nil {
+		switch err {
+		case db.ErrSubtaskDepth, db.ErrSubtaskCycle, db.ErrSubtaskOwner:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrNotFound:
+			apiError := types.ErrResourceNotFound("Task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error moving task", http.StatusInternalServerError, nil))
+	}
+
+	// The previous parent may be done without the task
+	autoCompleteParent(c, store, task)
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task moved successfully", fiber.StatusOK, moved))
+}
+
+// autoCompleteParent gives the parent of a task that left it the chance to
+// complete.
+func autoCompleteParent(c *fiber.Ctx, store *db.Store, task *types.Tasks) {
+	if task.ParentID == nil {
+		return
+	}
+	if parent, err := store.Tasks.Get(storeContext(c), *task.ParentID); err == nil {
+		store.AutoComplete(storeContext(c), parent)
+	}
+}
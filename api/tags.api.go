@@ -0,0 +1,230 @@
+package api
+
+import (
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultTagColor = "#9e9e9e"
+	maxTagNameLen   = 50
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// GetTags retrieves the logged-in user's tags with their usage counts
+func GetTags(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	tags, err := store.ListTags(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching tags", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tags retrieved successfully", fiber.StatusOK, tags))
+}
+
+func CreateTag(c *fiber.Ctx, store *db.Store) error {
+	var tag types.TagRequest
+	if err := c.BodyParser(&tag); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if err := validateTag(&tag); err != nil {
+		return c.Status(err.Code).JSON(err)
+	}
+
+	newTag, err := store.Tags.Create(storeContext(c), &types.TagCreate{
+		Name:   tag.Name,
+		Color:  tag.Color,
+		UserID: userId,
+	})
+	if err == db.ErrDuplicateTag {
+		apiError := types.NewError(fiber.StatusConflict, err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error creating tag", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Tag created successfully", fiber.StatusCreated, newTag))
+}
+
+// UpdateTag renames or recolors a tag. Items reference tags by ID, so a
+// rename shows up on every note and task carrying the tag.
+func UpdateTag(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var updatedTag types.TagRequest
+	if err := c.BodyParser(&updatedTag); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+
+	existingTag, err := checkTagAuthorization(c, store, id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	// Merge existing tag with updates (if fields are provided)
+	modifiedTag := types.TagRequest{
+		Name:  existingTag.Name,
+		Color: existingTag.Color,
+	}
+	if updatedTag.Name != "" {
+		modifiedTag.Name = updatedTag.Name
+	}
+	if updatedTag.Color != "" {
+		modifiedTag.Color = updatedTag.Color
+	}
+	if err := validateTag(&modifiedTag); err != nil {
+		return c.Status(err.Code).JSON(err)
+	}
+
+	tag, err := store.Tags.Update(storeContext(c), id, &types.TagUpdate{
+		Name:  modifiedTag.Name,
+		Color: modifiedTag.Color,
+	})
+	if err == db.ErrDuplicateTag {
+		apiError := types.NewError(fiber.StatusConflict, err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if err != nil {
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error updating tag")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tag updated successfully", fiber.StatusOK, tag))
+}
+
+// DeleteTag removes a tag and detaches it from every note and task
+func DeleteTag(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := checkTagAuthorization(c, store, id); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	_, err = store.DeleteTag(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Tag")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error deleting tag")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tag deleted successfully", fiber.StatusOK, nil))
+}
+
+// MergeTag moves every note and task from the tag in the URL to the tag in
+// the body, then deletes the former
+func MergeTag(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var merge types.TagMergeRequest
+	if err := c.BodyParser(&merge); err != nil || merge.Into.IsZero() {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if merge.Into == id {
+		apiError := types.ErrBadRequest("cannot merge a tag into itself")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := checkTagAuthorization(c, store, id); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	tag, err := store.MergeTags(storeContext(c), id, merge.Into)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Tag")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		if err == db.ErrTagOwnerMismatch {
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error merging tags")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tags merged successfully", fiber.StatusOK, tag))
+}
+
+// checkTagAuthorization makes sure only the owner of a tag or an admin can change it
+func checkTagAuthorization(c *fiber.Ctx, store *db.Store, tagId primitive.ObjectID) (*types.Tag, error) {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID format")
+	}
+
+	tag, err := store.Tags.Get(storeContext(c), tagId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, fmt.Errorf("tag not found")
+		}
+		return nil, fmt.Errorf("error retrieving tag: %w", err)
+	}
+
+	if c.Locals("role") != "admin" && tag.UserID != userId {
+		return nil, fmt.Errorf("unauthorized access to the tag")
+	}
+	return tag, nil
+}
+
+func validateTag(tag *types.TagRequest) *types.Error {
+	tag.Name = strings.Join(strings.Fields(tag.Name), " ")
+	if tag.Name == "" {
+		apiError := types.ErrBadRequest("tag name is required")
+		return &apiError
+	}
+	if len([]rune(tag.Name)) > maxTagNameLen {
+		apiError := types.ErrBadRequest(fmt.Sprintf("tag name must be at most %d characters", maxTagNameLen))
+		return &apiError
+	}
+	if !tagColorPattern.MatchString(tag.Color) {
+		apiError := types.ErrBadRequest("tag color must look like #1e88e5")
+		return &apiError
+	}
+	return nil
+}
+
+// checkItemTags makes sure every tag attached to a note or task belongs to
+// the item's owner
+func checkItemTags(c *fiber.Ctx, store *db.Store, ownerId primitive.ObjectID, tags []primitive.ObjectID) *types.Error {
+	owned, err := store.TagsOwnedBy(storeContext(c), ownerId, tags)
+	if err != nil {
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error checking tags")
+		return &apiError
+	}
+	if !owned {
+		apiError := types.ErrBadRequest("tags must exist and belong to the owner of the item")
+		return &apiError
+	}
+	return nil
+}
@@ -1,15 +1,20 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"golang-auth/db"
 	"golang-auth/types"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const maxStatusCommentLen = 1000
+
 // GetAllNotesForUser retrieves all notes for a specific user
 func GetAllTasksForLoginUser(c *fiber.Ctx, store *db.Store) error {
 	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
@@ -19,11 +24,7 @@ func GetAllTasksForLoginUser(c *fiber.Ctx, store *db.Store) error {
 		})
 	}
 
-	tasks, err := store.Tasks.List(c.Context(), userId)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching tasks", http.StatusInternalServerError, nil))
-	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tasks retrieved successfully", fiber.StatusOK, tasks))
+	return listUserTasks(c, store, userId)
 }
 
 func GetAllTasksForUserById(c *fiber.Ctx, store *db.Store) error {
@@ -34,11 +35,77 @@ func GetAllTasksForUserById(c *fiber.Ctx, store *db.Store) error {
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	tasks, err := store.Tasks.List(c.Context(), userId)
+	return listUserTasks(c, store, userId)
+}
+
+// listUserTasks sends one page of the user's tasks, see parseListQuery and
+// parseDueFilter, each with whether it is blocked and its comment count. Tasks can also be sorted
+// by priority and due_at. ?scope lists the tasks the user owns (owned, the
+// default), is assigned to (assigned) or created (created).
+func listUserTasks(c *fiber.Ctx, store *db.Store, userId primitive.ObjectID) error {
+	query, err := parseListQuery(c)
+	if err != nil {
+		return sendListError(c, err, "Error fetching tasks")
+	}
+	query.Scope = c.Query("scope", types.ScopeOwned)
+	if query.Scope != types.ScopeOwned && query.Scope != types.ScopeAssigned && query.Scope != types.ScopeCreated {
+		apiError := types.ErrBadRequest("scope must be owned, assigned or created")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if err := parseDueFilter(c, store, userId, query); err != nil {
+		return sendListError(c, err, "Error fetching tasks")
+	}
+	tasks, page, err := store.Tasks.ListPage(storeContext(c), userId, query)
+	if err != nil {
+		return sendListError(c, err, "Error fetching tasks")
+	}
+	if err := store.LoadBlocked(storeContext(c), tasks...); err != nil {
+		return sendListError(c, err, "Error fetching tasks")
+	}
+	if err := store.LoadTaskCommentCounts(storeContext(c), tasks...); err != nil {
+		return sendListError(c, err, "Error fetching comments")
+	}
+	if err := store.LoadTimeSpent(storeContext(c), tasks...); err != nil {
+		return sendListError(c, err, "Error fetching time entries")
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreatePaginatedResponse("Tasks retrieved successfully", fiber.StatusOK, tasks, page))
+}
+
+// parseDueFilter narrows a task list with ?due=overdue, ?due=today or
+// ?due=this_week. Days and weeks, which start on Monday, follow the IANA zone
+// in ?tz, UTC by default. Overdue leaves out tasks in a done status of the
+// user's workflow.
+func parseDueFilter(c *fiber.Ctx, store *db.Store, userId primitive.ObjectID, query *types.ListQuery) error {
+	due := c.Query("due")
+	if due == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(c.Query("tz", "UTC"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching tasks", http.StatusInternalServerError, nil))
+		return types.ErrBadRequest("tz must be an IANA time zone such as Europe/Paris")
 	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Tasks retrieved successfully", fiber.StatusOK, tasks))
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+	switch due {
+	case "overdue":
+		workflow, err := store.TaskWorkflow(storeContext(c), userId)
+		if err != nil {
+			return err
+		}
+		query.DueTo = &now
+		query.NotStatuses = workflow.Done
+	case "today":
+		tomorrow := today.AddDate(0, 0, 1)
+		query.DueFrom, query.DueTo = &today, &tomorrow
+	case "this_week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		nextMonday := monday.AddDate(0, 0, 7)
+		query.DueFrom, query.DueTo = &monday, &nextMonday
+	default:
+		return types.ErrBadRequest("due must be overdue, today or this_week")
+	}
+	return nil
 }
 
 func GetSingleTask(c *fiber.Ctx, store *db.Store) error {
@@ -49,19 +116,39 @@ func GetSingleTask(c *fiber.Ctx, store *db.Store) error {
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	task, err := store.Tasks.Get(c.Context(), id)
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionView)
 	if err != nil {
-		if err.Error() == "mongo: no documents in result" {
+		if err.Error() == "task not found" {
 			apiError := types.ErrResourceNotFound("Task")
 			return c.Status(apiError.Code).JSON(apiError)
 		}
-		apiError := types.NewError(fiber.StatusInternalServerError, "Error retrieving task")
-		return c.Status(apiError.Code).JSON(apiError)
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	// The progress of the task comes from its subtasks and checklist
+	if _, err := store.LoadSubtasks(storeContext(c), task); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching subtasks", http.StatusInternalServerError, nil))
+	}
+	if err := store.LoadBlocked(storeContext(c), task); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching dependencies", http.StatusInternalServerError, nil))
+	}
+	if err := store.LoadTaskCommentCounts(storeContext(c), task); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching comments", http.StatusInternalServerError, nil))
+	}
+	if err := store.LoadTimeSpent(storeContext(c), task); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching time entries", http.StatusInternalServerError, nil))
 	}
 	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task retrieved successfully", fiber.StatusOK, task))
 }
 
 func CreateTask(c *fiber.Ctx, store *db.Store) error {
+	return createTask(c, store, nil)
+}
+
+// createTask creates a task from the request body, as a subtask of parent
+// when it is set. Subtasks belong to the owner of their parent, so its
+// workflow and tags apply.
+func createTask(c *fiber.Ctx, store *db.Store, parent *types.Tasks) error {
 	// Parse the request body into TasksRequest
 	var task types.TasksRequest
 	if err := c.BodyParser(&task); err != nil {
@@ -75,11 +162,42 @@ func CreateTask(c *fiber.Ctx, store *db.Store) error {
 			"error": "Invalid user ID format",
 		})
 	}
+	ownerId := userId
+	if parent != nil {
+		ownerId = parent.UserID
+	}
+
+	// Tasks start in the initial status of the workflow unless they name
+	// another status of it
+	workflow, err := store.TaskWorkflow(storeContext(c), ownerId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching workflow", http.StatusInternalServerError, nil))
+	}
+	if task.Status == "" {
+		task.Status = workflow.Initial
+	} else if err := workflow.CheckStatus(task.Status); err != nil {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
 
 	// Create initial status history from the task status and logged-in user
+	comment, apiError := statusComment(task.StatusComment)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
 	statusEntry := &types.Status{
-		Status: task.Status,
-		UserId: userId.Hex(),
+		Status:  task.Status,
+		Comment: comment,
+		UserId:  userId.Hex(),
+	}
+
+	if apiError := checkItemTags(c, store, ownerId, task.Tags); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	schedule, apiError := parseTaskSchedule(&task, taskSchedule{})
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
 	}
 
 	// Prepare the task creation struct
@@ -87,13 +205,42 @@ func CreateTask(c *fiber.Ctx, store *db.Store) error {
 		Title:         task.Title,
 		Category:      task.Category,
 		Task:          task.Task,
+		Tags:          task.Tags,
 		UserID:        userId,
 		StatusHistory: []*types.Status{statusEntry}, // Add status entry to the history
+		Priority:      schedule.priority,
+		DueAt:         schedule.dueAt,
+		DueTimezone:   schedule.dueTimezone,
+		StartAt:       schedule.startAt,
+	}
+	if task.AutoComplete != nil {
+		createTask.AutoComplete = *task.AutoComplete
+	}
+	if createTask.Recurrence, apiError = parseTaskRecurrence(store, &task, schedule, nil); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	// Call the DB function to create the task
-	newTask, err := store.Tasks.Create(c.Context(), &createTask)
+	// Call the DB function to create the task, as long as its column can take
+	// it in
+	var newTask *types.Tasks
+	err = store.WithinWIPLimit(storeContext(c), workflow, ownerId, task.Status, func(ctx context.Context) error {
+		var err error
+		if parent != nil {
+			newTask, err = store.CreateSubtask(ctx, parent, &createTask)
+		} else {
+			newTask, err = store.Tasks.Create(ctx, &createTask)
+		}
+		return err
+	})
 	if err != nil {
+		switch err {
+		case db.ErrSubtaskDepth:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case db.ErrWIPLimit:
+			apiError := types.ErrBadRequest(wipLimitMessage(workflow, task.Status))
+			return c.Status(apiError.Code).JSON(apiError)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error creating task", http.StatusInternalServerError, nil))
 	}
 
@@ -115,17 +262,24 @@ func UpdateTask(c *fiber.Ctx, store *db.Store) error {
 	}
 
 	// Check authorization and retrieve the existing task
-	existingTask, err := CheckTaskAuthorization(c, store, id)
+	existingTask, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil && err.Error() == "unauthorized access to the task" {
+		existingTask, err = checkTaskAssignee(c, store, id)
+		if err == nil && !updatedTask.OnlyStatus() {
+			err = fmt.Errorf("assignees can only change the status of the task")
+		}
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
 	}
 
 	// Merge existing task with updates
 	modifiedTask := types.TasksUpdate{
-		Title:         existingTask.Title,
-		Category:      existingTask.Category,
-		Task:          existingTask.Task,
-		StatusHistory: existingTask.StatusHistory, // Preserve existing status history
+		Title:        existingTask.Title,
+		Category:     existingTask.Category,
+		Task:         existingTask.Task,
+		Tags:         existingTask.Tags,
+		AutoComplete: existingTask.AutoComplete,
 	}
 
 	// Update task details if provided
@@ -138,31 +292,257 @@ func UpdateTask(c *fiber.Ctx, store *db.Store) error {
 	if updatedTask.Task != "" {
 		modifiedTask.Task = updatedTask.Task
 	}
-
-	// Append new status to the status history
-	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid user ID format", http.StatusBadRequest, nil))
+	if updatedTask.Tags != nil {
+		if apiError := checkItemTags(c, store, existingTask.UserID, updatedTask.Tags); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		modifiedTask.Tags = updatedTask.Tags
+	}
+	if updatedTask.AutoComplete != nil {
+		modifiedTask.AutoComplete = *updatedTask.AutoComplete
+	}
+	schedule, apiError := parseTaskSchedule(&updatedTask, taskSchedule{
+		priority:    existingTask.Priority,
+		dueAt:       existingTask.DueAt,
+		dueTimezone: existingTask.DueTimezone,
+		startAt:     existingTask.StartAt,
+	})
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	modifiedTask.Priority = schedule.priority
+	modifiedTask.DueAt = schedule.dueAt
+	modifiedTask.DueTimezone = schedule.dueTimezone
+	modifiedTask.StartAt = schedule.startAt
+	// The recurrence is only written when the request changes it, so that the
+	// next occurrence linked meanwhile is not lost
+	if updatedTask.Recurrence != nil {
+		recurrence, apiError := parseTaskRecurrence(store, &updatedTask, schedule, existingTask.Recurrence)
+		if apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		modifiedTask.Recurrence = recurrence
+		modifiedTask.ClearRecurrence = recurrence == nil
 	}
-	newStatus := &types.Status{
-		Status: updatedTask.Status,
-		UserId: userId.Hex(),
+
+	// Append the new status to the status history when it changes, as far as
+	// checkStatusChange allows
+	completed := false
+	var workflow *types.Workflow
+	if updatedTask.Status != "" && updatedTask.Status != existingTask.CurrentStatus {
+		userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid user ID format", http.StatusBadRequest, nil))
+		}
+		workflow, err = store.TaskWorkflow(storeContext(c), existingTask.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching workflow", http.StatusInternalServerError, nil))
+		}
+		if apiError := checkStatusChange(c, store, workflow, existingTask, updatedTask.Status); apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		comment, apiError := statusComment(updatedTask.StatusComment)
+		if apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		modifiedTask.Status = &types.Status{
+			Status:         updatedTask.Status,
+			PreviousStatus: existingTask.CurrentStatus,
+			Comment:        comment,
+			UserId:         userId.Hex(),
+		}
+		completed = workflow.IsDone(updatedTask.Status) && !workflow.IsDone(existingTask.CurrentStatus)
+	} else if updatedTask.StatusComment != "" {
+		apiError := types.ErrBadRequest("status_comment can only be given with a status change")
+		return c.Status(apiError.Code).JSON(apiError)
 	}
-	modifiedTask.StatusHistory = append(modifiedTask.StatusHistory, newStatus)
 
-	// Update the task in the database
-	updatedTaskResult, err := store.Tasks.Update(c.Context(), id, &modifiedTask)
+	// Update the task in the database, as long as the column it moves to can
+	// take it in
+	var updatedTaskResult *types.Tasks
+	update := func(ctx context.Context) error {
+		var err error
+		updatedTaskResult, err = store.Tasks.Update(ctx, id, &modifiedTask)
+		return err
+	}
+	if modifiedTask.Status != nil {
+		err = store.WithinWIPLimit(storeContext(c), workflow, existingTask.UserID, modifiedTask.Status.Status, update)
+	} else {
+		err = update(storeContext(c))
+	}
 	if err != nil {
+		if err == db.ErrWIPLimit {
+			apiError := types.ErrBadRequest(wipLimitMessage(workflow, modifiedTask.Status.Status))
+			return c.Status(apiError.Code).JSON(apiError)
+		}
 		if err.Error() == "no task found" {
 			apiError := types.ErrResourceNotFound("Task")
 			return c.Status(apiError.Code).JSON(apiError)
 		}
+		if err == db.ErrConcurrentUpdate {
+			return c.Status(fiber.StatusConflict).JSON(types.CreateErrorResponse(err.Error(), http.StatusConflict, nil))
+		}
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error updating task")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
+	// Completing a recurring task schedules its next occurrence, and the
+	// change may complete the task or its parents
+	if completed {
+		updatedTaskResult = store.Recur(storeContext(c), updatedTaskResult)
+	}
+	updatedTaskResult = store.AutoComplete(storeContext(c), updatedTaskResult)
+
 	// Return the updated task as the response
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task updated successfully", fiber.StatusOK, updatedTaskResult))
+	return sendTaskWithBlocked(c, store, updatedTaskResult, "Task updated successfully")
+}
+
+// checkStatusChange returns why a task cannot move to status: the workflow
+// of its owner does not allow the move, or work would start while the task
+// waits for open blockers.
+func checkStatusChange(c *fiber.Ctx, store *db.Store, workflow *types.Workflow, task *types.Tasks, status string) *types.Error {
+	if err := workflow.CheckTransition(task.CurrentStatus, status); err != nil {
+		apiError := types.ErrBadRequest(err.Error())
+		return &apiError
+	}
+	if workflow.IsInProgress(status) && !workflow.IsInProgress(task.CurrentStatus) {
+		if err := store.LoadBlocked(storeContext(c), task); err != nil {
+			apiError := types.NewError(fiber.StatusInternalServerError, "Error fetching dependencies")
+			return &apiError
+		}
+		if task.Blocked {
+			apiError := types.ErrBadRequest("the task is blocked by tasks that are not done")
+			return &apiError
+		}
+	}
+	return nil
+}
+
+// wipLimitMessage explains that the column of status is full.
+func wipLimitMessage(workflow *types.Workflow, status string) string {
+	return fmt.Sprintf("the %q column is at its WIP limit of %d tasks", status, workflow.WIPLimits[status])
+}
+
+// taskSchedule is the priority and the dates of a task.
+type taskSchedule struct {
+	priority    types.Priority
+	dueAt       *time.Time
+	dueTimezone string
+	startAt     *time.Time
+}
+
+// parseTaskSchedule applies the priority, due date and start date of a
+// request to the current schedule of a task. Fields the request leaves out
+// keep their value, and an empty due_at or start_at clears the date.
+func parseTaskSchedule(request *types.TasksRequest, schedule taskSchedule) (taskSchedule, *types.Error) {
+	if request.Priority != "" {
+		priority, err := types.ParsePriority(request.Priority)
+		if err != nil {
+			apiError := types.ErrBadRequest(err.Error())
+			return schedule, &apiError
+		}
+		schedule.priority = priority
+	}
+
+	if request.DueTimezone != "" {
+		if _, err := time.LoadLocation(request.DueTimezone); err != nil {
+			apiError := types.ErrBadRequest("due_timezone must be an IANA time zone such as Europe/Paris")
+			return schedule, &apiError
+		}
+		schedule.dueTimezone = request.DueTimezone
+	}
+	location, _ := time.LoadLocation(schedule.dueTimezone)
+
+	var err error
+	if request.DueAt != nil {
+		if schedule.dueAt, err = parseTaskDate(*request.DueAt, location, true); err != nil {
+			apiError := types.ErrBadRequest("due_at must be a date or an RFC 3339 time")
+			return schedule, &apiError
+		}
+	}
+	if request.StartAt != nil {
+		if schedule.startAt, err = parseTaskDate(*request.StartAt, location, false); err != nil {
+			apiError := types.ErrBadRequest("start_at must be a date or an RFC 3339 time")
+			return schedule, &apiError
+		}
+	}
+	if schedule.dueAt != nil && schedule.startAt != nil && schedule.startAt.After(*schedule.dueAt) {
+		apiError := types.ErrBadRequest("start_at must not be after due_at")
+		return schedule, &apiError
+	}
+	return schedule, nil
+}
+
+// maxRecurrenceLength is the longest RRULE a task accepts.
+const maxRecurrenceLength = 200
+
+// parseTaskRecurrence applies the recurrence of a request to the current one
+// of a task. A new rule starts a series from the due date the request sets,
+// else from when the current occurrence is scheduled, else from the due date
+// or now. Once the next occurrence exists the series is changed there.
+func parseTaskRecurrence(store *db.Store, request *types.TasksRequest, schedule taskSchedule, recurrence *types.TaskRecurrence) (*types.TaskRecurrence, *types.Error) {
+	if request.Recurrence == nil {
+		return recurrence, nil
+	}
+	if recurrence != nil && recurrence.NextID != nil {
+		apiError := types.ErrBadRequest("the next occurrence of this task already exists; change the recurrence there")
+		return nil, &apiError
+	}
+	if *request.Recurrence == "" {
+		return nil, nil
+	}
+	if len(*request.Recurrence) > maxRecurrenceLength {
+		apiError := types.ErrBadRequest(fmt.Sprintf("recurrence must be at most %d characters", maxRecurrenceLength))
+		return nil, &apiError
+	}
+
+	at := store.Now()
+	switch {
+	case request.DueAt != nil && schedule.dueAt != nil:
+		at = *schedule.dueAt
+	case recurrence != nil:
+		at = recurrence.At
+	case schedule.dueAt != nil:
+		at = *schedule.dueAt
+	}
+	newRecurrence, err := db.NewTaskRecurrence(*request.Recurrence, at, schedule.dueTimezone, store.Now())
+	if err != nil {
+		apiError := types.ErrBadRequest("recurrence: " + err.Error())
+		return nil, &apiError
+	}
+	return newRecurrence, nil
+}
+
+// parseTaskDate reads an RFC 3339 time, or a date taken as the start or the
+// end of that day in location. An empty value clears the date.
+func parseTaskDate(value string, location *time.Location, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		day, err := time.ParseInLocation(time.DateOnly, value, location)
+		if err != nil {
+			return nil, err
+		}
+		t = day
+		if endOfDay {
+			t = day.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+	t = t.UTC().Truncate(time.Millisecond)
+	return &t, nil
+}
+
+// statusComment trims the comment recorded with a status change and checks
+// its length
+func statusComment(comment string) (string, *types.Error) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxStatusCommentLen {
+		apiError := types.ErrBadRequest(fmt.Sprintf("status_comment must be at most %d characters", maxStatusCommentLen))
+		return "", &apiError
+	}
+	return comment, nil
 }
 
 func DeleteTask(c *fiber.Ctx, store *db.Store) error {
@@ -174,12 +554,12 @@ func DeleteTask(c *fiber.Ctx, store *db.Store) error {
 	}
 
 	// Check authorization
-	_, err = CheckTaskAuthorization(c, store, id)
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
 	}
 
-	_, err = store.Tasks.Delete(c.Context(), id)
+	_, err = store.Tasks.Delete(storeContext(c), id)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			apiError := types.ErrResourceNotFound("Task")
@@ -188,13 +568,16 @@ func DeleteTask(c *fiber.Ctx, store *db.Store) error {
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error deleting task")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task deleted successfully", fiber.StatusOK, nil))
+
+	// The parent may be done without this subtask
+	autoCompleteParent(c, store, task)
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task moved to trash", fiber.StatusOK, nil))
 }
 
 // for checking purpose  that only logged in user or user who's role is
 // admin can only delete not other user like if he using postman or swagger
 
-func CheckTaskAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.ObjectID) (*types.Tasks, error) {
+func CheckTaskAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.ObjectID, access types.Permission) (*types.Tasks, error) {
 	// Retrieve the logged-in user's ID from the request context
 	// userId, ok := c.Locals("userId").(primitive.ObjectID)
 	// if !ok {
@@ -208,7 +591,7 @@ func CheckTaskAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.Obje
 	}
 
 	// Get the note by ID
-	task, err := store.Tasks.Get(c.Context(), noteId)
+	task, err := store.Tasks.Get(storeContext(c), noteId)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			return nil, fmt.Errorf("task not found")
@@ -216,12 +599,142 @@ func CheckTaskAuthorization(c *fiber.Ctx, store *db.Store, noteId primitive.Obje
 		return nil, fmt.Errorf("error retrieving note: %w", err)
 	}
 
-	// Check if the logged-in user is the owner of the task or an admin
-	if c.Locals("role") != "admin" && task.UserID != userId {
-		return nil, fmt.Errorf("unauthorized access to the task")
+	// Check if the logged-in user is the owner of the task, an admin, an
+	// assignee reading it or shared the task or one of its parents
+	assigneeAccess := access == types.PermissionView && task.HasAssignee(userId)
+	if c.Locals("role") != "admin" && task.UserID != userId && !assigneeAccess {
+		shared := false
+		for _, itemId := range append([]primitive.ObjectID{noteId}, task.Ancestors...) {
+			if shared, err = hasShareAccess(c, store, types.ShareTask, itemId, userId, access); err != nil {
+				return nil, fmt.Errorf("error retrieving task: %w", err)
+			}
+			if shared {
+				break
+			}
+		}
+		if !shared {
+			return nil, fmt.Errorf("unauthorized access to the task")
+		}
 	}
 
 	// If all checks pass, return the task without an error
 	return task, nil
 
 }
+
+// checkTaskAssignee retrieves a task for one of its assignees who cannot
+// edit it. Callers decide what such assignees may change: the status of the
+// task, whether its checklist items are done and the time they spend on it.
+func checkTaskAssignee(c *fiber.Ctx, store *db.Store, taskId primitive.ObjectID) (*types.Tasks, error) {
+	task, err := CheckTaskAuthorization(c, store, taskId, types.PermissionView)
+	if err != nil {
+		return nil, err
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil || !task.HasAssignee(userId) {
+		return nil, fmt.Errorf("unauthorized access to the task")
+	}
+	return task, nil
+}
+
+// GetTasksTrash lists the logged-in user's trashed tasks
+func GetTasksTrash(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID format",
+		})
+	}
+
+	tasks, err := store.Tasks.ListTrash(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching trashed tasks", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Trashed tasks retrieved successfully", fiber.StatusOK, tasks))
+}
+
+// RestoreTask takes a task out of the trash, along with the subtasks trashed
+// with it
+func RestoreTask(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	trashed, err := checkTrashedTaskAuthorization(c, store, id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	// Subtasks can only come back under a live parent
+	if trashed.ParentID != nil {
+		if _, err := store.Tasks.Get(storeContext(c), *trashed.ParentID); err != nil {
+			if err == db.ErrNotFound {
+				apiError := types.ErrBadRequest("restore the parent task first")
+				return c.Status(apiError.Code).JSON(apiError)
+			}
+			apiError := types.NewError(fiber.StatusInternalServerError, "Error restoring task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+	}
+
+	task, err := store.Tasks.Restore(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error restoring task")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task restored successfully", fiber.StatusOK, task))
+}
+
+// PurgeTask permanently removes a trashed task and its subtasks
+func PurgeTask(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	_, err = checkTrashedTaskAuthorization(c, store, id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	_, err = store.PurgeTask(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error purging task")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Task deleted permanently", fiber.StatusOK, nil))
+}
+
+// checkTrashedTaskAuthorization is the trash counterpart of CheckTaskAuthorization
+func checkTrashedTaskAuthorization(c *fiber.Ctx, store *db.Store, taskId primitive.ObjectID) (*types.Tasks, error) {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID format")
+	}
+
+	task, err := store.Tasks.GetTrashed(storeContext(c), taskId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, fmt.Errorf("task not found in trash")
+		}
+		return nil, fmt.Errorf("error retrieving task: %w", err)
+	}
+
+	if c.Locals("role") != "admin" && task.UserID != userId {
+		return nil, fmt.Errorf("unauthorized access to the task")
+	}
+	return task, nil
+}
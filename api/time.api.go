@@ -0,0 +1,459 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"golang-auth/db"
+	"golang-auth/types"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTaskTime lists the time entries of a task with the total time spent on it
+func GetTaskTime(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+
+	taskTime, err := store.TaskTime(storeContext(c), id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching time entries", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Time entries retrieved successfully", fiber.StatusOK, taskTime))
+}
+
+// AddTimeEntry enters time spent by the logged-in user on a task by hand
+func AddTimeEntry(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := checkTimeTracker(c, store)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	var request types.TimeEntryRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if request.StartedAt == "" {
+		apiError := types.ErrBadRequest("started_at is required")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	entry := &types.TimeEntry{TaskID: task.Id, UserID: userId, Manual: true}
+	if apiError := applyTimeEntryRequest(entry, &request); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	created, err := store.TimeEntries.Create(storeContext(c), entry)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error adding time entry", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Time entry added successfully", fiber.StatusCreated, created))
+}
+
+// UpdateTimeEntry changes the times or note of a time entry. The user who
+// spent the time can change it, and so can the owner of the task.
+func UpdateTimeEntry(c *fiber.Ctx, store *db.Store) error {
+	entry, apiError := findTimeEntry(c, store)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if entry.Running {
+		apiError := types.ErrBadRequest("stop the timer before changing its entry")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.TimeEntryRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	if apiError := applyTimeEntryRequest(entry, &request); apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	updated, err := store.TimeEntries.Update(storeContext(c), entry)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Time entry")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error updating time entry", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Time entry updated successfully", fiber.StatusOK, updated))
+}
+
+// DeleteTimeEntry removes a time entry. The user who spent the time can
+// remove it, and so can the owner of the task.
+func DeleteTimeEntry(c *fiber.Ctx, store *db.Store) error {
+	entry, apiError := findTimeEntry(c, store)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if _, err := store.TimeEntries.Delete(storeContext(c), entry.TaskID, entry.Id); err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Time entry")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error deleting time entry", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Time entry deleted successfully", fiber.StatusOK, nil))
+}
+
+// StartTimer starts timing the logged-in user's work on a task. A user has at
+// most one running timer.
+func StartTimer(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	task, err := checkTimeTracker(c, store)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse(err.Error(), http.StatusBadRequest, nil))
+	}
+	var request types.TimerRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+		}
+	}
+	note, apiError := timeEntryNote(request.Note)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	entry, err := store.StartTimer(storeContext(c), task, userId, note)
+	if err != nil {
+		if err == db.ErrTimerRunning {
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error starting timer", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.CreateSuccessResponse("Timer started successfully", fiber.StatusCreated, entry))
+}
+
+// StopTimer stops the logged-in user's timer on a task
+func StopTimer(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	var request types.TimerRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+		}
+	}
+	if request.Note != nil {
+		note, apiError := timeEntryNote(request.Note)
+		if apiError != nil {
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		request.Note = &note
+	}
+
+	entry, err := store.StopTimer(storeContext(c), userId, &id, request.Note)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrBadRequest("no timer is running on this task")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error stopping timer", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Timer stopped successfully", fiber.StatusOK, entry))
+}
+
+// GetRunningTimer retrieves the logged-in user's running timer, if any
+func GetRunningTimer(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	entry, err := store.TimeEntries.GetRunning(storeContext(c), userId)
+	if err != nil && err != db.ErrNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching timer", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Timer retrieved successfully", fiber.StatusOK, entry))
+}
+
+// GetTimeReport adds up the time spent per user and task category on the
+// logged-in user's tasks, the tasks shared with them and by the user
+// themselves. ?from and ?to are RFC 3339 times or dates in ?tz, both
+// included, and default to the current month. ?user_id and ?category narrow
+// the report and ?format=csv exports it.
+func GetTimeReport(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	format := c.Query("format", types.TimeReportJSON)
+	if format != types.TimeReportJSON && format != types.TimeReportCSV {
+		apiError := types.ErrBadRequest("format must be json or csv")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	query, apiError := parseTimeReportQuery(c, store)
+	if apiError != nil {
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	report, err := store.TimeReport(storeContext(c), userId, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error computing time report", http.StatusInternalServerError, nil))
+	}
+	if format == types.TimeReportCSV {
+		return sendTimeReportCSV(c, report)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Time report retrieved successfully", fiber.StatusOK, report))
+}
+
+// checkTimeTracker retrieves the task named in the route for a user who can
+// track time on it: anyone who can edit it and its assignees.
+func checkTimeTracker(c *fiber.Ctx, store *db.Store) (*types.Tasks, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID")
+	}
+	task, err := CheckTaskAuthorization(c, store, id, types.PermissionEdit)
+	if err != nil && err.Error() == "unauthorized access to the task" {
+		task, err = checkTaskAssignee(c, store, id)
+	}
+	return task, err
+}
+
+// findTimeEntry retrieves the time entry named in the route for the user who
+// spent the time or the owner of its task
+func findTimeEntry(c *fiber.Ctx, store *db.Store) (*types.TimeEntry, *types.Error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return nil, &apiError
+	}
+	entryId, err := primitive.ObjectIDFromHex(c.Params("entryId"))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return nil, &apiError
+	}
+	if _, err := CheckTaskAuthorization(c, store, id, types.PermissionView); err != nil {
+		apiError := types.NewError(fiber.StatusBadRequest, err.Error())
+		return nil, &apiError
+	}
+	entry, err := store.TimeEntries.Get(storeContext(c), id, entryId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Time entry")
+			return nil, &apiError
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error fetching time entry")
+		return nil, &apiError
+	}
+	if !isLoggedInUser(c, entry.UserID) {
+		if _, err := CheckTaskAuthorization(c, store, id, types.PermissionOwner); err != nil {
+			apiError := types.NewError(fiber.StatusBadRequest, err.Error())
+			return nil, &apiError
+		}
+	}
+	return entry, nil
+}
+
+// applyTimeEntryRequest sets the times and note of an entry from a request.
+// The end is given either as a time or as a duration from the start.
+func applyTimeEntryRequest(entry *types.TimeEntry, request *types.TimeEntryRequest) *types.Error {
+	if request.StartedAt != "" {
+		startedAt, err := time.Parse(time.RFC3339, request.StartedAt)
+		if err != nil {
+			apiError := types.ErrBadRequest("started_at must be an RFC 3339 time")
+			return &apiError
+		}
+		entry.StartedAt = startedAt.UTC().Truncate(time.Second)
+	}
+	switch {
+	case request.EndedAt != "" && request.Duration != 0:
+		apiError := types.ErrBadRequest("give either ended_at or duration, not both")
+		return &apiError
+	case request.EndedAt != "":
+		endedAt, err := time.Parse(time.RFC3339, request.EndedAt)
+		if err != nil {
+			apiError := types.ErrBadRequest("ended_at must be an RFC 3339 time")
+			return &apiError
+		}
+		endedAt = endedAt.UTC().Truncate(time.Second)
+		entry.EndedAt = &endedAt
+	case request.Duration != 0:
+		endedAt := entry.StartedAt.Add(time.Duration(request.Duration) * time.Second)
+		entry.EndedAt = &endedAt
+	case entry.EndedAt == nil:
+		apiError := types.ErrBadRequest("ended_at or duration is required")
+		return &apiError
+	default:
+		// Moving the start of an entry keeps its duration
+		endedAt := entry.StartedAt.Add(time.Duration(entry.Duration) * time.Second)
+		entry.EndedAt = &endedAt
+	}
+
+	duration := entry.EndedAt.Sub(entry.StartedAt)
+	if duration <= 0 {
+		apiError := types.ErrBadRequest("the entry must end after it starts")
+		return &apiError
+	}
+	if duration > types.MaxTimeEntryDuration {
+		apiError := types.ErrBadRequest(fmt.Sprintf("an entry must be at most %d hours long", int(types.MaxTimeEntryDuration.Hours())))
+		return &apiError
+	}
+	entry.Duration = int64(duration / time.Second)
+
+	if request.Note != nil {
+		note, apiError := timeEntryNote(request.Note)
+		if apiError != nil {
+			return apiError
+		}
+		entry.Note = note
+	}
+	return nil
+}
+
+// timeEntryNote trims the note of a time entry and checks its length
+func timeEntryNote(note *string) (string, *types.Error) {
+	if note == nil {
+		return "", nil
+	}
+	trimmed := strings.TrimSpace(*note)
+	if len([]rune(trimmed)) > types.MaxTimeEntryNoteLength {
+		apiError := types.ErrBadRequest(fmt.Sprintf("note must be at most %d characters", types.MaxTimeEntryNoteLength))
+		return "", &apiError
+	}
+	return trimmed, nil
+}
+
+// parseTimeReportQuery reads the date range and filters of a time report
+func parseTimeReportQuery(c *fiber.Ctx, store *db.Store) (*types.TimeReportQuery, *types.Error) {
+	location, err := time.LoadLocation(c.Query("tz", "UTC"))
+	if err != nil {
+		apiError := types.ErrBadRequest("tz must be an IANA time zone such as Europe/Paris")
+		return nil, &apiError
+	}
+	now := store.Now().In(location)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	query := &types.TimeReportQuery{From: monthStart.UTC(), To: monthStart.AddDate(0, 1, 0).UTC()}
+
+	if from := c.Query("from"); from != "" {
+		t, err := parseReportTime(from, location, false)
+		if err != nil {
+			apiError := types.ErrBadRequest("from must be a date or an RFC 3339 time")
+			return nil, &apiError
+		}
+		query.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseReportTime(to, location, true)
+		if err != nil {
+			apiError := types.ErrBadRequest("to must be a date or an RFC 3339 time")
+			return nil, &apiError
+		}
+		query.To = t
+	}
+	if !query.To.After(query.From) {
+		apiError := types.ErrBadRequest("to must be after from")
+		return nil, &apiError
+	}
+	if query.To.Sub(query.From) > types.MaxTimeReportRange {
+		apiError := types.ErrBadRequest(fmt.Sprintf("a report covers at most %d days", int(types.MaxTimeReportRange.Hours()/24)))
+		return nil, &apiError
+	}
+
+	if userId := c.Query("user_id"); userId != "" {
+		id, err := primitive.ObjectIDFromHex(userId)
+		if err != nil {
+			apiError := types.ErrBadRequest("user_id must be a valid ID")
+			return nil, &apiError
+		}
+		query.UserID = &id
+	}
+	if category := c.Query("category"); category != "" {
+		query.Category = &category
+	}
+	return query, nil
+}
+
+// parseReportTime parses an RFC 3339 time or a date in location. A date that
+// ends a range counts whole, up to the start of the next day.
+func parseReportTime(value string, location *time.Location, end bool) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.UTC(), nil
+}
+
+// sendTimeReportCSV answers with the rows of a time report as a CSV download
+func sendTimeReportCSV(c *fiber.Ctx, report *types.TimeReport) error {
+	var buf bytes.Buffer
+	if err := writeTimeReportCSV(&buf, report); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error exporting time report", http.StatusInternalServerError, nil))
+	}
+
+	filename := fmt.Sprintf("time-report-%s-%s.csv", report.From.Format(time.DateOnly), report.To.Add(-time.Second).Format(time.DateOnly))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// writeTimeReportCSV writes the rows of a time report as CSV
+func writeTimeReportCSV(out io.Writer, report *types.TimeReport) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"user_id", "user_name", "user_email", "category", "entries", "hours"}); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		err := w.Write([]string{
+			row.UserID.Hex(),
+			csvCell(row.UserName),
+			csvCell(row.UserEmail),
+			csvCell(row.Category),
+			strconv.Itoa(row.Entries),
+			strconv.FormatFloat(row.Hours, 'f', 2, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvCell quotes text that spreadsheets would otherwise run as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
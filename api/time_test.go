@@ -0,0 +1,47 @@
+package api
+
+import (
+	"bytes"
+	"golang-auth/types"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCSVCell(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                  "",
+		"work":              "work",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-1":                "'-1",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\tindented":        "'\tindented",
+		"a=b":               "a=b",
+	} {
+		if got := csvCell(value); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestWriteTimeReportCSV(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65f000000000000000000001")
+	report := &types.TimeReport{
+		From: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+		Rows: []*types.TimeReportRow{
+			{UserID: id, UserName: "=cmd|' /C calc'!A0", UserEmail: "ada@example.com", Category: "work, home", Entries: 2, Hours: 1.5},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeTimeReportCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	want := "user_id,user_name,user_email,category,entries,hours\n" +
+		"65f000000000000000000001,'=cmd|' /C calc'!A0,ada@example.com,\"work, home\",2,1.50\n"
+	if buf.String() != want {
+		t.Errorf("CSV is\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -1,40 +1,39 @@
 package api
 
 import (
+	"fmt"
 	"golang-auth/db"
 	"golang-auth/types"
 	"golang-auth/utils"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// GetAllUsers retrieves all users from the database.
+// GetAllUsers retrieves one page of users from the database, see parseListQuery.
 func GetAllUsers(c *fiber.Ctx, store *db.Store) error {
-	users, err := store.User.List(c.Context())
+	query, err := parseListQuery(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching users", http.StatusInternalServerError, nil))
+		return sendListError(c, err, "Error fetching users")
+	}
+	include, err := parseUserInclude(c)
+	if err != nil {
+		return sendListError(c, err, "Error fetching users")
 	}
 
-	// For each user, fetch their notes
-	for _, user := range users {
-		notes, err := store.Notes.List(c.Context(), user.Id)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes for user", http.StatusInternalServerError, nil))
-		}
-		tasks, err := store.Tasks.List(c.Context(), user.Id)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching tasks for user", http.StatusInternalServerError, nil))
-		}
-
-		user.Notes = notes
-		user.Tasks = tasks
+	users, page, err := store.User.ListPage(storeContext(c), query)
+	if err != nil {
+		return sendListError(c, err, "Error fetching users")
+	}
+	if err := store.AttachUserContent(storeContext(c), users, include); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes and tasks for users", http.StatusInternalServerError, nil))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Users retrieved successfully", fiber.StatusOK, users))
+	return c.Status(fiber.StatusOK).JSON(types.CreatePaginatedResponse("Users retrieved successfully", fiber.StatusOK, users, page))
 }
 
 // GetSingleUser retrieves a user by ID from the database.
@@ -73,6 +72,10 @@ func GetSingleUser(c *fiber.Ctx, store *db.Store) error {
 	return CommonUserGet(c, store, id)
 }
 
+// emailInTrashMessage answers signups and email changes to the email of a
+// trashed account, which keeps it until an admin restores or purges it.
+const emailInTrashMessage = "This email belongs to an account in the trash; ask an admin to restore it"
+
 func CreateUser(c *fiber.Ctx, store *db.Store) error {
 	var user types.UserRequest
 
@@ -89,7 +92,7 @@ func CreateUser(c *fiber.Ctx, store *db.Store) error {
 
 	if err == nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
-	} else if err != mongo.ErrNoDocuments {
+	} else if err != db.ErrNotFound {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check email"})
 	}
 
@@ -106,7 +109,14 @@ func CreateUser(c *fiber.Ctx, store *db.Store) error {
 	}
 
 	// Create user in the database
-	newUser, err := store.User.Create(c.Context(), &createUser)
+	newUser, err := store.User.Create(storeContext(c), &createUser)
+	if err == db.ErrDuplicateEmail {
+		// Lost the race against a concurrent signup with the same email
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
+	}
+	if err == db.ErrEmailInTrash {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": emailInTrashMessage})
+	}
 	if err != nil {
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error creating user")
 		return c.Status(apiError.Code).JSON(apiError)
@@ -126,7 +136,9 @@ func CreateUser(c *fiber.Ctx, store *db.Store) error {
 	})
 }
 
-// DeleteUser deletes a user by ID from the database.
+// DeleteUser moves a user to the trash together with their notes and tasks.
+// Passing ?transfer_to=<user id> hands the notes and tasks over to that user
+// instead. The uploaded avatar is only removed once the user is purged.
 func DeleteUser(c *fiber.Ctx, store *db.Store) error {
 	idParam := c.Params("id")
 	id, err := primitive.ObjectIDFromHex(idParam)
@@ -135,16 +147,93 @@ func DeleteUser(c *fiber.Ctx, store *db.Store) error {
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	deletedUser, err := store.User.Delete(c.Context(), id)
+	var transferTo *primitive.ObjectID
+	if transferParam := c.Query("transfer_to"); transferParam != "" {
+		transferId, err := primitive.ObjectIDFromHex(transferParam)
+		if err != nil {
+			apiError := types.ErrBadRequest("Invalid transfer_to user ID")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		if transferId == id {
+			apiError := types.ErrBadRequest("Cannot transfer data to the user being deleted")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		transferTo = &transferId
+	}
+
+	report, err := store.DeleteUser(storeContext(c), id, transferTo)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			apiError := types.ErrResourceNotFound("User")
 			return c.Status(apiError.Code).JSON(apiError)
 		}
+		if err == db.ErrTransferTargetNotFound {
+			apiError := types.ErrResourceNotFound("Transfer target user")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error deleting user")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
-	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User deleted successfully", fiber.StatusOK, deletedUser))
+
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User moved to trash", fiber.StatusOK, report))
+}
+
+// GetUsersTrash lists every user in the trash.
+func GetUsersTrash(c *fiber.Ctx, store *db.Store) error {
+	users, err := store.User.ListTrash(storeContext(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching trashed users", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Trashed users retrieved successfully", fiber.StatusOK, users))
+}
+
+// RestoreUser takes a user out of the trash with the notes and tasks trashed alongside them.
+func RestoreUser(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	user, err := store.RestoreUser(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed user")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		if err == db.ErrDuplicateEmail {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error restoring user")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User restored successfully", fiber.StatusOK, user))
+}
+
+// PurgeUser permanently removes a trashed user, their notes, tasks and uploaded avatar.
+func PurgeUser(c *fiber.Ctx, store *db.Store) error {
+	idParam := c.Params("id")
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	report, err := store.PurgeUser(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("Trashed user")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error purging user")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	// Files are not part of the transaction, so only remove them once it committed
+	report.AvatarRemoved = utils.RemoveUserAvatar(report.User.ProfilePicture)
+
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User deleted permanently", fiber.StatusOK, report))
 }
 
 func UpdateUser(c *fiber.Ctx, store *db.Store) error {
@@ -159,8 +248,28 @@ func UpdateUser(c *fiber.Ctx, store *db.Store) error {
 	return CommmonUserUpdate(c, store, id)
 }
 
+// parseUserInclude reads ?include=notes,tasks, which embeds the user's notes
+// and tasks in the response next to their counts.
+func parseUserInclude(c *fiber.Ctx) (types.UserInclude, error) {
+	var include types.UserInclude
+	if c.Query("include") == "" {
+		return include, nil
+	}
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(name) {
+		case "notes":
+			include.Notes = true
+		case "tasks":
+			include.Tasks = true
+		default:
+			return include, types.ErrBadRequest("include accepts notes and tasks")
+		}
+	}
+	return include, nil
+}
+
 func CommonUserGet(c *fiber.Ctx, store *db.Store, id primitive.ObjectID) error {
-	user, err := store.User.Get(c.Context(), id)
+	user, err := store.User.Get(storeContext(c), id)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			apiError := types.ErrResourceNotFound("User")
@@ -170,19 +279,14 @@ func CommonUserGet(c *fiber.Ctx, store *db.Store, id primitive.ObjectID) error {
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	notes, err := store.Notes.List(c.Context(), id)
+	include, err := parseUserInclude(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes for user", http.StatusInternalServerError, nil))
-	}
-	tasks, err := store.Tasks.List(c.Context(), id)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching tasks for user", http.StatusInternalServerError, nil))
+		apiError := err.(types.Error)
+		return c.Status(apiError.Code).JSON(apiError)
 	}
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes", http.StatusInternalServerError, nil))
+	if err := store.AttachUserContent(storeContext(c), []*types.UserResponse{user}, include); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching notes and tasks for user", http.StatusInternalServerError, nil))
 	}
-	user.Notes = notes
-	user.Tasks = tasks
 	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User retrieved successfully", fiber.StatusOK, user))
 }
 
@@ -193,7 +297,7 @@ func CommmonUserUpdate(c *fiber.Ctx, store *db.Store, id primitive.ObjectID) err
 		return c.Status(apiError.Code).JSON(apiError)
 	}
 
-	existingUser, err := store.User.Get(c.Context(), id)
+	existingUser, err := store.User.Get(storeContext(c), id)
 	if err != nil {
 		if err.Error() == "mongo: no documents in result" {
 			apiError := types.ErrResourceNotFound("User")
@@ -215,13 +319,24 @@ func CommmonUserUpdate(c *fiber.Ctx, store *db.Store, id primitive.ObjectID) err
 		modifiedUser.Email = updatedUser.Email
 	}
 	if updatedUser.ProfilePicture != "" {
+		// Uploaded avatars can only be set through UploadAvatar
+		if utils.IsUserAvatar(updatedUser.ProfilePicture) && updatedUser.ProfilePicture != existingUser.ProfilePicture {
+			apiError := types.ErrBadRequest("profile_picture must be a stock avatar, upload your own picture instead")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
 		modifiedUser.ProfilePicture = updatedUser.ProfilePicture
 	}
 	if (updatedUser.SocialMedia != types.SocialMedia{}) {
 		modifiedUser.SocialMedia = updatedUser.SocialMedia
 	}
 
-	updatedUserResult, err := store.User.Update(c.Context(), id, &modifiedUser)
+	updatedUserResult, err := store.User.Update(storeContext(c), id, &modifiedUser)
+	if err == db.ErrDuplicateEmail {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already in use"})
+	}
+	if err == db.ErrEmailInTrash {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": emailInTrashMessage})
+	}
 	if err != nil {
 		if err.Error() == "no user found" {
 			apiError := types.ErrResourceNotFound("User")
@@ -230,10 +345,94 @@ func CommmonUserUpdate(c *fiber.Ctx, store *db.Store, id primitive.ObjectID) err
 		apiError := types.NewError(fiber.StatusInternalServerError, "Error updating user")
 		return c.Status(apiError.Code).JSON(apiError)
 	}
+	if modifiedUser.ProfilePicture != existingUser.ProfilePicture {
+		utils.RemoveUserAvatar(existingUser.ProfilePicture)
+	}
 
 	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("User updated successfully", fiber.StatusOK, updatedUserResult))
 }
 
+// UploadAvatar replaces the logged-in user's profile picture with the image
+// sent in the "avatar" field of a multipart form. See utils.SaveUserAvatar for
+// how the image is checked and resized. The files of the previous uploaded
+// avatar are removed.
+func UploadAvatar(c *fiber.Ctx, store *db.Store) error {
+	id, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		apiError := types.ErrBadRequest("avatar is required")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	if file.Size > utils.MaxAvatarBytes {
+		apiError := types.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("avatar must be at most %d bytes", utils.MaxAvatarBytes))
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	body, err := file.Open()
+	if err != nil {
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error reading avatar")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, utils.MaxAvatarBytes))
+	body.Close()
+	if err != nil {
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error reading avatar")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	existingUser, err := store.User.Get(storeContext(c), id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			apiError := types.ErrResourceNotFound("User")
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error retrieving user")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	pictures, err := utils.SaveUserAvatar(id.Hex(), data)
+	if err != nil {
+		switch err {
+		case utils.ErrAvatarType:
+			apiError := types.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		case utils.ErrAvatarDimensions:
+			apiError := types.ErrBadRequest(err.Error())
+			return c.Status(apiError.Code).JSON(apiError)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error saving avatar")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	profilePicture := pictures[utils.AvatarSizes[len(utils.AvatarSizes)-1]]
+
+	updatedUser, err := store.User.Update(storeContext(c), id, &types.UserUpdate{
+		Name:           existingUser.Name,
+		Email:          existingUser.Email,
+		ProfilePicture: profilePicture,
+		SocialMedia:    existingUser.SocialMedia,
+	})
+	if err != nil {
+		if profilePicture != existingUser.ProfilePicture {
+			utils.RemoveUserAvatar(profilePicture)
+		}
+		apiError := types.NewError(fiber.StatusInternalServerError, "Error updating user")
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+	// Uploading the same image again yields the same files, which must stay
+	if profilePicture != existingUser.ProfilePicture {
+		utils.RemoveUserAvatar(existingUser.ProfilePicture)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Avatar uploaded successfully", fiber.StatusOK, types.AvatarUpload{
+		User:     updatedUser,
+		Variants: pictures,
+	}))
+}
+
 func GetAllAvatar(c *fiber.Ctx, store *db.Store) error {
 
 	// Define the directory path
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"golang-auth/db"
+	"golang-auth/types"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetWorkflow retrieves the workflow the logged-in user's tasks follow
+func GetWorkflow(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	workflow, err := store.TaskWorkflow(storeContext(c), userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error fetching workflow", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Workflow retrieved successfully", fiber.StatusOK, workflow))
+}
+
+// UpdateWorkflow replaces the workflow of the logged-in user. Existing tasks
+// keep their status; tasks in a status the new workflow lacks can move to
+// any of its statuses.
+func UpdateWorkflow(c *fiber.Ctx, store *db.Store) error {
+	var request types.WorkflowRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.CreateErrorResponse("Invalid request body", http.StatusBadRequest, nil))
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	workflow := &types.Workflow{
+		UserID:      userId,
+		Initial:     request.Initial,
+		Statuses:    request.Statuses,
+		Transitions: request.Transitions,
+		Done:        request.Done,
+		WIPLimits:   request.WIPLimits,
+	}
+	if workflow.Initial == "" && len(workflow.Statuses) > 0 {
+		workflow.Initial = workflow.Statuses[0]
+	}
+	if workflow.Transitions == nil {
+		workflow.Transitions = map[string][]string{}
+	}
+	if workflow.Done == nil {
+		workflow.Done = finalStatuses(workflow)
+	}
+	if err := workflow.Validate(); err != nil {
+		apiError := types.ErrBadRequest(err.Error())
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	saved, err := store.Workflows.Upsert(storeContext(c), workflow)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error saving workflow", http.StatusInternalServerError, nil))
+	}
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Workflow updated successfully", fiber.StatusOK, saved))
+}
+
+// ResetWorkflow drops the workflow of the logged-in user so their tasks
+// follow the default one again
+func ResetWorkflow(c *fiber.Ctx, store *db.Store) error {
+	userId, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		apiError := types.ErrInvalidID()
+		return c.Status(apiError.Code).JSON(apiError)
+	}
+
+	if _, err := store.Workflows.DeleteByUser(storeContext(c), userId); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.CreateErrorResponse("Error resetting workflow", http.StatusInternalServerError, nil))
+	}
+	workflow := db.DefaultWorkflow()
+	workflow.UserID = userId
+	return c.Status(fiber.StatusOK).JSON(types.CreateSuccessResponse("Workflow reset to the default", fiber.StatusOK, workflow))
+}
+
+// finalStatuses lists the statuses of a workflow that tasks cannot move out
+// of, or its last status when every status leads somewhere.
+func finalStatuses(workflow *types.Workflow) []string {
+	final := []string{}
+	for _, status := range workflow.Statuses {
+		if len(workflow.Transitions[status]) == 0 {
+			final = append(final, status)
+		}
+	}
+	if len(final) == 0 && len(workflow.Statuses) > 0 {
+		final = append(final, workflow.Statuses[len(workflow.Statuses)-1])
+	}
+	return final
+}
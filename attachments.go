@@ -0,0 +1,52 @@
+package main
+
+import (
+	"golang-auth/db"
+	"golang-auth/storage"
+	"golang-auth/utils"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// configureAttachments sets where attachments are stored, see
+// storage.NewFromEnv, and which files are accepted: ATTACHMENT_MAX_SIZE is
+// the largest file in bytes and ATTACHMENT_TYPES a comma separated list of
+// content types.
+func configureAttachments(store *db.Store) {
+	blobs, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal("Failed to open blob storage: ", err)
+	}
+	store.Blobs = blobs
+
+	if value := os.Getenv("ATTACHMENT_MAX_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid ATTACHMENT_MAX_SIZE %q", value)
+		}
+		store.AttachmentMaxSize = size
+	}
+	if value := os.Getenv("ATTACHMENT_TYPES"); value != "" {
+		contentTypes := []string{}
+		for _, contentType := range strings.Split(value, ",") {
+			if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" {
+				contentTypes = append(contentTypes, contentType)
+			}
+		}
+		store.AttachmentTypes = contentTypes
+	}
+}
+
+// bodyLimit lets requests carry the largest accepted attachment or avatar
+// plus the multipart framing around it, and is never below Fiber's default.
+func bodyLimit(store *db.Store) int {
+	limit := max(store.AttachmentMaxSize, utils.MaxAvatarBytes) + 1<<20
+	if limit < fiber.DefaultBodyLimit {
+		return fiber.DefaultBodyLimit
+	}
+	return int(limit)
+}
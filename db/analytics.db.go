@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskAnalytics computes the analytics of a task against the workflow of its
+// owner, which decides when work starts and which statuses count as done.
+func (s *Store) TaskAnalytics(ctx context.Context, task *types.Tasks) (*types.TaskAnalytics, error) {
+	workflow, err := s.TaskWorkflow(ctx, task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeTask(task, workflow, timestamp()), nil
+}
+
+// TaskAnalyticsReport aggregates the analytics of the user's live tasks and
+// of the tasks shared with them, per category or per owner.
+func (s *Store) TaskAnalyticsReport(ctx context.Context, userId primitive.ObjectID, groupBy string) (*types.TaskAnalyticsReport, error) {
+	tasks, err := s.Tasks.List(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := s.SharedWith(ctx, userId, types.ShareTask)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shared {
+		if item.Task != nil {
+			tasks = append(tasks, item.Task)
+		}
+	}
+
+	now := timestamp()
+	workflows := map[primitive.ObjectID]*types.Workflow{}
+	groups := map[string][]*types.TaskAnalytics{}
+	all := make([]*types.TaskAnalytics, 0, len(tasks))
+	for _, task := range tasks {
+		workflow, ok := workflows[task.UserID]
+		if !ok {
+			if workflow, err = s.TaskWorkflow(ctx, task.UserID); err != nil {
+				return nil, err
+			}
+			workflows[task.UserID] = workflow
+		}
+		analytics := analyzeTask(task, workflow, now)
+		all = append(all, analytics)
+
+		key := task.Category
+		if groupBy == types.AnalyticsByUser {
+			key = task.UserID.Hex()
+		}
+		groups[key] = append(groups[key], analytics)
+	}
+
+	report := &types.TaskAnalyticsReport{
+		GroupBy: groupBy,
+		Overall: aggregateTaskAnalytics("", all),
+		Groups:  make([]*types.TaskAnalyticsGroup, 0, len(groups)),
+	}
+	for key, analytics := range groups {
+		report.Groups = append(report.Groups, aggregateTaskAnalytics(key, analytics))
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Key < report.Groups[j].Key })
+	return report, nil
+}
+
+// analyzeTask walks the status history of a task. Work starts with the first
+// status that is neither the initial nor a done status, and the task is
+// completed when it entered the done statuses it is still in.
+func analyzeTask(task *types.Tasks, workflow *types.Workflow, now time.Time) *types.TaskAnalytics {
+	analytics := &types.TaskAnalytics{
+		TaskID:        task.Id,
+		UserID:        task.UserID,
+		Category:      task.Category,
+		CurrentStatus: task.CurrentStatus,
+		TimeInStatus:  map[string]int64{},
+	}
+
+	history := make([]*types.Status, 0, len(task.StatusHistory))
+	for _, entry := range task.StatusHistory {
+		if entry != nil {
+			history = append(history, entry)
+		}
+	}
+	for i, entry := range history {
+		end := now
+		if i+1 < len(history) {
+			end = history[i+1].ChangedAt
+		}
+		if spent := end.Sub(entry.ChangedAt); spent > 0 {
+			analytics.TimeInStatus[entry.Status] += int64(spent / time.Second)
+		}
+		if i > 0 && entry.Status != history[i-1].Status {
+			analytics.Transitions++
+		}
+		if analytics.StartedAt == nil && entry.Status != "" && workflow.IsInProgress(entry.Status) {
+			startedAt := entry.ChangedAt
+			analytics.StartedAt = &startedAt
+		}
+	}
+
+	done := len(history)
+	for done > 0 && workflow.IsDone(history[done-1].Status) {
+		done--
+	}
+	if done == len(history) {
+		return analytics
+	}
+	completedAt := history[done].ChangedAt
+	analytics.CompletedAt = &completedAt
+	analytics.LeadTime = seconds(completedAt.Sub(task.CreatedAt))
+	if analytics.StartedAt != nil {
+		analytics.CycleTime = seconds(completedAt.Sub(*analytics.StartedAt))
+	}
+	return analytics
+}
+
+// aggregateTaskAnalytics averages the analytics of a group of tasks.
+func aggregateTaskAnalytics(key string, tasks []*types.TaskAnalytics) *types.TaskAnalyticsGroup {
+	group := &types.TaskAnalyticsGroup{
+		Key:                 key,
+		Tasks:               len(tasks),
+		AverageTimeInStatus: map[string]int64{},
+	}
+	inStatus := map[string]int64{}
+	var leadTotal, cycleTotal time.Duration
+	var cycles int
+	for _, task := range tasks {
+		for status, spent := range task.TimeInStatus {
+			group.AverageTimeInStatus[status] += spent
+			inStatus[status]++
+		}
+		if task.CompletedAt == nil {
+			continue
+		}
+		group.Completed++
+		leadTotal += time.Duration(*task.LeadTime) * time.Second
+		if task.CycleTime != nil {
+			cycleTotal += time.Duration(*task.CycleTime) * time.Second
+			cycles++
+		}
+	}
+	for status, total := range group.AverageTimeInStatus {
+		group.AverageTimeInStatus[status] = total / inStatus[status]
+	}
+	if group.Completed > 0 {
+		group.AverageLeadTime = seconds(leadTotal / time.Duration(group.Completed))
+	}
+	if cycles > 0 {
+		group.AverageCycleTime = seconds(cycleTotal / time.Duration(cycles))
+	}
+	return group
+}
+
+// seconds converts a duration to whole seconds, counting negative ones as 0.
+func seconds(d time.Duration) *int64 {
+	value := int64(max(d, 0) / time.Second)
+	return &value
+}
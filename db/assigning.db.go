@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListAssignees retrieves the users a task is assigned to, in the order they
+// were assigned. Users in the trash are left out.
+func (s *Store) ListAssignees(ctx context.Context, task *types.Tasks) ([]*types.UserResponse, error) {
+	users := []*types.UserResponse{}
+	for _, userId := range task.Assignees {
+		user, err := s.User.Get(ctx, userId)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// AssignTask assigns a task to a user and notifies them, unless they assigned
+// it to themselves. Assigning a task again to one of its assignees changes
+// nothing.
+func (s *Store) AssignTask(ctx context.Context, task *types.Tasks, userId primitive.ObjectID) (*types.Tasks, error) {
+	if task.HasAssignee(userId) {
+		return task, nil
+	}
+	assigned, err := s.Tasks.Assign(ctx, task.Id, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.Notify(ctx, &types.Notification{
+		UserID:   userId,
+		Type:     types.NotificationTaskAssigned,
+		ItemType: types.ShareTask,
+		ItemID:   task.Id,
+		Message:  fmt.Sprintf("You were assigned to the task %q", task.Title),
+	})
+	return assigned, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestAssignConcurrently checks that assignments made at the same time all
+// stick rather than overwriting each other.
+func TestAssignConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	owner := createTestUser(t, store, "owner")
+	task := createTestTask(t, store, owner.Id, "Shared")
+	var users []primitive.ObjectID
+	for i := 0; i < 20; i++ {
+		users = append(users, createTestUser(t, store, fmt.Sprintf("user%d", i)).Id)
+	}
+
+	concurrently(t, len(users), func(i int) error {
+		_, err := store.Tasks.Assign(ctx, task.Id, users[i])
+		return err
+	})
+	assigned, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assigned.Assignees) != len(users) {
+		t.Errorf("task has %d assignees, want %d", len(assigned.Assignees), len(users))
+	}
+
+	concurrently(t, 10, func(i int) error {
+		_, err := store.Tasks.Unassign(ctx, task.Id, users[i])
+		return err
+	})
+	unassigned, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unassigned.Assignees) != 10 {
+		t.Errorf("task has %d assignees after unassigning half, want 10", len(unassigned.Assignees))
+	}
+	if _, err := store.Tasks.Unassign(ctx, task.Id, users[0]); err != ErrNotFound {
+		t.Errorf("unassigning a user who is not assigned returned %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"golang-auth/types"
+	"io"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultAttachmentMaxSize is the largest file, in bytes, accepted as an
+// attachment unless Store.AttachmentMaxSize says otherwise.
+const DefaultAttachmentMaxSize = 10 << 20
+
+// DefaultAttachmentTypes are the content types accepted as attachments unless
+// Store.AttachmentTypes says otherwise. Office documents are detected as zip.
+var DefaultAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+	"application/zip",
+}
+
+// AddAttachment writes the contents of an uploaded file to blob storage and
+// saves its metadata, including the SHA-256 of the contents. attachment.Size
+// must be the exact length of body.
+func (s *Store) AddAttachment(ctx context.Context, attachment *types.Attachment, body io.Reader) (*types.Attachment, error) {
+	attachment.Id = primitive.NewObjectID()
+	hash := sha256.New()
+	if err := s.Blobs.Put(ctx, attachmentKey(attachment), io.TeeReader(body, hash), attachment.Size, attachment.ContentType); err != nil {
+		return nil, err
+	}
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	saved, err := s.Attachments.Create(ctx, attachment)
+	if err != nil {
+		s.removeBlobs(ctx, []*types.Attachment{attachment})
+		return nil, err
+	}
+	return saved, nil
+}
+
+// OpenAttachment returns the contents of an attachment
+func (s *Store) OpenAttachment(ctx context.Context, attachment *types.Attachment) (io.ReadCloser, error) {
+	return s.Blobs.Open(ctx, attachmentKey(attachment))
+}
+
+// RemoveAttachment deletes an attachment of an item together with its
+// contents and returns it
+func (s *Store) RemoveAttachment(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error) {
+	attachment, err := s.Attachments.Delete(ctx, itemType, itemId, id)
+	if err != nil {
+		return nil, err
+	}
+	s.removeBlobs(ctx, []*types.Attachment{attachment})
+	return attachment, nil
+}
+
+// removeBlobs deletes the contents of attachments whose metadata is already
+// gone. Failures only leave unreachable files behind, so they are logged
+// rather than returned.
+func (s *Store) removeBlobs(ctx context.Context, attachments []*types.Attachment) {
+	for _, attachment := range attachments {
+		if err := s.Blobs.Delete(ctx, attachmentKey(attachment)); err != nil {
+			log.Printf("Failed to remove attachment %s: %v", attachment.Id.Hex(), err)
+		}
+	}
+}
+
+// attachmentKey is where the contents of an attachment are kept in blob storage.
+func attachmentKey(attachment *types.Attachment) string {
+	return attachment.ItemType + "/" + attachment.ItemID.Hex() + "/" + attachment.Id.Hex()
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAttachmentsStore struct {
+	collection *mongo.Collection
+}
+
+// Create saves the metadata of an uploaded file and returns it. An id set by
+// the caller is kept so it can match the key the contents were stored under.
+func (a *MongoAttachmentsStore) Create(ctx context.Context, attachment *types.Attachment) (*types.Attachment, error) {
+	if attachment.Id.IsZero() {
+		attachment.Id = primitive.NewObjectID()
+	}
+	attachment.CreatedAt = timestamp()
+	attachment.CreatedBy = actorFrom(ctx)
+
+	if _, err := a.collection.InsertOne(ctx, attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Get retrieves an attachment of an item
+func (a *MongoAttachmentsStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error) {
+	var attachment types.Attachment
+	err := a.collection.FindOne(ctx, bson.M{"_id": id, "item_type": itemType, "item_id": itemId}).Decode(&attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListByItem retrieves the attachments of an item, oldest first
+func (a *MongoAttachmentsStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error) {
+	return a.find(ctx, bson.M{"item_type": itemType, "item_id": itemId})
+}
+
+// Delete removes an attachment of an item and returns it
+func (a *MongoAttachmentsStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error) {
+	var attachment types.Attachment
+	err := a.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "item_type": itemType, "item_id": itemId}).Decode(&attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// DeleteByItem removes every attachment of an item and returns them
+func (a *MongoAttachmentsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error) {
+	return a.deleteMatching(ctx, bson.M{"item_type": itemType, "item_id": itemId})
+}
+
+// DeleteOrphans removes the attachments of notes and tasks that no longer
+// exist and returns them
+func (a *MongoAttachmentsStore) DeleteOrphans(ctx context.Context) ([]*types.Attachment, error) {
+	deleted := []*types.Attachment{}
+	for itemType, collection := range map[string]string{types.ShareNote: "note", types.ShareTask: "task"} {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"item_type": itemType}}},
+			{{Key: "$group", Value: bson.M{"_id": "$item_id"}}},
+			{{Key: "$lookup", Value: bson.M{"from": collection, "localField": "_id", "foreignField": "_id", "as": "item"}}},
+			{{Key: "$match", Value: bson.M{"item": bson.M{"$size": 0}}}},
+		}
+		cursor, err := a.collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return deleted, err
+		}
+		var orphans []struct {
+			ItemId primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &orphans); err != nil {
+			return deleted, err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		itemIds := make([]primitive.ObjectID, len(orphans))
+		for i, orphan := range orphans {
+			itemIds[i] = orphan.ItemId
+		}
+		removed, err := a.deleteMatching(ctx, bson.M{"item_type": itemType, "item_id": bson.M{"$in": itemIds}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, removed...)
+	}
+	return deleted, nil
+}
+
+func (a *MongoAttachmentsStore) deleteMatching(ctx context.Context, filter bson.M) ([]*types.Attachment, error) {
+	attachments, err := a.find(ctx, filter)
+	if err != nil || len(attachments) == 0 {
+		return attachments, err
+	}
+	ids := make([]primitive.ObjectID, len(attachments))
+	for i, attachment := range attachments {
+		ids[i] = attachment.Id
+	}
+	if _, err := a.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (a *MongoAttachmentsStore) find(ctx context.Context, filter bson.M) ([]*types.Attachment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := a.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	attachments := []*types.Attachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
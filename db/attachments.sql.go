@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLAttachmentsStore struct {
+	db *sqlDB
+}
+
+// Create saves the metadata of an uploaded file and returns it. An id set by
+// the caller is kept so it can match the key the contents were stored under.
+func (a *SQLAttachmentsStore) Create(ctx context.Context, attachment *types.Attachment) (*types.Attachment, error) {
+	if attachment.Id.IsZero() {
+		attachment.Id = primitive.NewObjectID()
+	}
+	attachment.CreatedAt = timestamp()
+	attachment.CreatedBy = actorFrom(ctx)
+
+	data, err := marshalDoc(attachment)
+	if err != nil {
+		return nil, err
+	}
+	_, err = a.db.exec(ctx, "INSERT INTO attachments (id, item_type, item_id, created_at, data) VALUES (?, ?, ?, ?, ?)",
+		attachment.Id.Hex(), attachment.ItemType, attachment.ItemID.Hex(), sqlTime(attachment.CreatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Get retrieves an attachment of an item
+func (a *SQLAttachmentsStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error) {
+	var attachment types.Attachment
+	err := a.db.findDoc(ctx, &attachment, "SELECT data FROM attachments WHERE id = ? AND item_type = ? AND item_id = ?",
+		id.Hex(), itemType, itemId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListByItem retrieves the attachments of an item, oldest first
+func (a *SQLAttachmentsStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error) {
+	return listDocs[types.Attachment](ctx, a.db, "SELECT data FROM attachments WHERE item_type = ? AND item_id = ? ORDER BY created_at, id",
+		itemType, itemId.Hex())
+}
+
+// Delete removes an attachment of an item and returns it
+func (a *SQLAttachmentsStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error) {
+	attachment, err := a.Get(ctx, itemType, itemId, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.db.exec(ctx, "DELETE FROM attachments WHERE id = ?", attachment.Id.Hex()); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// DeleteByItem removes every attachment of an item and returns them
+func (a *SQLAttachmentsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error) {
+	attachments, err := a.ListByItem(ctx, itemType, itemId)
+	if err != nil || len(attachments) == 0 {
+		return attachments, err
+	}
+	if _, err := a.db.exec(ctx, "DELETE FROM attachments WHERE item_type = ? AND item_id = ?", itemType, itemId.Hex()); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// DeleteOrphans removes the attachments of notes and tasks that no longer
+// exist and returns them
+func (a *SQLAttachmentsStore) DeleteOrphans(ctx context.Context) ([]*types.Attachment, error) {
+	orphaned := `FROM attachments WHERE
+		(item_type = ? AND item_id NOT IN (SELECT id FROM notes)) OR
+		(item_type = ? AND item_id NOT IN (SELECT id FROM tasks))`
+	attachments, err := listDocs[types.Attachment](ctx, a.db, "SELECT data "+orphaned, types.ShareNote, types.ShareTask)
+	if err != nil || len(attachments) == 0 {
+		return attachments, err
+	}
+	if _, err := a.db.exec(ctx, "DELETE "+orphaned, types.ShareNote, types.ShareTask); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"slices"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrWIPLimit is returned when a task would enter a board column that
+	// already holds as many tasks as its WIP limit.
+	ErrWIPLimit = errors.New("the column is at its WIP limit")
+
+	// ErrBoardNeighbor is returned when a task would be placed next to a task
+	// that is not in the column it moves to.
+	ErrBoardNeighbor = errors.New("tasks can only be placed next to tasks of the column they move to")
+)
+
+// TaskBoard groups the live tasks of a user by status, one column per status
+// of their workflow in order, then one per status the workflow lacks.
+func (s *Store) TaskBoard(ctx context.Context, userId primitive.ObjectID) (*types.TaskBoard, error) {
+	workflow, err := s.TaskWorkflow(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Tasks.List(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LoadBlocked(ctx, tasks...); err != nil {
+		return nil, err
+	}
+	sortByRank(tasks)
+
+	board := &types.TaskBoard{Columns: []*types.BoardColumn{}}
+	columns := map[string]*types.BoardColumn{}
+	for _, status := range workflow.Statuses {
+		column := &types.BoardColumn{
+			Status:   status,
+			Done:     workflow.IsDone(status),
+			Known:    true,
+			WIPLimit: workflow.WIPLimits[status],
+			Tasks:    []*types.Tasks{},
+		}
+		board.Columns = append(board.Columns, column)
+		columns[status] = column
+	}
+	var unknown []string
+	for _, task := range tasks {
+		column, ok := columns[task.CurrentStatus]
+		if !ok {
+			column = &types.BoardColumn{Status: task.CurrentStatus, Tasks: []*types.Tasks{}}
+			columns[task.CurrentStatus] = column
+			unknown = append(unknown, task.CurrentStatus)
+		}
+		column.Tasks = append(column.Tasks, task)
+		column.Count++
+	}
+	slices.Sort(unknown)
+	for _, status := range unknown {
+		board.Columns = append(board.Columns, columns[status])
+	}
+	for _, column := range board.Columns {
+		column.OverLimit = column.WIPLimit > 0 && column.Count > column.WIPLimit
+	}
+	return board, nil
+}
+
+// CheckWIPLimit returns ErrWIPLimit when the column of status on the board
+// of a user cannot take in another task.
+func (s *Store) CheckWIPLimit(ctx context.Context, workflow *types.Workflow, userId primitive.ObjectID, status string) error {
+	limit := workflow.WIPLimits[status]
+	if limit == 0 {
+		return nil
+	}
+	tasks, err := s.Tasks.List(ctx, userId)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, task := range tasks {
+		if task.CurrentStatus == status {
+			count++
+		}
+	}
+	if count >= limit {
+		return ErrWIPLimit
+	}
+	return nil
+}
+
+// WithinWIPLimit runs write, which puts a task in the column of status on the
+// board of a user, unless the column is full and ErrWIPLimit is returned. The
+// column is counted and written in a transaction that holds the owner, so
+// that tasks added at once cannot overfill it.
+func (s *Store) WithinWIPLimit(ctx context.Context, workflow *types.Workflow, userId primitive.ObjectID, status string, write func(ctx context.Context) error) error {
+	if workflow.WIPLimits[status] == 0 {
+		return write(ctx)
+	}
+	return s.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.backend.lockUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := s.CheckWIPLimit(ctx, workflow, userId, status); err != nil {
+			return err
+		}
+		return write(ctx)
+	})
+}
+
+// MoveOnBoard places a task right after the task afterId or right before the
+// task beforeId of its column, or at the end of it when both are nil. When
+// status is set the task moves to that column and the status is appended to
+// its history in the same write. Only the moved task is changed, unless
+// other tasks of the user share a rank and have to be told apart first.
+// The board is read and written in a transaction that holds its owner, so
+// that tasks moved at once cannot overfill a column or take the same rank.
+func (s *Store) MoveOnBoard(ctx context.Context, task *types.Tasks, status *types.Status, afterId *primitive.ObjectID, beforeId *primitive.ObjectID) (*types.Tasks, error) {
+	var moved *types.Tasks
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.backend.lockUser(ctx, task.UserID); err != nil {
+			return err
+		}
+		var err error
+		moved, err = s.moveOnBoard(ctx, task, status, afterId, beforeId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// moveOnBoard does the work of MoveOnBoard once the board is held.
+func (s *Store) moveOnBoard(ctx context.Context, task *types.Tasks, status *types.Status, afterId *primitive.ObjectID, beforeId *primitive.ObjectID) (*types.Tasks, error) {
+	target := task.CurrentStatus
+	if status != nil {
+		target = status.Status
+	}
+	tasks, err := s.Tasks.List(ctx, task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	sortByRank(tasks)
+	if err := s.repairRanks(ctx, tasks); err != nil {
+		return nil, err
+	}
+	var column []*types.Tasks
+	for _, t := range tasks {
+		if t.CurrentStatus == target && t.Id != task.Id {
+			column = append(column, t)
+		}
+	}
+
+	if status != nil {
+		workflow, err := s.TaskWorkflow(ctx, task.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if limit := workflow.WIPLimits[target]; limit > 0 && len(column) >= limit {
+			return nil, ErrWIPLimit
+		}
+	}
+
+	var before, after string
+	switch {
+	case afterId != nil:
+		i := slices.IndexFunc(column, func(t *types.Tasks) bool { return t.Id == *afterId })
+		if i < 0 {
+			return nil, ErrBoardNeighbor
+		}
+		before = column[i].Rank
+		if i+1 < len(column) {
+			after = column[i+1].Rank
+		}
+	case beforeId != nil:
+		i := slices.IndexFunc(column, func(t *types.Tasks) bool { return t.Id == *beforeId })
+		if i < 0 {
+			return nil, ErrBoardNeighbor
+		}
+		after = column[i].Rank
+		if i > 0 {
+			before = column[i-1].Rank
+		}
+	case len(column) > 0:
+		before = column[len(column)-1].Rank
+	}
+	rank, err := utils.RankBetween(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return s.Tasks.SetRank(ctx, task.Id, rank, status)
+}
+
+// repairRanks gives tasks sorted by rank distinct valid ranks in the same
+// order. Tasks created at the same time, or handed over from another user,
+// can share a rank, leaving no room to place a task between them. Only the
+// tasks that tie with or sort before the one ahead of them are rewritten.
+func (s *Store) repairRanks(ctx context.Context, tasks []*types.Tasks) error {
+	previous := ""
+	for i, task := range tasks {
+		if utils.ValidRank(task.Rank) && task.Rank > previous {
+			previous = task.Rank
+			continue
+		}
+		next := ""
+		for _, later := range tasks[i+1:] {
+			if later.Rank > previous && utils.ValidRank(later.Rank) {
+				next = later.Rank
+				break
+			}
+		}
+		rank, err := utils.RankBetween(previous, next)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Tasks.SetRank(ctx, task.Id, rank, nil); err != nil {
+			return err
+		}
+		task.Rank = rank
+		previous = rank
+	}
+	return nil
+}
+
+// sortByRank orders tasks as they appear on the board, oldest first among
+// tasks of the same rank.
+func sortByRank(tasks []*types.Tasks) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Rank != tasks[j].Rank {
+			return tasks[i].Rank < tasks[j].Rank
+		}
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].Id.Hex() < tasks[j].Id.Hex()
+	})
+}
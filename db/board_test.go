@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// columnTitles lists the titles of the tasks in the column of status on the
+// board of userId, in board order.
+func columnTitles(t *testing.T, store *Store, userId primitive.ObjectID, status string) []string {
+	t.Helper()
+	board, err := store.TaskBoard(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, column := range board.Columns {
+		if column.Status == status {
+			for _, task := range column.Tasks {
+				titles = append(titles, task.Title)
+			}
+		}
+	}
+	return titles
+}
+
+func equalTitles(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMoveOnBoard(t *testing.T) {
+	tests := []struct {
+		name   string
+		move   string // Title of the task to move
+		after  string // Title of the task to place it after
+		before string // Title of the task to place it before
+		want   []string
+		err    error
+	}{
+		{name: "to the end", move: "A", want: []string{"B", "C", "A"}},
+		{name: "after a task", move: "A", after: "B", want: []string{"B", "A", "C"}},
+		{name: "after the last task", move: "A", after: "C", want: []string{"B", "C", "A"}},
+		{name: "before a task", move: "C", before: "B", want: []string{"A", "C", "B"}},
+		{name: "before the first task", move: "C", before: "A", want: []string{"C", "A", "B"}},
+		{name: "next to itself", move: "A", after: "A", err: ErrBoardNeighbor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestSQLStore(t)
+			user := createTestUser(t, store, "owner")
+			tasks := map[string]*types.Tasks{}
+			for _, title := range []string{"A", "B", "C"} {
+				tasks[title] = createTestTask(t, store, user.Id, title)
+			}
+			var afterId, beforeId *primitive.ObjectID
+			if tt.after != "" {
+				afterId = &tasks[tt.after].Id
+			}
+			if tt.before != "" {
+				beforeId = &tasks[tt.before].Id
+			}
+
+			_, err := store.MoveOnBoard(ctx, tasks[tt.move], nil, afterId, beforeId)
+			if err != tt.err {
+				t.Fatalf("MoveOnBoard returned %v, want %v", err, tt.err)
+			}
+			if err == nil {
+				if got := columnTitles(t, store, user.Id, "todo"); !equalTitles(got, tt.want) {
+					t.Errorf("column is %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveOnBoardToColumn(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	a := createTestTask(t, store, user.Id, "A")
+	b := createTestTask(t, store, user.Id, "B")
+	c := createTestTask(t, store, user.Id, "C")
+	workflow := DefaultWorkflow()
+	workflow.UserID = user.Id
+	workflow.WIPLimits = map[string]int{"in_progress": 2}
+	if _, err := store.Workflows.Upsert(ctx, workflow); err != nil {
+		t.Fatal(err)
+	}
+	start := func(task *types.Tasks, beforeId *primitive.ObjectID) (*types.Tasks, error) {
+		status := &types.Status{Status: "in_progress", PreviousStatus: "todo", UserId: user.Id.Hex()}
+		return store.MoveOnBoard(ctx, task, status, nil, beforeId)
+	}
+
+	moved, err := start(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.CurrentStatus != "in_progress" || len(moved.StatusHistory) != 2 {
+		t.Errorf("moved task has status %q and %d history entries, want in_progress and 2", moved.CurrentStatus, len(moved.StatusHistory))
+	}
+	if _, err := start(b, &a.Id); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := columnTitles(t, store, user.Id, "in_progress"), []string{"B", "A"}; !equalTitles(got, want) {
+		t.Errorf("in_progress column is %v, want %v", got, want)
+	}
+	if _, err := start(c, nil); err != ErrWIPLimit {
+		t.Errorf("moving past the WIP limit returned %v, want ErrWIPLimit", err)
+	}
+	if _, err := start(c, &a.Id); err != ErrWIPLimit {
+		t.Errorf("moving next to a task of a full column returned %v, want ErrWIPLimit", err)
+	}
+	if got, want := columnTitles(t, store, user.Id, "todo"), []string{"C"}; !equalTitles(got, want) {
+		t.Errorf("todo column is %v, want %v", got, want)
+	}
+}
+
+func TestMoveOnBoardConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	workflow := DefaultWorkflow()
+	workflow.UserID = user.Id
+	workflow.WIPLimits = map[string]int{"in_progress": 2}
+	if _, err := store.Workflows.Upsert(ctx, workflow); err != nil {
+		t.Fatal(err)
+	}
+	var tasks []*types.Tasks
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, createTestTask(t, store, user.Id, fmt.Sprintf("Task %d", i)))
+	}
+
+	concurrently(t, len(tasks), func(i int) error {
+		status := &types.Status{Status: "in_progress", PreviousStatus: "todo", UserId: user.Id.Hex()}
+		if _, err := store.MoveOnBoard(ctx, tasks[i], status, nil, nil); err != nil && err != ErrWIPLimit {
+			return err
+		}
+		return nil
+	})
+	board, err := store.TaskBoard(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range board.Columns {
+		if column.Status != "in_progress" {
+			continue
+		}
+		if column.Count != 2 {
+			t.Errorf("in_progress column holds %d tasks, want its WIP limit of 2", column.Count)
+		}
+		for i := 1; i < len(column.Tasks); i++ {
+			if column.Tasks[i].Rank <= column.Tasks[i-1].Rank {
+				t.Errorf("tasks moved at once share rank %q", column.Tasks[i].Rank)
+			}
+		}
+	}
+}
+
+func TestWithinWIPLimitConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	// limitedBoard returns a user whose in_progress column takes 2 tasks
+	limitedBoard := func(name string) (*types.UserResponse, *types.Workflow) {
+		user := createTestUser(t, store, name)
+		workflow := DefaultWorkflow()
+		workflow.UserID = user.Id
+		workflow.WIPLimits = map[string]int{"in_progress": 2}
+		if _, err := store.Workflows.Upsert(ctx, workflow); err != nil {
+			t.Fatal(err)
+		}
+		return user, workflow
+	}
+	inProgress := func(userId primitive.ObjectID) int {
+		tasks, err := store.Tasks.List(ctx, userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, task := range tasks {
+			if task.CurrentStatus == "in_progress" {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("create", func(t *testing.T) {
+		user, workflow := limitedBoard("creator")
+		concurrently(t, 10, func(i int) error {
+			err := store.WithinWIPLimit(ctx, workflow, user.Id, "in_progress", func(ctx context.Context) error {
+				_, err := store.Tasks.Create(ctx, &types.TasksCreate{
+					Title:         fmt.Sprintf("Task %d", i),
+					UserID:        user.Id,
+					StatusHistory: []*types.Status{{Status: "in_progress", UserId: user.Id.Hex()}},
+				})
+				return err
+			})
+			if err != nil && err != ErrWIPLimit {
+				return err
+			}
+			return nil
+		})
+		if count := inProgress(user.Id); count != 2 {
+			t.Errorf("in_progress column holds %d created tasks, want its WIP limit of 2", count)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		user, workflow := limitedBoard("mover")
+		var tasks []*types.Tasks
+		for i := 0; i < 10; i++ {
+			tasks = append(tasks, createTestTask(t, store, user.Id, fmt.Sprintf("Task %d", i)))
+		}
+		concurrently(t, len(tasks), func(i int) error {
+			err := store.WithinWIPLimit(ctx, workflow, user.Id, "in_progress", func(ctx context.Context) error {
+				_, err := store.Tasks.Update(ctx, tasks[i].Id, &types.TasksUpdate{
+					Title:  tasks[i].Title,
+					Status: &types.Status{Status: "in_progress", PreviousStatus: "todo", UserId: user.Id.Hex()},
+				})
+				return err
+			})
+			if err != nil && err != ErrWIPLimit {
+				return err
+			}
+			return nil
+		})
+		if count := inProgress(user.Id); count != 2 {
+			t.Errorf("in_progress column holds %d moved tasks, want its WIP limit of 2", count)
+		}
+	})
+}
+
+// TestMoveOnBoardEqualRanks covers tasks created concurrently or handed over
+// from another user, which can end up with the same rank.
+func TestMoveOnBoardEqualRanks(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	var tasks []*types.Tasks
+	for _, title := range []string{"A", "B", "C", "D"} {
+		task := createTestTask(t, store, user.Id, title)
+		// A, B and C share a rank; D keeps its own
+		if title != "D" {
+			var err error
+			if task, err = store.Tasks.SetRank(ctx, task.Id, "a0", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+
+	if _, err := store.MoveOnBoard(ctx, tasks[3], nil, &tasks[0].Id, nil); err != nil {
+		t.Fatalf("moving between tasks of equal rank returned %v", err)
+	}
+	if got, want := columnTitles(t, store, user.Id, "todo"), []string{"A", "D", "B", "C"}; !equalTitles(got, want) {
+		t.Errorf("column is %v, want %v", got, want)
+	}
+	if _, err := store.MoveOnBoard(ctx, tasks[2], nil, nil, &tasks[1].Id); err != nil {
+		t.Fatalf("moving after repairing ranks returned %v", err)
+	}
+	if got, want := columnTitles(t, store, user.Id, "todo"), []string{"A", "D", "C", "B"}; !equalTitles(got, want) {
+		t.Errorf("column is %v, want %v", got, want)
+	}
+
+	listed, err := store.Tasks.List(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranks := map[string]bool{}
+	for _, task := range listed {
+		if ranks[task.Rank] {
+			t.Errorf("rank %q is still shared", task.Rank)
+		}
+		ranks[task.Rank] = true
+	}
+}
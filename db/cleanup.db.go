@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTransferTargetNotFound is returned by DeleteUser when the user that should
+// receive the deleted user's data does not exist.
+var ErrTransferTargetNotFound = errors.New("transfer target user not found")
+
+// DeleteUser moves a user to the trash together with every note and task they
+// own, in a single transaction where the backend supports one. The notes and
+// tasks share the user's deleted_at so RestoreUser can bring them back. When
+// transferTo is set the notes, tasks and tags are reassigned to that user instead.
+func (s *Store) DeleteUser(ctx context.Context, id primitive.ObjectID, transferTo *primitive.ObjectID) (*types.UserDeletionReport, error) {
+	var report *types.UserDeletionReport
+
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		// Transactions may be retried, so start from an empty report every time
+		report = &types.UserDeletionReport{TransferredTo: transferTo}
+
+		user, err := s.User.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		report.User = user
+
+		if transferTo != nil {
+			if _, err := s.User.Get(ctx, *transferTo); err != nil {
+				if err == ErrNotFound {
+					return ErrTransferTargetNotFound
+				}
+				return err
+			}
+			report.NotesTransferred, err = s.Notes.TransferOwner(ctx, id, *transferTo)
+			if err != nil {
+				return err
+			}
+			report.TasksTransferred, err = s.Tasks.TransferOwner(ctx, id, *transferTo)
+			if err != nil {
+				return err
+			}
+			return s.transferTags(ctx, id, *transferTo)
+		}
+
+		report.NotesDeleted, err = s.Notes.TrashByUser(ctx, id, *user.DeletedAt)
+		if err != nil {
+			return err
+		}
+		report.TasksDeleted, err = s.Tasks.TrashByUser(ctx, id, *user.DeletedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// RestoreUser takes a user out of the trash along with the notes and tasks
+// that were trashed when the user was deleted.
+func (s *Store) RestoreUser(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	var restored *types.UserResponse
+
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		trashed, err := s.User.GetTrashed(ctx, id)
+		if err != nil {
+			return err
+		}
+		restored, err = s.User.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Notes.RestoreByUser(ctx, id, *trashed.DeletedAt); err != nil {
+			return err
+		}
+		_, err = s.Tasks.RestoreByUser(ctx, id, *trashed.DeletedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// PurgeUser permanently removes a trashed user, their task workflow, their
+// notifications, comments and time entries and every note, task and tag they
+// still own, along with the history, shares, public links, comments, time
+// entries and attachments of those items and the shares granted to them. The user is also taken off the
+// tasks they were assigned to. The avatar file is left to the caller.
+func (s *Store) PurgeUser(ctx context.Context, id primitive.ObjectID) (*types.UserDeletionReport, error) {
+	var report *types.UserDeletionReport
+	var attachments []*types.Attachment
+
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		report = &types.UserDeletionReport{}
+
+		user, err := s.User.Purge(ctx, id)
+		if err != nil {
+			return err
+		}
+		report.User = user
+
+		report.NotesDeleted, err = s.Notes.DeleteByUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		report.TasksDeleted, err = s.Tasks.DeleteByUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Tags.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Workflows.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Shares.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Shares.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		if _, err := s.Tasks.UnassignUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Notifications.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Notifications.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		if _, err := s.Comments.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Comments.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		if _, err := s.TimeEntries.DeleteByUser(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.TimeEntries.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		if _, err := s.NoteLinks.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		if _, err := s.NoteRevisions.DeleteOrphans(ctx); err != nil {
+			return err
+		}
+		attachments, err = s.Attachments.DeleteOrphans(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.removeBlobs(ctx, attachments)
+	return report, nil
+}
+
+// PurgeTrash permanently removes users, notes and tasks that were moved to
+// the trash before the given time, and the history, shares, public links,
+// attachments, notifications, comments and time entries of the removed notes
+// and tasks. Other tasks stop waiting for the removed tasks.
+func (s *Store) PurgeTrash(ctx context.Context, before time.Time) (*types.TrashPurgeReport, error) {
+	report := &types.TrashPurgeReport{
+		Users:  []*types.UserDeletionReport{},
+		Before: before,
+	}
+
+	users, err := s.User.ListDeletedBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		userReport, err := s.PurgeUser(ctx, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		report.Users = append(report.Users, userReport)
+	}
+
+	report.Notes, err = s.Notes.PurgeDeletedBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	report.Tasks, err = s.Tasks.PurgeDeletedBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Tasks.DeleteDanglingBlockers(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.NoteRevisions.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.Shares.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.NoteLinks.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.Notifications.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.Comments.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.TimeEntries.DeleteOrphans(ctx); err != nil {
+		return nil, err
+	}
+	attachments, err := s.Attachments.DeleteOrphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.removeBlobs(ctx, attachments)
+	return report, nil
+}
+
+// PurgeNote permanently removes a trashed note, its history, shares, public
+// links, attachments, notifications and comments.
+func (s *Store) PurgeNote(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	var purged *types.Notes
+	var attachments []*types.Attachment
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		purged, err = s.Notes.Purge(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.NoteRevisions.DeleteByNote(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Shares.DeleteByItem(ctx, types.ShareNote, id); err != nil {
+			return err
+		}
+		if _, err := s.NoteLinks.DeleteByNote(ctx, id); err != nil {
+			return err
+		}
+		if _, err := s.Notifications.DeleteByItem(ctx, types.ShareNote, id); err != nil {
+			return err
+		}
+		if _, err := s.Comments.DeleteByItem(ctx, types.ShareNote, id); err != nil {
+			return err
+		}
+		attachments, err = s.Attachments.DeleteByItem(ctx, types.ShareNote, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.removeBlobs(ctx, attachments)
+	return purged, nil
+}
+
+// PurgeTask permanently removes a trashed task and its subtasks, along with
+// their shares, attachments, notifications, comments and time entries. Other
+// tasks stop waiting for them.
+func (s *Store) PurgeTask(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	var purged *types.Tasks
+	var attachments []*types.Attachment
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		// Transactions may be retried, so start from no attachments every time
+		attachments = nil
+
+		subtasks, err := s.Tasks.ListDescendants(ctx, id)
+		if err != nil {
+			return err
+		}
+		purged, err = s.Tasks.Purge(ctx, id)
+		if err != nil {
+			return err
+		}
+		ids := []primitive.ObjectID{id}
+		for _, subtask := range subtasks {
+			ids = append(ids, subtask.Id)
+		}
+		for _, taskId := range ids {
+			if _, err := s.Shares.DeleteByItem(ctx, types.ShareTask, taskId); err != nil {
+				return err
+			}
+			if _, err := s.Notifications.DeleteByItem(ctx, types.ShareTask, taskId); err != nil {
+				return err
+			}
+			if _, err := s.Comments.DeleteByItem(ctx, types.ShareTask, taskId); err != nil {
+				return err
+			}
+			if _, err := s.TimeEntries.DeleteByTask(ctx, taskId); err != nil {
+				return err
+			}
+			if _, err := s.Tasks.UnblockAll(ctx, taskId); err != nil {
+				return err
+			}
+			removed, err := s.Attachments.DeleteByItem(ctx, types.ShareTask, taskId)
+			if err != nil {
+				return err
+			}
+			attachments = append(attachments, removed...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.removeBlobs(ctx, attachments)
+	return purged, nil
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCommentParent is returned when replying to a comment that is not on the
+// item the reply is left on.
+var ErrCommentParent = errors.New("the comment replied to is not on this item")
+
+// AddComment saves a comment on an item and notifies the users it mentions.
+// A reply to a reply joins the thread of the comment that started it.
+func (s *Store) AddComment(ctx context.Context, comment *types.Comment) (*types.Comment, error) {
+	if comment.ParentID != nil {
+		parent, err := s.Comments.Get(ctx, comment.ItemType, comment.ItemID, *comment.ParentID)
+		if err == ErrNotFound {
+			return nil, ErrCommentParent
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.ParentID != nil {
+			comment.ParentID = parent.ParentID
+		}
+	}
+	title, audience, err := s.commentAudience(ctx, comment.ItemType, comment.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	comment.Mentions, err = s.resolveMentions(ctx, audience, comment.Body)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.Comments.Create(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyMentions(ctx, created, title, created.Mentions)
+	return created, nil
+}
+
+// EditComment replaces the body of a comment and notifies the users it
+// mentions for the first time.
+func (s *Store) EditComment(ctx context.Context, comment *types.Comment, body string) (*types.Comment, error) {
+	title, audience, err := s.commentAudience(ctx, comment.ItemType, comment.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := s.resolveMentions(ctx, audience, body)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.Comments.Update(ctx, comment.ItemType, comment.ItemID, comment.Id, body, mentions)
+	if err != nil {
+		return nil, err
+	}
+	var added []primitive.ObjectID
+	for _, userId := range updated.Mentions {
+		if !slices.Contains(comment.Mentions, userId) {
+			added = append(added, userId)
+		}
+	}
+	s.notifyMentions(ctx, updated, title, added)
+	return updated, nil
+}
+
+// CommentThreads retrieves the comments of an item oldest first, each with
+// its replies oldest first.
+func (s *Store) CommentThreads(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Comment, error) {
+	comments, err := s.Comments.ListByItem(ctx, itemType, itemId)
+	if err != nil {
+		return nil, err
+	}
+	threads := []*types.Comment{}
+	byId := map[primitive.ObjectID]*types.Comment{}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			threads = append(threads, comment)
+			byId[comment.Id] = comment
+		}
+	}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			continue
+		}
+		if parent, ok := byId[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		} else {
+			// Keep replies whose comment is gone rather than hide them
+			threads = append(threads, comment)
+		}
+	}
+	return threads, nil
+}
+
+// LoadTaskCommentCounts sets how many comments each task has.
+func (s *Store) LoadTaskCommentCounts(ctx context.Context, tasks ...*types.Tasks) error {
+	ids := make([]primitive.ObjectID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	counts, err := s.Comments.CountByItems(ctx, types.ShareTask, ids)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		count := counts[task.Id]
+		task.CommentCount = &count
+	}
+	return nil
+}
+
+// LoadNoteCommentCounts sets how many comments each note has.
+func (s *Store) LoadNoteCommentCounts(ctx context.Context, notes ...*types.Notes) error {
+	ids := make([]primitive.ObjectID, len(notes))
+	for i, note := range notes {
+		ids[i] = note.Id
+	}
+	counts, err := s.Comments.CountByItems(ctx, types.ShareNote, ids)
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		count := counts[note.Id]
+		note.CommentCount = &count
+	}
+	return nil
+}
+
+// commentAudience returns the title of an item and the users who can read it
+// without being admins: the owner of a note and the users it is shared with,
+// or the owner and assignees of a task and the users it or one of its parents
+// is shared with.
+func (s *Store) commentAudience(ctx context.Context, itemType string, itemId primitive.ObjectID) (string, []primitive.ObjectID, error) {
+	var title string
+	var audience, shared []primitive.ObjectID
+	switch itemType {
+	case types.ShareNote:
+		note, err := s.Notes.Get(ctx, itemId)
+		if err != nil {
+			return "", nil, err
+		}
+		title, audience, shared = note.Title, []primitive.ObjectID{note.UserID}, []primitive.ObjectID{note.Id}
+	case types.ShareTask:
+		task, err := s.Tasks.Get(ctx, itemId)
+		if err != nil {
+			return "", nil, err
+		}
+		title = task.Title
+		audience = append([]primitive.ObjectID{task.UserID}, task.Assignees...)
+		shared = append([]primitive.ObjectID{task.Id}, task.Ancestors...)
+	default:
+		return "", nil, fmt.Errorf("unknown item type %q", itemType)
+	}
+	for _, id := range shared {
+		shares, err := s.Shares.ListByItem(ctx, itemType, id)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, share := range shares {
+			audience = append(audience, share.UserID)
+		}
+	}
+	slices.SortFunc(audience, func(a, b primitive.ObjectID) int { return strings.Compare(a.Hex(), b.Hex()) })
+	return title, slices.Compact(audience), nil
+}
+
+// resolveMentions finds the users of audience that body mentions, by email or
+// by name. Names shared by several users of the audience are ambiguous and
+// mention no one. Users in the trash are left out.
+func (s *Store) resolveMentions(ctx context.Context, audience []primitive.ObjectID, body string) ([]primitive.ObjectID, error) {
+	mentions := utils.ParseMentions(body)
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+	var users []*types.UserResponse
+	names := map[string]int{}
+	for _, userId := range audience {
+		user, err := s.User.Get(ctx, userId)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+		names[utils.MentionName(user.Name)]++
+	}
+
+	var mentioned []primitive.ObjectID
+	for _, mention := range mentions {
+		for _, user := range users {
+			matches := mention.Email != "" && strings.EqualFold(user.Email, mention.Email) ||
+				mention.Name != "" && names[mention.Name] == 1 && utils.MentionName(user.Name) == mention.Name
+			if matches && !slices.Contains(mentioned, user.Id) {
+				mentioned = append(mentioned, user.Id)
+			}
+		}
+	}
+	return mentioned, nil
+}
+
+// notifyMentions tells the given users they were mentioned in a comment.
+func (s *Store) notifyMentions(ctx context.Context, comment *types.Comment, title string, userIds []primitive.ObjectID) {
+	for _, userId := range userIds {
+		s.Notify(ctx, &types.Notification{
+			UserID:   userId,
+			Type:     types.NotificationMentioned,
+			ItemType: comment.ItemType,
+			ItemID:   comment.ItemID,
+			Message:  fmt.Sprintf("You were mentioned in a comment on the %s %q", comment.ItemType, title),
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mentionTest is a note shared with some users and not others, who have
+// names that mentions may confuse.
+type mentionTest struct {
+	store                          *Store
+	note                           *types.Notes
+	owner, jane, sam, samuel, olga primitive.ObjectID
+}
+
+func newMentionTest(t *testing.T) *mentionTest {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := func(name, email string) primitive.ObjectID {
+		t.Helper()
+		user, err := store.User.Create(ctx, &types.UserCreate{Name: name, Email: email, Password: "password"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.Id
+	}
+	m := &mentionTest{
+		store:  store,
+		owner:  user("Owner", "owner@example.com"),
+		jane:   user("Jane", "jane@example.com"),
+		sam:    user("Sam Lee", "sam@example.com"),
+		samuel: user("sam.lee", "samuel@example.com"),
+		olga:   user("Olga", "olga@example.com"),
+	}
+	m.note = createTestNote(t, store, m.owner, "Plan")
+	for _, userId := range []primitive.ObjectID{m.jane, m.sam, m.samuel} {
+		_, err := store.Shares.Upsert(ctx, &types.Share{ItemType: types.ShareNote, ItemID: m.note.Id, UserID: userId, Permission: types.PermissionView})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestResolveMentions(t *testing.T) {
+	ctx := context.Background()
+	m := newMentionTest(t)
+	_, audience, err := m.store.commentAudience(ctx, types.ShareNote, m.note.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []primitive.ObjectID
+	}{
+		{"by name", "@jane have a look", []primitive.ObjectID{m.jane}},
+		{"by email", "@JANE@example.com.", []primitive.ObjectID{m.jane}},
+		{"plain email address", "write to jane@example.com", nil},
+		{"name and email once", "@jane and @jane@example.com", []primitive.ObjectID{m.jane}},
+		{"ambiguous name", "@samlee or @Sam_Lee", nil},
+		{"email of an ambiguous name", "@samuel@example.com", []primitive.ObjectID{m.samuel}},
+		{"outside the audience by name", "@olga", nil},
+		{"outside the audience by email", "@olga@example.com", nil},
+		{"unknown user", "@nobody @nobody@example.com", nil},
+		{"in order", "@samuel@example.com then @owner", []primitive.ObjectID{m.samuel, m.owner}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.store.resolveMentions(ctx, audience, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%q mentions %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+
+	// Once one of them is in the trash the name is no longer ambiguous
+	if _, err := m.store.User.Delete(ctx, m.samuel); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.store.resolveMentions(ctx, audience, "@samlee @samuel@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []primitive.ObjectID{m.sam}) {
+		t.Errorf("with one of two namesakes in the trash the mention resolves to %v, want %v", got, m.sam)
+	}
+}
+
+func TestCommentMentionNotifications(t *testing.T) {
+	m := newMentionTest(t)
+	ctx := WithActor(context.Background(), m.owner)
+	mentions := func(userId primitive.ObjectID) int {
+		t.Helper()
+		notifications, err := m.store.Notifications.ListByUser(ctx, userId, false, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, notification := range notifications {
+			if notification.Type == types.NotificationMentioned && notification.ItemID == m.note.Id {
+				count++
+			}
+		}
+		return count
+	}
+	expect := func(step string, want map[primitive.ObjectID]int) {
+		t.Helper()
+		for userId, name := range map[primitive.ObjectID]string{m.owner: "owner", m.jane: "jane", m.sam: "sam", m.samuel: "samuel", m.olga: "olga"} {
+			if got := mentions(userId); got != want[userId] {
+				t.Errorf("after %s %s has %d mention notifications, want %d", step, name, got, want[userId])
+			}
+		}
+	}
+
+	comment, err := m.store.AddComment(ctx, &types.Comment{
+		ItemType: types.ShareNote,
+		ItemID:   m.note.Id,
+		UserID:   m.owner,
+		Body:     "@jane @olga @samlee and me, @owner",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(comment.Mentions, []primitive.ObjectID{m.jane, m.owner}) {
+		t.Errorf("comment mentions %v, want jane and the owner", comment.Mentions)
+	}
+	expect("the comment", map[primitive.ObjectID]int{m.jane: 1})
+
+	comment, err = m.store.EditComment(ctx, comment, "@jane, @sam@example.com and @olga@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect("the first edit", map[primitive.ObjectID]int{m.jane: 1, m.sam: 1})
+
+	if _, err = m.store.EditComment(ctx, comment, "@sam@example.com and @jane, again"); err != nil {
+		t.Fatal(err)
+	}
+	expect("the second edit", map[primitive.ObjectID]int{m.jane: 1, m.sam: 1})
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoCommentsStore struct {
+	collection *mongo.Collection
+}
+
+// Create inserts a new comment and returns it
+func (c *MongoCommentsStore) Create(ctx context.Context, comment *types.Comment) (*types.Comment, error) {
+	newComment := *comment
+	newComment.Id = primitive.NewObjectID()
+	newComment.Mentions = idList(comment.Mentions)
+	newComment.Edits = []*types.CommentEdit{}
+	newComment.Replies = nil
+	newComment.CreatedAt = timestamp()
+	newComment.UpdatedAt = newComment.CreatedAt
+	if _, err := c.collection.InsertOne(ctx, newComment); err != nil {
+		return nil, err
+	}
+	return &newComment, nil
+}
+
+// Get retrieves a comment of an item
+func (c *MongoCommentsStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Comment, error) {
+	var comment types.Comment
+	err := c.collection.FindOne(ctx, bson.M{"_id": id, "item_type": itemType, "item_id": itemId}).Decode(&comment)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListByItem retrieves the comments and replies of an item, oldest first
+func (c *MongoCommentsStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Comment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := c.collection.Find(ctx, bson.M{"item_type": itemType, "item_id": itemId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	comments := []*types.Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Update replaces the body and mentions of a comment, keeping the previous
+// body in its edits, and returns it. Nothing changes when the body is the same.
+func (c *MongoCommentsStore) Update(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID, body string, mentions []primitive.ObjectID) (*types.Comment, error) {
+	comment, err := c.Get(ctx, itemType, itemId, id)
+	if err != nil || comment.Body == body {
+		return comment, err
+	}
+	now := timestamp()
+	// Only replace the body that was read so concurrent edits both end up in
+	// the history
+	filter := bson.M{"_id": id, "body": comment.Body}
+	update := bson.M{
+		"$set":  bson.M{"body": body, "mentions": idList(mentions), "updated_at": now},
+		"$push": bson.M{"edits": &types.CommentEdit{Body: comment.Body, EditedAt: now}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated types.Comment
+	err = c.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	if err == ErrNotFound {
+		return c.Update(ctx, itemType, itemId, id, body, mentions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete removes a comment of an item and the replies to it, and returns how
+// many comments were removed
+func (c *MongoCommentsStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (int64, error) {
+	if _, err := c.Get(ctx, itemType, itemId, id); err != nil {
+		return 0, err
+	}
+	filter := bson.M{"item_type": itemType, "item_id": itemId, "$or": bson.A{bson.M{"_id": id}, bson.M{"parent_id": id}}}
+	result, err := c.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// CountByItems counts the comments and replies of each of the given items
+func (c *MongoCommentsStore) CountByItems(ctx context.Context, itemType string, itemIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	counts := map[primitive.ObjectID]int64{}
+	if len(itemIds) == 0 {
+		return counts, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"item_type": itemType, "item_id": bson.M{"$in": itemIds}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$item_id", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := c.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ItemId primitive.ObjectID `bson:"_id"`
+		Count  int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ItemId] = row.Count
+	}
+	return counts, nil
+}
+
+// DeleteByItem removes every comment of an item
+func (c *MongoCommentsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := c.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": itemId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByUser removes every comment written by a user and the replies to them
+func (c *MongoCommentsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	ids, err := c.collection.Distinct(ctx, "_id", bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	filter := bson.M{"$or": bson.A{bson.M{"user_id": userId}, bson.M{"parent_id": bson.M{"$in": ids}}}}
+	result, err := c.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the comments of notes and tasks that no longer exist
+func (c *MongoCommentsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	var deleted int64
+	for itemType, collection := range map[string]string{types.ShareNote: "note", types.ShareTask: "task"} {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"item_type": itemType}}},
+			{{Key: "$group", Value: bson.M{"_id": "$item_id"}}},
+			{{Key: "$lookup", Value: bson.M{"from": collection, "localField": "_id", "foreignField": "_id", "as": "item"}}},
+			{{Key: "$match", Value: bson.M{"item": bson.M{"$size": 0}}}},
+		}
+		cursor, err := c.collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return deleted, err
+		}
+		var orphans []struct {
+			ItemId primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &orphans); err != nil {
+			return deleted, err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		itemIds := make([]primitive.ObjectID, len(orphans))
+		for i, orphan := range orphans {
+			itemIds[i] = orphan.ItemId
+		}
+		result, err := c.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": bson.M{"$in": itemIds}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLCommentsStore struct {
+	db *sqlDB
+}
+
+// Create inserts a new comment and returns it
+func (c *SQLCommentsStore) Create(ctx context.Context, comment *types.Comment) (*types.Comment, error) {
+	newComment := *comment
+	newComment.Id = primitive.NewObjectID()
+	newComment.Mentions = idList(comment.Mentions)
+	newComment.Edits = []*types.CommentEdit{}
+	newComment.Replies = nil
+	newComment.CreatedAt = timestamp()
+	newComment.UpdatedAt = newComment.CreatedAt
+	data, err := marshalDoc(newComment)
+	if err != nil {
+		return nil, err
+	}
+	var parentId any
+	if newComment.ParentID != nil {
+		parentId = newComment.ParentID.Hex()
+	}
+	_, err = c.db.exec(ctx, "INSERT INTO comments (id, item_type, item_id, parent_id, user_id, created_at, data) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		newComment.Id.Hex(), newComment.ItemType, newComment.ItemID.Hex(), parentId, newComment.UserID.Hex(), sqlTime(newComment.CreatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newComment, nil
+}
+
+// Get retrieves a comment of an item
+func (c *SQLCommentsStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Comment, error) {
+	var comment types.Comment
+	err := c.db.findDoc(ctx, &comment, "SELECT data FROM comments WHERE id = ? AND item_type = ? AND item_id = ?",
+		id.Hex(), itemType, itemId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListByItem retrieves the comments and replies of an item, oldest first
+func (c *SQLCommentsStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Comment, error) {
+	return listDocs[types.Comment](ctx, c.db, "SELECT data FROM comments WHERE item_type = ? AND item_id = ? ORDER BY created_at, id",
+		itemType, itemId.Hex())
+}
+
+// Update replaces the body and mentions of a comment, keeping the previous
+// body in its edits, and returns it. Nothing changes when the body is the same.
+func (c *SQLCommentsStore) Update(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID, body string, mentions []primitive.ObjectID) (*types.Comment, error) {
+	comment, err := c.Get(ctx, itemType, itemId, id)
+	if err != nil || comment.Body == body {
+		return comment, err
+	}
+	now := timestamp()
+	comment.Edits = append(comment.Edits, &types.CommentEdit{Body: comment.Body, EditedAt: now})
+	comment.Body = body
+	comment.Mentions = idList(mentions)
+	comment.UpdatedAt = now
+	data, err := marshalDoc(comment)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.db.exec(ctx, "UPDATE comments SET data = ? WHERE id = ?", data, comment.Id.Hex()); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Delete removes a comment of an item and the replies to it, and returns how
+// many comments were removed
+func (c *SQLCommentsStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (int64, error) {
+	result, err := c.db.exec(ctx, "DELETE FROM comments WHERE item_type = ? AND item_id = ? AND (id = ? OR parent_id = ?)",
+		itemType, itemId.Hex(), id.Hex(), id.Hex())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err == nil && deleted == 0 {
+		return 0, ErrNotFound
+	}
+	return deleted, err
+}
+
+// CountByItems counts the comments and replies of each of the given items
+func (c *SQLCommentsStore) CountByItems(ctx context.Context, itemType string, itemIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	counts := map[primitive.ObjectID]int64{}
+	if len(itemIds) == 0 {
+		return counts, nil
+	}
+	args := append([]any{itemType}, hexIds(itemIds)...)
+	rows, err := c.db.query(ctx, "SELECT item_id, COUNT(*) FROM comments WHERE item_type = ? AND item_id IN ("+placeholders(len(itemIds))+") GROUP BY item_id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemId string
+		var count int64
+		if err := rows.Scan(&itemId, &count); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(itemId)
+		if err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteByItem removes every comment of an item
+func (c *SQLCommentsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := c.db.exec(ctx, "DELETE FROM comments WHERE item_type = ? AND item_id = ?", itemType, itemId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByUser removes every comment written by a user and the replies to them
+func (c *SQLCommentsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := c.db.exec(ctx, "DELETE FROM comments WHERE user_id = ? OR parent_id IN (SELECT id FROM comments WHERE user_id = ?)",
+		userId.Hex(), userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the comments of notes and tasks that no longer exist
+func (c *SQLCommentsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := c.db.exec(ctx, `DELETE FROM comments WHERE
+		(item_type = ? AND item_id NOT IN (SELECT id FROM notes)) OR
+		(item_type = ? AND item_id NOT IN (SELECT id FROM tasks))`, types.ShareNote, types.ShareTask)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -2,29 +2,135 @@ package db
 
 import (
 	"context"
+	"errors"
+	"golang-auth/storage"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrNotFound is returned by every backend when the requested record does not
+// exist. It aliases mongo.ErrNoDocuments so callers that compare against the
+// Mongo error keep working regardless of the configured backend.
+var ErrNotFound = mongo.ErrNoDocuments
+
+// ErrDuplicateEmail is returned when creating or updating a user would reuse
+// an email that already belongs to another account.
+var ErrDuplicateEmail = errors.New("email already in use")
+
+// ErrEmailInTrash is returned instead of ErrDuplicateEmail when the email
+// belongs to a user in the trash, who keeps it until restored or purged.
+var ErrEmailInTrash = errors.New("email belongs to an account in the trash")
+
+// ErrConcurrentUpdate is returned when a record changed since an update was
+// prepared from it in a way the update cannot build on, or kept changing
+// while an update waited for a moment it was left alone.
+var ErrConcurrentUpdate = errors.New("the record is being changed by another request, try again")
+
+// maxUpdateAttempts is how many times conditional updates start over when
+// the record changed since they read it.
+const maxUpdateAttempts = 5
+
+// normalizeEmail is the form emails are stored and looked up in, so that
+// addresses differing only in case or surrounding spaces name one account.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ErrDuplicateTag is returned when creating or renaming a tag would reuse a
+// name the user already has.
+var ErrDuplicateTag = errors.New("tag name already in use")
+
 type Store struct {
-	User  UserStore
-	Notes NotesStore
-	Tasks TasksStore
+	User          UserStore
+	Notes         NotesStore
+	Tasks         TasksStore
+	Tags          TagsStore
+	NoteRevisions NoteRevisionsStore
+	Shares        SharesStore
+	NoteLinks     NoteLinksStore
+	Attachments   AttachmentsStore
+	Workflows     WorkflowsStore
+	Notifications NotificationsStore
+	Comments      CommentsStore
+	TimeEntries   TimeEntriesStore
+
+	// Blobs holds the contents of attachments.
+	Blobs storage.Blobs
+
+	// NoteRevisionLimit is how many earlier versions are kept per note;
+	// 0 keeps them all.
+	NoteRevisionLimit int
+
+	// AttachmentMaxSize is the largest attachment accepted, in bytes, and
+	// AttachmentTypes the content types accepted.
+	AttachmentMaxSize int64
+	AttachmentTypes   []string
+
+	// Clock tells the time to recurring tasks; nil means time.Now. Tests can
+	// set it to move through a series without waiting.
+	Clock func() time.Time
+
+	backend backend
 }
 
-// NewStore initializes the DB connection and returns a new Store
+// Now returns the current time by the store's clock.
+func (s *Store) Now() time.Time {
+	if s.Clock != nil {
+		return s.Clock().UTC().Truncate(time.Millisecond)
+	}
+	return timestamp()
+}
+
+// backend is implemented by every database so operations that span several
+// stores can run without knowing which database sits behind them.
+type backend interface {
+	migrate(ctx context.Context) error
+	appliedMigrations(ctx context.Context) ([]MigrationRecord, error)
+	withTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	lockUser(ctx context.Context, id primitive.ObjectID) error
+}
+
+// WithTransaction runs fn atomically when the backend supports transactions.
+// Store calls inside fn must use the context passed to it.
+func (s *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.backend.withTransaction(ctx, fn)
+}
+
+// NewStore initializes the DB connection and returns a new Store.
+//
+// The backend is selected with DB_DRIVER: "mongo" (default) connects to
+// MONGO_URL, while "sqlite" and "postgres" open DATABASE_URL. Migrations are
+// not applied here; see Store.Migrate.
 func NewStore() *Store {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	// Load environment variables from .env file, when there is one; the
+	// environment may set them all, as main allows
+	godotenv.Load()
+
+	driver := os.Getenv("DB_DRIVER")
+	switch driver {
+	case "", "mongo":
+		return newMongoStore()
+	case "sqlite", "postgres":
+		store, err := NewSQLStore(driver, os.Getenv("DATABASE_URL"))
+		if err != nil {
+			log.Fatal("Failed to open SQL store:", err)
+		}
+		return store
+	default:
+		log.Fatalf("Unsupported DB_DRIVER %q", driver)
+		return nil
 	}
+}
 
+func newMongoStore() *Store {
 	// Read MongoDB URI from environment variable
 	mongoURI := os.Getenv("MONGO_URL")
 	if mongoURI == "" {
@@ -47,20 +153,114 @@ func NewStore() *Store {
 		log.Fatal("MongoDB connection error:", err)
 	}
 
-	userCollection := client.Database("go-lang-auth-db").Collection("user")
-	notesCollection := client.Database("go-lang-auth-db").Collection("note")
-	tasksCollection := client.Database("go-lang-auth-db").Collection("task")
+	return newMongoStoreIn(ctx, client, client.Database("go-lang-auth-db"))
+}
+
+// newMongoStoreIn returns a store keeping its collections in database.
+func newMongoStoreIn(ctx context.Context, client *mongo.Client, database *mongo.Database) *Store {
+	userCollection := database.Collection("user")
+	notesCollection := database.Collection("note")
+	tasksCollection := database.Collection("task")
+	tagsCollection := database.Collection("tag")
+	revisionsCollection := database.Collection("note_revision")
+	sharesCollection := database.Collection("share")
+	linksCollection := database.Collection("note_link")
+	attachmentsCollection := database.Collection("attachment")
+	workflowsCollection := database.Collection("workflow")
+	notificationsCollection := database.Collection("notification")
+	commentsCollection := database.Collection("comment")
+	timeEntriesCollection := database.Collection("time_entry")
 
-	// Return the store containing the UserStore
+	// Return the store containing the Mongo backed stores
 	return &Store{
-		User: UserStore{
+		User: &MongoUserStore{
 			collection: userCollection,
 		},
-		Notes: NotesStore{
+		Notes: &MongoNotesStore{
 			collection: notesCollection,
 		},
-		Tasks: TasksStore{
+		Tasks: &MongoTasksStore{
 			collection: tasksCollection,
 		},
+		Tags: &MongoTagsStore{
+			collection: tagsCollection,
+		},
+		NoteRevisions: &MongoNoteRevisionsStore{
+			collection: revisionsCollection,
+		},
+		Shares: &MongoSharesStore{
+			collection: sharesCollection,
+		},
+		NoteLinks: &MongoNoteLinksStore{
+			collection: linksCollection,
+		},
+		Attachments: &MongoAttachmentsStore{
+			collection: attachmentsCollection,
+		},
+		Workflows: &MongoWorkflowsStore{
+			collection: workflowsCollection,
+		},
+		Notifications: &MongoNotificationsStore{
+			collection: notificationsCollection,
+		},
+		Comments: &MongoCommentsStore{
+			collection: commentsCollection,
+		},
+		TimeEntries: &MongoTimeEntriesStore{
+			collection: timeEntriesCollection,
+		},
+		Blobs:             storage.NewLocal(storage.DefaultLocalDir),
+		NoteRevisionLimit: DefaultNoteRevisionLimit,
+		AttachmentMaxSize: DefaultAttachmentMaxSize,
+		AttachmentTypes:   DefaultAttachmentTypes,
+		backend: &mongoBackend{
+			client:       client,
+			database:     database,
+			transactions: supportsTransactions(ctx, client),
+		},
 	}
 }
+
+type mongoBackend struct {
+	client       *mongo.Client
+	database     *mongo.Database
+	transactions bool
+}
+
+// supportsTransactions reports whether the server is a replica set member or a
+// mongos router; standalone servers reject multi-document transactions.
+func supportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	var hello bson.M
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false
+	}
+	_, replicaSet := hello["setName"]
+	return replicaSet || hello["msg"] == "isdbgrid"
+}
+
+func (m *mongoBackend) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !m.transactions {
+		return fn(ctx)
+	}
+	if mongo.SessionFromContext(ctx) != nil {
+		// Already inside a transaction
+		return fn(ctx)
+	}
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// lockUser does nothing: MongoDB has no read locks, and its transactions
+// only conflict on documents they both write.
+func (m *mongoBackend) lockUser(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
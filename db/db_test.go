@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/storage"
+	"golang-auth/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testSQLiteOptions trades durability for speed in throwaway databases.
+const testSQLiteOptions = "?_pragma=synchronous(off)&_pragma=journal_mode(memory)"
+
+// newTestSQLStore opens a migrated SQLite store in a temporary directory.
+func newTestSQLStore(tb testing.TB) *Store {
+	tb.Helper()
+	store, err := NewSQLStore("sqlite", "file:"+filepath.Join(tb.TempDir(), "test.db")+testSQLiteOptions)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { store.backend.(*sqlDB).pool.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+	return store
+}
+
+// newTestMongoStore opens a migrated store in a fresh database of the server
+// at MONGO_TEST_URL, such as mongodb://localhost:27017, and drops it after the
+// test. The test is skipped when MONGO_TEST_URL is not set.
+func newTestMongoStore(tb testing.TB) *Store {
+	tb.Helper()
+	uri := os.Getenv("MONGO_TEST_URL")
+	if uri == "" {
+		tb.Skip("MONGO_TEST_URL is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		tb.Fatal(err)
+	}
+	database := client.Database("golang-auth-test-" + primitive.NewObjectID().Hex())
+	tb.Cleanup(func() {
+		database.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	store := newMongoStoreIn(ctx, client, database)
+	store.Blobs = storage.NewLocal(tb.TempDir())
+	if err := store.Migrate(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return store
+}
+
+// forEachBackend runs test against a SQLite store and, when MONGO_TEST_URL
+// is set, a MongoDB store.
+func forEachBackend(t *testing.T, test func(t *testing.T, store *Store)) {
+	t.Run("sqlite", func(t *testing.T) { test(t, newTestSQLStore(t)) })
+	t.Run("mongo", func(t *testing.T) { test(t, newTestMongoStore(t)) })
+}
+
+// createTestUser adds a user named name to store.
+func createTestUser(tb testing.TB, store *Store, name string) *types.UserResponse {
+	tb.Helper()
+	user, err := store.User.Create(context.Background(), &types.UserCreate{
+		Name:     name,
+		Email:    fmt.Sprintf("%s@example.com", name),
+		Password: "password",
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return user
+}
+
+// createTestTask adds a task in the "todo" status to the board of userId.
+func createTestTask(tb testing.TB, store *Store, userId primitive.ObjectID, title string) *types.Tasks {
+	tb.Helper()
+	task, err := store.Tasks.Create(context.Background(), &types.TasksCreate{
+		Title:         title,
+		UserID:        userId,
+		StatusHistory: []*types.Status{{Status: "todo", UserId: userId.Hex()}},
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return task
+}
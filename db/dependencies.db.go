@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"slices"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrDependencyCycle is returned when a task would end up waiting for
+	// itself.
+	ErrDependencyCycle = errors.New("the dependency would create a cycle")
+
+	// ErrDependencyOwner is returned when a task would wait for a task of
+	// another user.
+	ErrDependencyOwner = errors.New("a task can only be blocked by a task of the same owner")
+)
+
+// AddDependency makes task wait for blocker. Cycles are looked for among
+// every task of the owner, trashed ones included, so that restoring a task
+// cannot close one. The check and the write share a transaction that holds
+// the owner, so that two dependencies added at once cannot close a cycle
+// either.
+func (s *Store) AddDependency(ctx context.Context, task *types.Tasks, blocker *types.Tasks) (*types.Tasks, error) {
+	if blocker.UserID != task.UserID {
+		return nil, ErrDependencyOwner
+	}
+	if slices.Contains(task.BlockedBy, blocker.Id) {
+		return task, nil
+	}
+
+	var blocked *types.Tasks
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.backend.lockUser(ctx, task.UserID); err != nil {
+			return err
+		}
+		live, err := s.Tasks.List(ctx, task.UserID)
+		if err != nil {
+			return err
+		}
+		trashed, err := s.Tasks.ListTrash(ctx, task.UserID)
+		if err != nil {
+			return err
+		}
+		blockers := map[primitive.ObjectID][]primitive.ObjectID{}
+		for _, t := range slices.Concat(live, trashed) {
+			blockers[t.Id] = t.BlockedBy
+		}
+		if waitsFor(blocker.Id, task.Id, blockers, map[primitive.ObjectID]bool{}) {
+			return ErrDependencyCycle
+		}
+		blocked, err = s.Tasks.Block(ctx, task.Id, blocker.Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocked, nil
+}
+
+// waitsFor reports whether the task from waits, directly or not, for the
+// task to.
+func waitsFor(from, to primitive.ObjectID, blockers map[primitive.ObjectID][]primitive.ObjectID, seen map[primitive.ObjectID]bool) bool {
+	if from == to {
+		return true
+	}
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	for _, blocker := range blockers[from] {
+		if waitsFor(blocker, to, blockers, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadBlocked sets whether each task is blocked: some of the tasks it waits
+// for are live and not done by the workflow of their owner. Trashed and
+// removed blockers are ignored.
+func (s *Store) LoadBlocked(ctx context.Context, tasks ...*types.Tasks) error {
+	var ids []primitive.ObjectID
+	for _, task := range tasks {
+		ids = append(ids, task.BlockedBy...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	blockers, err := s.Tasks.GetMany(ctx, idList(ids))
+	if err != nil {
+		return err
+	}
+
+	workflows := map[primitive.ObjectID]*types.Workflow{}
+	open := map[primitive.ObjectID]bool{}
+	for _, blocker := range blockers {
+		workflow, ok := workflows[blocker.UserID]
+		if !ok {
+			if workflow, err = s.TaskWorkflow(ctx, blocker.UserID); err != nil {
+				return err
+			}
+			workflows[blocker.UserID] = workflow
+		}
+		open[blocker.Id] = !workflow.IsDone(blocker.CurrentStatus)
+	}
+	for _, task := range tasks {
+		task.Blocked = slices.ContainsFunc(task.BlockedBy, func(id primitive.ObjectID) bool { return open[id] })
+	}
+	return nil
+}
+
+// TaskDependencies retrieves the live tasks a task waits for and the live
+// tasks waiting for it, oldest first, all with their blocked flag.
+func (s *Store) TaskDependencies(ctx context.Context, task *types.Tasks) (*types.TaskDependencies, error) {
+	blockedBy, err := s.Tasks.GetMany(ctx, idList(task.BlockedBy))
+	if err != nil {
+		return nil, err
+	}
+	if blockedBy == nil {
+		blockedBy = []*types.Tasks{}
+	}
+	sortTasks(blockedBy)
+	blocks, err := s.Tasks.ListBlocked(ctx, task.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LoadBlocked(ctx, slices.Concat([]*types.Tasks{task}, blockedBy, blocks)...); err != nil {
+		return nil, err
+	}
+	return &types.TaskDependencies{Blocked: task.Blocked, BlockedBy: blockedBy, Blocks: blocks}, nil
+}
+
+// DependencyGraph builds the dependency graph of the live tasks of a user.
+// Tasks that neither wait for nor block another one are left out, and tasks
+// that are ready at the same time come oldest first.
+func (s *Store) DependencyGraph(ctx context.Context, userId primitive.ObjectID) (*types.DependencyGraph, error) {
+	tasks, err := s.Tasks.List(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	sortTasks(tasks)
+	live := map[primitive.ObjectID]bool{}
+	for _, task := range tasks {
+		live[task.Id] = true
+	}
+
+	graph := &types.DependencyGraph{Tasks: []*types.Tasks{}, Edges: []*types.DependencyEdge{}}
+	linked := map[primitive.ObjectID]bool{}
+	waiting := map[primitive.ObjectID]int{}
+	blocks := map[primitive.ObjectID][]*types.Tasks{}
+	byId := map[primitive.ObjectID]*types.Tasks{}
+	for _, task := range tasks {
+		byId[task.Id] = task
+		for _, blocker := range task.BlockedBy {
+			if !live[blocker] {
+				continue
+			}
+			graph.Edges = append(graph.Edges, &types.DependencyEdge{From: blocker, To: task.Id})
+			linked[blocker], linked[task.Id] = true, true
+			waiting[task.Id]++
+		}
+	}
+	for _, edge := range graph.Edges {
+		blocks[edge.From] = append(blocks[edge.From], byId[edge.To])
+	}
+
+	// Kahn's algorithm, starting from the tasks that wait for nothing
+	var ready []*types.Tasks
+	for _, task := range tasks {
+		if linked[task.Id] && waiting[task.Id] == 0 {
+			ready = append(ready, task)
+		}
+	}
+	placed := map[primitive.ObjectID]bool{}
+	for len(ready) > 0 {
+		task := ready[0]
+		ready = ready[1:]
+		graph.Tasks = append(graph.Tasks, task)
+		placed[task.Id] = true
+		for _, blocked := range blocks[task.Id] {
+			if waiting[blocked.Id]--; waiting[blocked.Id] == 0 {
+				ready = append(ready, blocked)
+			}
+		}
+	}
+	// AddDependency prevents cycles, but tasks caught in one would otherwise
+	// go missing
+	for _, task := range tasks {
+		if linked[task.Id] && !placed[task.Id] {
+			graph.Tasks = append(graph.Tasks, task)
+		}
+	}
+
+	if err := s.LoadBlocked(ctx, graph.Tasks...); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// sortTasks orders tasks oldest first.
+func sortTasks(tasks []*types.Tasks) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].Id.Hex() < tasks[j].Id.Hex()
+	})
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"testing"
+	"time"
+)
+
+func TestAddDependency(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	design := createTestTask(t, store, user.Id, "Design")
+	build := createTestTask(t, store, user.Id, "Build")
+	ship := createTestTask(t, store, user.Id, "Ship")
+
+	build, err := store.AddDependency(ctx, build, design)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddDependency(ctx, ship, build); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddDependency(ctx, design, ship); err != ErrDependencyCycle {
+		t.Errorf("closing a cycle returned %v, want ErrDependencyCycle", err)
+	}
+	if _, err := store.AddDependency(ctx, design, design); err != ErrDependencyCycle {
+		t.Errorf("waiting for itself returned %v, want ErrDependencyCycle", err)
+	}
+	if again, err := store.AddDependency(ctx, build, design); err != nil || len(again.BlockedBy) != 1 {
+		t.Errorf("adding a dependency twice = %v, %v, want it once", again, err)
+	}
+
+	other := createTestTask(t, store, createTestUser(t, store, "other").Id, "Elsewhere")
+	if _, err := store.AddDependency(ctx, build, other); err != ErrDependencyOwner {
+		t.Errorf("waiting for a task of another user returned %v, want ErrDependencyOwner", err)
+	}
+	if _, err := store.Tasks.Unblock(ctx, build.Id, ship.Id); err != ErrNotFound {
+		t.Errorf("removing a missing dependency returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestAddDependencyConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	// Each pair of tasks is linked both ways at once, which only one of the
+	// two may win
+	const pairs = 10
+	var tasks [pairs][2]*types.Tasks
+	for i := range tasks {
+		tasks[i] = [2]*types.Tasks{
+			createTestTask(t, store, user.Id, fmt.Sprintf("First %d", i)),
+			createTestTask(t, store, user.Id, fmt.Sprintf("Second %d", i)),
+		}
+	}
+
+	concurrently(t, 2*pairs, func(i int) error {
+		pair := tasks[i/2]
+		task, blocker := pair[i%2], pair[1-i%2]
+		if _, err := store.AddDependency(ctx, task, blocker); err != nil && err != ErrDependencyCycle {
+			return err
+		}
+		return nil
+	})
+	for i, pair := range tasks {
+		var linked int
+		for _, task := range pair {
+			stored, err := store.Tasks.Get(ctx, task.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			linked += len(stored.BlockedBy)
+		}
+		if linked != 1 {
+			t.Errorf("pair %d has %d dependencies, want 1", i, linked)
+		}
+	}
+}
+
+func TestPurgeTrashUnblocks(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	purged := createTestTask(t, store, user.Id, "Purged")
+	kept := createTestTask(t, store, user.Id, "Kept")
+	waiting := createTestTask(t, store, user.Id, "Waiting")
+	for _, blocker := range []*types.Tasks{purged, kept} {
+		var err error
+		if waiting, err = store.AddDependency(ctx, waiting, blocker); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Tasks.Delete(ctx, purged.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.Tasks.Get(ctx, waiting.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.BlockedBy) != 1 || stored.BlockedBy[0] != kept.Id {
+		t.Errorf("task waits for %v after the purge, want only %s", stored.BlockedBy, kept.Id)
+	}
+	graph, err := store.DependencyGraph(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, edge := range graph.Edges {
+		if edge.From == purged.Id {
+			t.Errorf("the dependency graph still has an edge from the purged task")
+		}
+	}
+}
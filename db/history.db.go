@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultNoteRevisionLimit is the number of earlier versions kept per note
+// unless Store.NoteRevisionLimit says otherwise.
+const DefaultNoteRevisionLimit = 50
+
+// ErrNoteVersionConflict is returned when a note was updated by someone else
+// between reading it and saving the new version.
+var ErrNoteVersionConflict = errors.New("note was changed by another update, try again")
+
+// UpdateNote saves the current content of a note as a revision and then
+// applies the update as the next version. Updates that change nothing are
+// not recorded. The oldest revisions beyond NoteRevisionLimit are dropped.
+func (s *Store) UpdateNote(ctx context.Context, id primitive.ObjectID, update *types.NotesUpdate) (*types.Notes, error) {
+	var updated *types.Notes
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.Notes.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.Title == update.Title && current.Category == update.Category && current.Note == update.Note &&
+			noteFormat(current.Format) == noteFormat(update.Format) && slices.Equal(idList(current.Tags), idList(update.Tags)) {
+			updated = current
+			return nil
+		}
+
+		err = s.NoteRevisions.Create(ctx, &types.NoteRevision{
+			NoteID:    current.Id,
+			Version:   current.Version,
+			Title:     current.Title,
+			Category:  current.Category,
+			Note:      current.Note,
+			Format:    noteFormat(current.Format),
+			Tags:      idList(current.Tags),
+			EditedAt:  current.UpdatedAt,
+			EditedBy:  current.UpdatedBy,
+			CreatedAt: timestamp(),
+		})
+		if err != nil {
+			return err
+		}
+		updated, err = s.Notes.Update(ctx, id, update)
+		if err != nil {
+			return err
+		}
+		if s.NoteRevisionLimit > 0 {
+			_, err = s.NoteRevisions.Prune(ctx, id, s.NoteRevisionLimit)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// RestoreNoteRevision brings back the content of an earlier version as a new
+// version of the note, so the versions in between stay in the history. Tags
+// that were deleted since, or no longer belong to the note's owner, are left out.
+func (s *Store) RestoreNoteRevision(ctx context.Context, noteId primitive.ObjectID, version int) (*types.Notes, error) {
+	note, err := s.Notes.Get(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.NoteRevisions.Get(ctx, noteId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []primitive.ObjectID{}
+	if len(revision.Tags) > 0 {
+		existing, err := s.Tags.GetMany(ctx, revision.Tags)
+		if err != nil {
+			return nil, err
+		}
+		owned := map[primitive.ObjectID]bool{}
+		for _, tag := range existing {
+			owned[tag.Id] = tag.UserID == note.UserID
+		}
+		for _, tagId := range revision.Tags {
+			if owned[tagId] {
+				tags = append(tags, tagId)
+			}
+		}
+	}
+
+	return s.UpdateNote(ctx, noteId, &types.NotesUpdate{
+		Title:    revision.Title,
+		Category: revision.Category,
+		Note:     revision.Note,
+		Format:   revision.Format,
+		Tags:     tags,
+	})
+}
+
+// NoteVersion returns the content of a note at the given version, reading
+// the note itself for the current version and its revisions otherwise.
+func (s *Store) NoteVersion(ctx context.Context, note *types.Notes, version int) (*types.NoteRevision, error) {
+	if version == note.Version {
+		return &types.NoteRevision{
+			NoteID:   note.Id,
+			Version:  note.Version,
+			Title:    note.Title,
+			Category: note.Category,
+			Note:     note.Note,
+			Format:   noteFormat(note.Format),
+			Tags:     note.Tags,
+			EditedAt: note.UpdatedAt,
+			EditedBy: note.UpdatedBy,
+		}, nil
+	}
+	return s.NoteRevisions.Get(ctx, note.Id, version)
+}
+
+// noteFormat treats notes written before formats existed as plain text.
+func noteFormat(format string) string {
+	if format == "" {
+		return types.NoteFormatPlain
+	}
+	return format
+}
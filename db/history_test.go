@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createTestNote adds a note titled title to the notes of userId.
+func createTestNote(tb testing.TB, store *Store, userId primitive.ObjectID, title string) *types.Notes {
+	tb.Helper()
+	note, err := store.Notes.Create(context.Background(), &types.NotesCreate{Title: title, Note: title, UserID: userId})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return note
+}
+
+// revisionVersions lists the versions kept in the history of a note, newest
+// first, along with their bodies.
+func revisionVersions(tb testing.TB, store *Store, noteId primitive.ObjectID) ([]int, []string) {
+	tb.Helper()
+	revisions, err := store.NoteRevisions.List(context.Background(), noteId)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	versions, bodies := []int{}, []string{}
+	for _, revision := range revisions {
+		versions = append(versions, revision.Version)
+		bodies = append(bodies, revision.Note)
+	}
+	return versions, bodies
+}
+
+func TestUpdateNoteRevisions(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	note := createTestNote(t, store, user.Id, "v1")
+
+	for _, body := range []string{"v2", "v2", "v3"} {
+		var err error
+		if note, err = store.UpdateNote(ctx, note.Id, &types.NotesUpdate{Title: note.Title, Note: body}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if note.Version != 3 {
+		t.Errorf("note is at version %d, want 3 since one update changed nothing", note.Version)
+	}
+	versions, bodies := revisionVersions(t, store, note.Id)
+	if fmt.Sprint(versions) != "[2 1]" || fmt.Sprint(bodies) != "[v2 v1]" {
+		t.Errorf("history has versions %v with bodies %v, want [2 1] and [v2 v1]", versions, bodies)
+	}
+}
+
+func TestUpdateNotePrunes(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	store.NoteRevisionLimit = 2
+	user := createTestUser(t, store, "owner")
+	note := createTestNote(t, store, user.Id, "v1")
+	other := createTestNote(t, store, user.Id, "other v1")
+
+	for version := 2; version <= 5; version++ {
+		if _, err := store.UpdateNote(ctx, note.Id, &types.NotesUpdate{Title: note.Title, Note: fmt.Sprintf("v%d", version)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.UpdateNote(ctx, other.Id, &types.NotesUpdate{Title: other.Title, Note: "other v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if versions, _ := revisionVersions(t, store, note.Id); fmt.Sprint(versions) != "[4 3]" {
+		t.Errorf("history keeps versions %v, want the newest 2: [4 3]", versions)
+	}
+	if versions, _ := revisionVersions(t, store, other.Id); fmt.Sprint(versions) != "[1]" {
+		t.Errorf("pruning a note changed the history of another to %v", versions)
+	}
+	if _, err := store.NoteRevisions.Get(ctx, note.Id, 1); err != ErrNotFound {
+		t.Errorf("getting a pruned version returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestRestoreNoteRevision(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	note := createTestNote(t, store, user.Id, "v1")
+	for _, body := range []string{"v2", "v3"} {
+		if _, err := store.UpdateNote(ctx, note.Id, &types.NotesUpdate{Title: "Renamed " + body, Note: body}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restored, err := store.RestoreNoteRevision(ctx, note.Id, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 4 || restored.Title != "v1" || restored.Note != "v1" {
+		t.Errorf("restoring version 1 gave version %d titled %q with body %q, want version 4 with the content of version 1",
+			restored.Version, restored.Title, restored.Note)
+	}
+	versions, bodies := revisionVersions(t, store, note.Id)
+	if fmt.Sprint(versions) != "[3 2 1]" || fmt.Sprint(bodies) != "[v3 v2 v1]" {
+		t.Errorf("history has versions %v with bodies %v after the restore, want [3 2 1] and [v3 v2 v1]", versions, bodies)
+	}
+
+	if _, err := store.RestoreNoteRevision(ctx, note.Id, 9); err != ErrNotFound {
+		t.Errorf("restoring a missing version returned %v, want ErrNotFound", err)
+	}
+	if again, err := store.RestoreNoteRevision(ctx, note.Id, 1); err != nil || again.Version != 4 {
+		t.Errorf("restoring the current content again = %v, %v, want version 4 unchanged", again, err)
+	}
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// MaxLinkPasswordAttempts is how many wrong passwords in a row lock a
+	// public link.
+	MaxLinkPasswordAttempts = 5
+
+	// LinkLockout is how long a locked link refuses every password.
+	LinkLockout = 15 * time.Minute
+)
+
+type MongoNoteLinksStore struct {
+	collection *mongo.Collection
+}
+
+// Create generates a new public link for a note and returns it
+func (l *MongoNoteLinksStore) Create(ctx context.Context, link *types.NoteLink) (*types.NoteLink, error) {
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+	link.Id = primitive.NewObjectID()
+	link.Token = token
+	link.Protected = link.PasswordHash != ""
+	link.Views = 0
+	link.CreatedAt = timestamp()
+	link.CreatedBy = actorFrom(ctx)
+
+	if _, err := l.collection.InsertOne(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ListByNote retrieves the public links of a note, oldest first
+func (l *MongoNoteLinksStore) ListByNote(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteLink, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := l.collection.Find(ctx, bson.M{"note_id": noteId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	links := []*types.NoteLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// GetByToken retrieves the link with the given token
+func (l *MongoNoteLinksStore) GetByToken(ctx context.Context, token string) (*types.NoteLink, error) {
+	var link types.NoteLink
+	if err := l.collection.FindOne(ctx, bson.M{"token": token}).Decode(&link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RecordView counts one more view of a link and returns it
+func (l *MongoNoteLinksStore) RecordView(ctx context.Context, id primitive.ObjectID) (*types.NoteLink, error) {
+	update := bson.M{
+		"$inc":   bson.M{"views": 1},
+		"$set":   bson.M{"last_viewed_at": timestamp()},
+		"$unset": bson.M{"failed_attempts": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var link types.NoteLink
+	if err := l.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RecordFailedAttempt counts one more wrong password for a link and returns
+// it, locked when that was one too many. The counter is incremented in place;
+// the lockout is then set by whichever attempt still sees its own count, so
+// concurrent attempts are not lost.
+func (l *MongoNoteLinksStore) RecordFailedAttempt(ctx context.Context, id primitive.ObjectID, now time.Time) (*types.NoteLink, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var link types.NoteLink
+	err := l.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failed_attempts": 1}}, opts).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+	if link.FailedAttempts < MaxLinkPasswordAttempts {
+		return &link, nil
+	}
+	lockedUntil := now.Add(LinkLockout)
+	update := bson.M{
+		"$set":   bson.M{"locked_until": lockedUntil},
+		"$unset": bson.M{"failed_attempts": ""},
+	}
+	if _, err := l.collection.UpdateOne(ctx, bson.M{"_id": id, "failed_attempts": link.FailedAttempts}, update); err != nil {
+		return nil, err
+	}
+	link.FailedAttempts = 0
+	link.LockedUntil = &lockedUntil
+	return &link, nil
+}
+
+// Delete revokes a link of a note and returns it
+func (l *MongoNoteLinksStore) Delete(ctx context.Context, noteId primitive.ObjectID, id primitive.ObjectID) (*types.NoteLink, error) {
+	var link types.NoteLink
+	if err := l.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "note_id": noteId}).Decode(&link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteByNote revokes every link of a note
+func (l *MongoNoteLinksStore) DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error) {
+	result, err := l.collection.DeleteMany(ctx, bson.M{"note_id": noteId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the links of notes that no longer exist
+func (l *MongoNoteLinksStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$note_id"}}},
+		{{Key: "$lookup", Value: bson.M{"from": "note", "localField": "_id", "foreignField": "_id", "as": "note"}}},
+		{{Key: "$match", Value: bson.M{"note": bson.M{"$size": 0}}}},
+	}
+	cursor, err := l.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var orphans []struct {
+		NoteId primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &orphans); err != nil {
+		return 0, err
+	}
+	if len(orphans) == 0 {
+		return 0, nil
+	}
+	noteIds := make([]primitive.ObjectID, len(orphans))
+	for i, orphan := range orphans {
+		noteIds[i] = orphan.NoteId
+	}
+	result, err := l.collection.DeleteMany(ctx, bson.M{"note_id": bson.M{"$in": noteIds}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// newLinkToken returns 32 random bytes encoded for use in a URL.
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLNoteLinksStore struct {
+	db *sqlDB
+}
+
+// Create generates a new public link for a note and returns it
+func (l *SQLNoteLinksStore) Create(ctx context.Context, link *types.NoteLink) (*types.NoteLink, error) {
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+	link.Id = primitive.NewObjectID()
+	link.Token = token
+	link.Protected = link.PasswordHash != ""
+	link.Views = 0
+	link.CreatedAt = timestamp()
+	link.CreatedBy = actorFrom(ctx)
+
+	data, err := marshalDoc(link)
+	if err != nil {
+		return nil, err
+	}
+	_, err = l.db.exec(ctx, "INSERT INTO note_links (id, note_id, token, created_at, data) VALUES (?, ?, ?, ?, ?)",
+		link.Id.Hex(), link.NoteID.Hex(), link.Token, sqlTime(link.CreatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ListByNote retrieves the public links of a note, oldest first
+func (l *SQLNoteLinksStore) ListByNote(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteLink, error) {
+	return listDocs[types.NoteLink](ctx, l.db, "SELECT data FROM note_links WHERE note_id = ? ORDER BY created_at, id", noteId.Hex())
+}
+
+// GetByToken retrieves the link with the given token
+func (l *SQLNoteLinksStore) GetByToken(ctx context.Context, token string) (*types.NoteLink, error) {
+	var link types.NoteLink
+	if err := l.db.findDoc(ctx, &link, "SELECT data FROM note_links WHERE token = ?", token); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RecordView counts one more view of a link and returns it. The counter is
+// incremented in place so concurrent views are not lost.
+func (l *SQLNoteLinksStore) RecordView(ctx context.Context, id primitive.ObjectID) (*types.NoteLink, error) {
+	set := `json_remove(json_set(data, '$.views', COALESCE(json_extract(data, '$.views'), 0) + 1, '$.last_viewed_at', ?), '$.failed_attempts')`
+	if l.db.dialect == dialectPostgres {
+		set = `(jsonb_set(jsonb_set(data::jsonb, '{views}', to_jsonb(COALESCE((data::jsonb ->> 'views')::bigint, 0) + 1)),
+			'{last_viewed_at}', to_jsonb(?::text)) - 'failed_attempts')::text`
+	}
+	result, err := l.db.exec(ctx, "UPDATE note_links SET data = "+set+" WHERE id = ?", sqlTime(timestamp()), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrNotFound
+	}
+	var link types.NoteLink
+	if err := l.db.getDoc(ctx, "note_links", id.Hex(), &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RecordFailedAttempt counts one more wrong password for a link and returns
+// it, locked when that was one too many. The row is locked while it is
+// updated so concurrent attempts are not lost.
+func (l *SQLNoteLinksStore) RecordFailedAttempt(ctx context.Context, id primitive.ObjectID, now time.Time) (*types.NoteLink, error) {
+	var link types.NoteLink
+	err := l.db.withTransaction(ctx, func(ctx context.Context) error {
+		if err := l.db.findDoc(ctx, &link, "SELECT data FROM note_links WHERE id = ?"+l.db.dialect.forUpdate(), id.Hex()); err != nil {
+			return err
+		}
+		link.FailedAttempts++
+		if link.FailedAttempts >= MaxLinkPasswordAttempts {
+			lockedUntil := now.Add(LinkLockout)
+			link.FailedAttempts = 0
+			link.LockedUntil = &lockedUntil
+		}
+		data, err := marshalDoc(link)
+		if err != nil {
+			return err
+		}
+		_, err = l.db.exec(ctx, "UPDATE note_links SET data = ? WHERE id = ?", data, id.Hex())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Delete revokes a link of a note and returns it
+func (l *SQLNoteLinksStore) Delete(ctx context.Context, noteId primitive.ObjectID, id primitive.ObjectID) (*types.NoteLink, error) {
+	var link types.NoteLink
+	err := l.db.findDoc(ctx, &link, "SELECT data FROM note_links WHERE id = ? AND note_id = ?", id.Hex(), noteId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := l.db.exec(ctx, "DELETE FROM note_links WHERE id = ?", id.Hex()); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteByNote revokes every link of a note
+func (l *SQLNoteLinksStore) DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error) {
+	result, err := l.db.exec(ctx, "DELETE FROM note_links WHERE note_id = ?", noteId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the links of notes that no longer exist
+func (l *SQLNoteLinksStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := l.db.exec(ctx, "DELETE FROM note_links WHERE note_id NOT IN (SELECT id FROM notes)")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"testing"
+	"time"
+)
+
+// createTestLink adds a password protected link to a new note of a new user.
+func createTestLink(t *testing.T, store *Store) *types.NoteLink {
+	t.Helper()
+	ctx := context.Background()
+	user := createTestUser(t, store, "owner")
+	note, err := store.Notes.Create(ctx, &types.NotesCreate{Title: "Shared", UserID: user.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := store.NoteLinks.Create(ctx, &types.NoteLink{NoteID: note.Id, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+func TestRecordFailedAttempt(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	link := createTestLink(t, store)
+	now := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+	for i := 1; i < MaxLinkPasswordAttempts; i++ {
+		failed, err := store.NoteLinks.RecordFailedAttempt(ctx, link.Id, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failed.FailedAttempts != i || failed.Locked(now) {
+			t.Fatalf("after %d wrong passwords the link has %d and locked until %v", i, failed.FailedAttempts, failed.LockedUntil)
+		}
+	}
+	// A view in between starts the count over
+	if _, err := store.NoteLinks.RecordView(ctx, link.Id); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= MaxLinkPasswordAttempts; i++ {
+		failed, err := store.NoteLinks.RecordFailedAttempt(ctx, link.Id, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked := failed.Locked(now); locked != (i == MaxLinkPasswordAttempts) {
+			t.Fatalf("after %d wrong passwords since the view the link is locked: %v", i, locked)
+		}
+	}
+	stored, err := store.NoteLinks.GetByToken(ctx, link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Locked(now.Add(LinkLockout-time.Second)) || stored.Locked(now.Add(LinkLockout)) || stored.FailedAttempts != 0 {
+		t.Errorf("stored link is locked until %v with %d attempts, want %s from now with none", stored.LockedUntil, stored.FailedAttempts, LinkLockout)
+	}
+}
+
+func TestRecordFailedAttemptConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	link := createTestLink(t, store)
+	now := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+	concurrently(t, MaxLinkPasswordAttempts, func(int) error {
+		_, err := store.NoteLinks.RecordFailedAttempt(ctx, link.Id, now)
+		return err
+	})
+	stored, err := store.NoteLinks.GetByToken(ctx, link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Locked(now) {
+		t.Errorf("%d wrong passwords at once left the link with %d attempts and unlocked", MaxLinkPasswordAttempts, stored.FailedAttempts)
+	}
+}
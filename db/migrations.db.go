@@ -0,0 +1,636 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationRecord describes a migration that has been applied to the database.
+type MigrationRecord struct {
+	Version   int       `json:"version" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	AppliedAt time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+// Migrate applies every pending migration of the configured backend.
+// Migrations are idempotent, so running it on every start is safe.
+func (s *Store) Migrate(ctx context.Context) error {
+	return s.backend.migrate(ctx)
+}
+
+// AppliedMigrations lists the migrations recorded as applied, oldest first.
+func (s *Store) AppliedMigrations(ctx context.Context) ([]MigrationRecord, error) {
+	return s.backend.appliedMigrations(ctx)
+}
+
+// mongoMigration is one versioned step of the Mongo schema. Up must be safe
+// to run more than once in case two instances start at the same time.
+type mongoMigration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, database *mongo.Database) error
+}
+
+// mongoMigrations must only ever be appended to; applied versions are
+// recorded in the schema_migrations collection and skipped afterwards.
+var mongoMigrations = []mongoMigration{
+	{
+		Version: 1,
+		Name:    "create user, note and task indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("user").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetName("email_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = database.Collection("note").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetName("user_id"),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = database.Collection("task").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetName("user_id"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 2,
+		Name:    "backfill missing user roles",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			filter := bson.M{"$or": bson.A{
+				bson.M{"role": bson.M{"$exists": false}},
+				bson.M{"role": ""},
+			}}
+			_, err := database.Collection("user").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"role": "user"}})
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "backfill empty task status history",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Matches both a missing field and an explicit null
+			filter := bson.M{"statushistory": nil}
+			_, err := database.Collection("task").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"statushistory": bson.A{}}})
+			return err
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create deleted_at indexes for the trash",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"user", "note", "task"} {
+				_, err := database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+					Options: options.Index().SetName("deleted_at"),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "backfill created_at and updated_at from ObjectIDs",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// $toDate on an ObjectID yields the time it was generated
+			createdAt := bson.M{"$toDate": "$_id"}
+			for _, name := range []string{"user", "note", "task"} {
+				_, err := database.Collection(name).UpdateMany(ctx,
+					bson.M{"created_at": bson.M{"$exists": false}},
+					mongo.Pipeline{{{Key: "$set", Value: bson.M{
+						"created_at": createdAt,
+						"updated_at": bson.M{"$ifNull": bson.A{"$updated_at", createdAt}},
+					}}}},
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Status entries recorded before timestamps existed get the task creation time
+			_, err := database.Collection("task").UpdateMany(ctx,
+				bson.M{"statushistory": bson.M{"$elemMatch": bson.M{"changed_at": bson.M{"$exists": false}}}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{
+					"statushistory": bson.M{"$map": bson.M{
+						"input": "$statushistory",
+						"as":    "status",
+						"in": bson.M{"$mergeObjects": bson.A{
+							"$$status",
+							bson.M{"changed_at": bson.M{"$ifNull": bson.A{"$$status.changed_at", createdAt}}},
+						}},
+					}},
+				}}}},
+			)
+			return err
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create created_at indexes for paginated lists",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"note", "task"} {
+				_, err := database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			_, err := database.Collection("user").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+				Options: options.Index().SetName("created_at"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create text indexes for search",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for name, body := range map[string]string{"note": "note", "task": "task"} {
+				_, err := database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys: bson.D{{Key: "title", Value: "text"}, {Key: "category", Value: "text"}, {Key: body, Value: "text"}},
+					Options: options.Index().SetName("search").
+						SetWeights(bson.M{"title": 10, "category": 2, body: 1}),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create tags index and backfill empty tag lists",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("tag").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}},
+				Options: options.Index().SetName("user_id_key_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"note", "task"} {
+				collection := database.Collection(name)
+				_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "tags", Value: 1}},
+					Options: options.Index().SetName("tags"),
+				})
+				if err != nil {
+					return err
+				}
+				filter := bson.M{"$or": bson.A{bson.M{"tags": bson.M{"$exists": false}}, bson.M{"tags": nil}}}
+				if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"tags": bson.A{}}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create note revisions index and number existing notes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("note_revision").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "note_id", Value: 1}, {Key: "version", Value: -1}},
+				Options: options.Index().SetName("note_id_version_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = database.Collection("note").UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+			return err
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create shares indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("share").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "item_type", Value: 1}, {Key: "item_id", Value: 1}, {Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("item_user_unique").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create note links indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("note_link").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "token", Value: 1}},
+					Options: options.Index().SetName("token_unique").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "note_id", Value: 1}},
+					Options: options.Index().SetName("note_id"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version: 12,
+		Name:    "mark existing notes as plain text",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("note").UpdateMany(ctx, bson.M{"format": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"format": "plain"}})
+			return err
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create attachments index",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("attachment").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "item_type", Value: 1}, {Key: "item_id", Value: 1}, {Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("item_created_at"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 14,
+		Name:    "store the current status of tasks",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// The current status is the last entry of the history
+			set := bson.M{"$set": bson.M{"current_status": bson.M{"$ifNull": bson.A{
+				bson.M{"$arrayElemAt": bson.A{"$statushistory.status", -1}},
+				"",
+			}}}}
+			_, err := database.Collection("task").UpdateMany(ctx, bson.M{"current_status": bson.M{"$exists": false}}, bson.A{set})
+			if err != nil {
+				return err
+			}
+			_, err = database.Collection("task").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "current_status", Value: 1}},
+				Options: options.Index().SetName("user_id_current_status"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create workflows index",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("workflow").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetName("user_id_unique").SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		Version: 16,
+		Name:    "backfill previous status of task status history",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Every entry after the first records the status of the one before it
+			previous := bson.M{"$arrayElemAt": bson.A{"$statushistory.status", bson.M{"$subtract": bson.A{"$$i", 1}}}}
+			set := bson.M{"$set": bson.M{"statushistory": bson.M{"$map": bson.M{
+				"input": bson.M{"$range": bson.A{0, bson.M{"$size": "$statushistory"}}},
+				"as":    "i",
+				"in": bson.M{"$mergeObjects": bson.A{
+					bson.M{"$arrayElemAt": bson.A{"$statushistory", "$$i"}},
+					bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$$i", 0}}, bson.M{"previous_status": previous}, bson.M{}}},
+				}},
+			}}}}
+			filter := bson.M{"statushistory.1": bson.M{"$exists": true}}
+			_, err := database.Collection("task").UpdateMany(ctx, filter, bson.A{set})
+			return err
+		},
+	},
+	{
+		Version: 17,
+		Name:    "add task priorities and due dates",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			tasks := database.Collection("task")
+			_, err := tasks.UpdateMany(ctx, bson.M{"priority": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"priority": 0}})
+			if err != nil {
+				return err
+			}
+			_, err = tasks.UpdateMany(ctx, bson.M{"due_sort": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"due_sort": types.NoDueDate}})
+			if err != nil {
+				return err
+			}
+			_, err = tasks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "due_sort", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("user_id_due_sort"),
+				},
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "priority", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("user_id_priority"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add task assignees and notifications",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			tasks := database.Collection("task")
+			_, err := tasks.UpdateMany(ctx, bson.M{"assignees": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"assignees": bson.A{}}})
+			if err != nil {
+				return err
+			}
+			_, err = tasks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "assignees", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("assignees_created_at"),
+				},
+				{
+					Keys:    bson.D{{Key: "created_by", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("created_by_created_at"),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			_, err = database.Collection("notification").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+					Options: options.Index().SetName("user_id_created_at"),
+				},
+				{
+					Keys:    bson.D{{Key: "item_type", Value: 1}, {Key: "item_id", Value: 1}},
+					Options: options.Index().SetName("item"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version: 19,
+		Name:    "add subtasks and checklists",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			tasks := database.Collection("task")
+			for _, key := range []string{"ancestors", "checklist"} {
+				_, err := tasks.UpdateMany(ctx, bson.M{key: bson.M{"$exists": false}}, bson.M{"$set": bson.M{key: bson.A{}}})
+				if err != nil {
+					return err
+				}
+			}
+			_, err := tasks.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "ancestors", Value: 1}, {Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("ancestors_created_at"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 20,
+		Name:    "add task dependencies",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			tasks := database.Collection("task")
+			_, err := tasks.UpdateMany(ctx, bson.M{"blocked_by": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"blocked_by": bson.A{}}})
+			if err != nil {
+				return err
+			}
+			_, err = tasks.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "blocked_by", Value: 1}, {Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("blocked_by_created_at"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 21,
+		Name:    "add recurring tasks",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("task").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "recurrence.next_at", Value: 1}},
+				Options: options.Index().SetName("recurrence_next_at").SetSparse(true),
+			})
+			return err
+		},
+	},
+	{
+		Version: 22,
+		Name:    "add task board ranks",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Rank the tasks of each user in the order they were created,
+			// after those ranked already
+			tasks := database.Collection("task")
+			opts := options.Find().
+				SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+				SetProjection(bson.M{"user_id": 1})
+			cursor, err := tasks.Find(ctx, bson.M{"rank": bson.M{"$exists": false}}, opts)
+			if err != nil {
+				return err
+			}
+			var unranked []*types.Tasks
+			if err := cursor.All(ctx, &unranked); err != nil {
+				return err
+			}
+			store := &MongoTasksStore{collection: tasks}
+			ranks := map[primitive.ObjectID]string{}
+			for _, task := range unranked {
+				last, ok := ranks[task.UserID]
+				if !ok {
+					if last, err = store.LastRank(ctx, task.UserID); err != nil {
+						return err
+					}
+				}
+				if ranks[task.UserID], err = utils.RankBetween(last, ""); err != nil {
+					return err
+				}
+				if _, err := tasks.UpdateOne(ctx, bson.M{"_id": task.Id}, bson.M{"$set": bson.M{"rank": ranks[task.UserID]}}); err != nil {
+					return err
+				}
+			}
+			_, err = tasks.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "rank", Value: 1}},
+				Options: options.Index().SetName("user_id_rank"),
+			})
+			return err
+		},
+	},
+	{
+		Version: 23,
+		Name:    "add comments",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("comment").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "item_type", Value: 1}, {Key: "item_id", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("item_created_at"),
+				},
+				{
+					Keys:    bson.D{{Key: "parent_id", Value: 1}},
+					Options: options.Index().SetName("parent_id").SetSparse(true),
+				},
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id"),
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version: 24,
+		Name:    "add time tracking",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("time_entry").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "started_at", Value: 1}},
+					Options: options.Index().SetName("task_id_started_at"),
+				},
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "started_at", Value: 1}},
+					Options: options.Index().SetName("user_id_started_at"),
+				},
+				{
+					// A user has at most one running timer
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("running_user_id").SetUnique(true).SetPartialFilterExpression(bson.M{"running": true}),
+				},
+			})
+			return err
+		},
+	},
+	{
+		// Emails used to be stored as typed, so the unique index let
+		// addresses differing only in case or spaces through
+		Version: 25,
+		Name:    "normalize user emails",
+		Up:      normalizeMongoEmails,
+	},
+}
+
+func (m *mongoBackend) migrate(ctx context.Context) error {
+	collection := m.database.Collection("schema_migrations")
+
+	for _, migration := range mongoMigrations {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": migration.Version})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := migration.Up(ctx, m.database); err != nil {
+			return migrationError(migration.Version, migration.Name, err)
+		}
+		_, err = collection.InsertOne(ctx, MigrationRecord{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		})
+		// Another instance may have recorded the same version in the meantime
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mongoBackend) appliedMigrations(ctx context.Context) ([]MigrationRecord, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.database.Collection("schema_migrations").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	records := []MigrationRecord{}
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// userEmail is the email a user is stored with.
+type userEmail struct {
+	Id    string
+	Email string
+}
+
+// normalizedEmails returns the users whose email changes once normalized.
+// When that would give several users the same email it fails instead,
+// listing those emails so the accounts can be merged or renamed by hand.
+func normalizedEmails(users []userEmail) ([]userEmail, error) {
+	owners := map[string]int{}
+	for _, user := range users {
+		owners[normalizeEmail(user.Email)]++
+	}
+	var conflicts []string
+	for email, count := range owners {
+		if count > 1 {
+			conflicts = append(conflicts, email)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("these emails belong to more than one user once lowercased and trimmed, rename or remove the extra accounts and migrate again: %s", strings.Join(conflicts, ", "))
+	}
+
+	var changed []userEmail
+	for _, user := range users {
+		if email := normalizeEmail(user.Email); email != user.Email {
+			changed = append(changed, userEmail{Id: user.Id, Email: email})
+		}
+	}
+	return changed, nil
+}
+
+// normalizeMongoEmails stores every user email in its normalized form.
+func normalizeMongoEmails(ctx context.Context, database *mongo.Database) error {
+	collection := database.Collection("user")
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		Id    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+	users := make([]userEmail, len(docs))
+	for i, doc := range docs {
+		users[i] = userEmail{Id: doc.Id.Hex(), Email: doc.Email}
+	}
+	changed, err := normalizedEmails(users)
+	if err != nil {
+		return err
+	}
+	for _, user := range changed {
+		id, _ := primitive.ObjectIDFromHex(user.Id)
+		if _, err := collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"email": user.Email}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrationError(version int, name string, err error) error {
+	return fmt.Errorf("migration %d (%s): %w", version, name, err)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizedEmails(t *testing.T) {
+	changed, err := normalizedEmails([]userEmail{
+		{Id: "1", Email: "ada@example.com"},
+		{Id: "2", Email: " Grace@Example.com"},
+		{Id: "3", Email: "ALAN@EXAMPLE.COM "},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []userEmail{{Id: "2", Email: "grace@example.com"}, {Id: "3", Email: "alan@example.com"}}
+	if !slices.Equal(changed, want) {
+		t.Errorf("changed emails are %v, want %v", changed, want)
+	}
+
+	_, err = normalizedEmails([]userEmail{
+		{Id: "1", Email: "ada@example.com"},
+		{Id: "2", Email: "Ada@example.com"},
+		{Id: "3", Email: "grace@example.com"},
+		{Id: "4", Email: "alan@example.com"},
+		{Id: "5", Email: " alan@example.com"},
+	})
+	if err == nil || !strings.HasSuffix(err.Error(), ": ada@example.com, alan@example.com") {
+		t.Errorf("conflicting emails returned %v, want an error listing them", err)
+	}
+}
+
+// insertRawUser adds a user row with email as it is, the way rows written
+// before emails were normalized look.
+func insertRawUser(t *testing.T, store *Store, id string, email string) {
+	t.Helper()
+	_, err := store.backend.(*sqlDB).exec(context.Background(),
+		"INSERT INTO users (id, email, created_at, updated_at, data) VALUES (?, ?, '', '', ?)",
+		id, email, `{"_id":"`+id+`","email":"`+email+`"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rerunEmailMigration forgets that emails were normalized and migrates again.
+func rerunEmailMigration(store *Store) error {
+	ctx := context.Background()
+	if _, err := store.backend.(*sqlDB).exec(ctx, "DELETE FROM schema_migrations WHERE version = 24"); err != nil {
+		return err
+	}
+	return store.Migrate(ctx)
+}
+
+func TestMigrateNormalizesSQLEmails(t *testing.T) {
+	store := newTestSQLStore(t)
+	insertRawUser(t, store, "65f000000000000000000001", " Ada@Example.com")
+	if err := rerunEmailMigration(store); err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.User.FindByEmail("ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "ada@example.com" {
+		t.Errorf("stored email is %q, want ada@example.com", user.Email)
+	}
+
+	insertRawUser(t, store, "65f000000000000000000002", "ADA@example.com")
+	err = rerunEmailMigration(store)
+	if err == nil || !strings.Contains(err.Error(), "more than one user") || !strings.HasSuffix(err.Error(), ": ada@example.com") {
+		t.Errorf("migrating duplicate emails returned %v, want an error listing ada@example.com", err)
+	}
+}
@@ -4,33 +4,84 @@ import (
 	"context"
 	"fmt"
 	"golang-auth/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type NotesStore struct {
+type MongoNotesStore struct {
 	collection *mongo.Collection
 }
 
-func (n *NotesStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error) {
-	filter := bson.M{"user_id": userId}
-	cursor, err := n.collection.Find(ctx, filter)
+func (n *MongoNotesStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error) {
+	filter := notDeleted(bson.M{"user_id": userId})
+	return n.find(ctx, filter)
+}
+
+// ListPage retrieves one page of a user's notes matching the query
+func (n *MongoNotesStore) ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Notes, *types.Pagination, error) {
+	req, err := resolveListQuery(query, noteSortFields)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var notes []*types.Notes
-	err = cursor.All(ctx, &notes)
+	filter := req.createdRange(notDeleted(bson.M{"user_id": userId}))
+	if req.Category != "" {
+		filter["category"] = req.Category
+	}
+	if len(req.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": req.Tags}
+	}
+	return findPage[types.Notes](ctx, n.collection, filter, req)
+}
+
+// ListByUsers retrieves the notes of several users with a single query
+func (n *MongoNotesStore) ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Notes, error) {
+	filter := notDeleted(bson.M{"user_id": bson.M{"$in": userIds}})
+	// Sorting by owner first lets the user_id_created_at index return the
+	// documents in order
+	opts := options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := n.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
+	notes := []*types.Notes{}
+	if err := cursor.All(ctx, &notes); err != nil {
+		return nil, err
+	}
 	return notes, nil
 }
 
-func (n *NotesStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+// CountByUsers counts the live notes of several users with a single aggregation
+func (n *MongoNotesStore) CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countByUsers(ctx, n.collection, userIds)
+}
+
+// ReplaceTag swaps the tag from for to on every note carrying it, or
+// removes it when to is nil
+func (n *MongoNotesStore) ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	return replaceTag(ctx, n.collection, from, to)
+}
+
+// CountByTags counts the user's live notes per tag
+func (n *MongoNotesStore) CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countByTags(ctx, n.collection, userId)
+}
+
+// Search finds the notes of the user, or shared with them, matching any of
+// the terms, best matches first
+func (n *MongoNotesStore) Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error) {
+	filter := searchFilter(searchOwners(userId, shared, false), terms, query)
+	return searchCollection(ctx, n.collection, filter, query.Limit, func(note *types.Notes, score float64) *types.SearchResult {
+		return &types.SearchResult{Type: "note", Score: score, Note: note}
+	})
+}
+
+func (n *MongoNotesStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
 	var note *types.Notes
-	filter := bson.M{"_id": id}
+	filter := notDeleted(bson.M{"_id": id})
 	err := n.collection.FindOne(ctx, filter).Decode(&note)
 	if err != nil {
 		return nil, err
@@ -38,40 +89,60 @@ func (n *NotesStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Not
 	return note, nil
 }
 
-func (n *NotesStore) Create(ctx context.Context, note *types.NotesCreate) (*types.Notes, error) {
+// GetMany retrieves the live notes with the given IDs; unknown and trashed IDs are skipped
+func (n *MongoNotesStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Notes, error) {
+	return n.find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+}
+
+func (n *MongoNotesStore) Create(ctx context.Context, note *types.NotesCreate) (*types.Notes, error) {
+	note.CreatedAt = timestamp()
+	note.UpdatedAt = note.CreatedAt
+	note.CreatedBy = actorFrom(ctx)
+	note.UpdatedBy = note.CreatedBy
+	note.Tags = idList(note.Tags)
+	note.Format = noteFormat(note.Format)
+	note.Version = 1
+
 	result, err := n.collection.InsertOne(ctx, note)
 	if err != nil {
 		return nil, err
 	}
 	newNote := types.Notes{
-		Title:    note.Title,
-		Category: note.Category,
-		Note:     note.Note,
-		UserID:   note.UserID,
-		Id:       result.InsertedID.(primitive.ObjectID),
+		Title:     note.Title,
+		Category:  note.Category,
+		Note:      note.Note,
+		Format:    note.Format,
+		Tags:      note.Tags,
+		Version:   note.Version,
+		UserID:    note.UserID,
+		Id:        result.InsertedID.(primitive.ObjectID),
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+		CreatedBy: note.CreatedBy,
+		UpdatedBy: note.UpdatedBy,
 	}
 
 	return &newNote, nil
 }
 
-func (n *NotesStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
-	var deletedNote *types.Notes
-	err := n.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&deletedNote)
-	if err != nil {
-		return nil, err
-	}
-	_, err = n.collection.DeleteOne(ctx, bson.M{"_id": id})
-	if err != nil {
-		return nil, err
+// Delete moves a note to the trash and returns it
+func (n *MongoNotesStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": timestamp()},
 	}
-	return deletedNote, nil
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
 }
 
-func (n *NotesStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.NotesUpdate) (*types.Notes, error) {
+func (n *MongoNotesStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.NotesUpdate) (*types.Notes, error) {
+	updatedData.UpdatedAt = timestamp()
+	updatedData.UpdatedBy = actorFrom(ctx)
+	updatedData.Tags = idList(updatedData.Tags)
+	updatedData.Format = noteFormat(updatedData.Format)
 	update := bson.M{
 		"$set": updatedData,
+		"$inc": bson.M{"version": 1},
 	}
-	result, err := n.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := n.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), update)
 	if err != nil {
 		return nil, err
 	}
@@ -86,3 +157,114 @@ func (n *NotesStore) Update(ctx context.Context, id primitive.ObjectID, updatedD
 	}
 	return updatedNote, nil
 }
+
+// DeleteByUser permanently removes every note owned by the user, including
+// trashed ones, and returns how many were removed
+func (n *MongoNotesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// TransferOwner moves every note owned by fromUserId to toUserId
+func (n *MongoNotesStore) TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"user_id": toUserId, "updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"user_id": fromUserId}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListTrash retrieves the trashed notes of a user
+func (n *MongoNotesStore) ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error) {
+	return n.find(ctx, inTrash(bson.M{"user_id": userId}))
+}
+
+// GetTrashed retrieves a single trashed note by ID
+func (n *MongoNotesStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	var note *types.Notes
+	err := n.collection.FindOne(ctx, inTrash(bson.M{"_id": id})).Decode(&note)
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Restore takes a note out of the trash and returns it
+func (n *MongoNotesStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	return n.findOneAndUpdate(ctx, inTrash(bson.M{"_id": id}), update)
+}
+
+// Purge permanently removes a trashed note and returns it
+func (n *MongoNotesStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	var purgedNote *types.Notes
+	err := n.collection.FindOneAndDelete(ctx, inTrash(bson.M{"_id": id})).Decode(&purgedNote)
+	if err != nil {
+		return nil, err
+	}
+	return purgedNote, nil
+}
+
+// TrashByUser moves every live note of the user to the trash with the given timestamp
+func (n *MongoNotesStore) TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": deletedAt},
+	}
+	result, err := n.collection.UpdateMany(ctx, notDeleted(bson.M{"user_id": userId}), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RestoreByUser restores the notes of the user that were trashed at deletedAt
+func (n *MongoNotesStore) RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"user_id": userId, "deleted_at": deletedAt}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// PurgeDeletedBefore permanently removes notes trashed before the given time
+func (n *MongoNotesStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (n *MongoNotesStore) find(ctx context.Context, filter bson.M) ([]*types.Notes, error) {
+	cursor, err := n.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var notes []*types.Notes
+	err = cursor.All(ctx, &notes)
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (n *MongoNotesStore) findOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*types.Notes, error) {
+	var note *types.Notes
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := n.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&note)
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
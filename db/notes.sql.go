@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLNotesStore struct {
+	db *sqlDB
+}
+
+// List retrieves all notes for a specific user
+func (n *SQLNotesStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error) {
+	return listDocs[types.Notes](ctx, n.db, "SELECT data FROM notes WHERE user_id = ? AND deleted_at IS NULL ORDER BY id", userId.Hex())
+}
+
+// ListPage retrieves one page of a user's notes matching the query
+func (n *SQLNotesStore) ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Notes, *types.Pagination, error) {
+	req, err := resolveListQuery(query, noteSortFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	page := &sqlPage{}
+	page.add("user_id = ?", userId.Hex())
+	page.add("deleted_at IS NULL")
+	page.createdRange(req)
+	if req.Category != "" {
+		page.add(n.db.dialect.jsonText("category")+" = ?", req.Category)
+	}
+	for _, tag := range req.Tags {
+		page.add(n.db.dialect.jsonArrayContains("tags"), tag.Hex())
+	}
+	return listPage[types.Notes](ctx, n.db, "notes", page, req)
+}
+
+// ListByUsers retrieves the notes of several users with a single query
+func (n *SQLNotesStore) ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Notes, error) {
+	if len(userIds) == 0 {
+		return []*types.Notes{}, nil
+	}
+	query := "SELECT data FROM notes WHERE user_id IN (" + placeholders(len(userIds)) + ") AND deleted_at IS NULL ORDER BY user_id, created_at, id"
+	return listDocs[types.Notes](ctx, n.db, query, hexIds(userIds)...)
+}
+
+// CountByUsers counts the live notes of several users with a single query
+func (n *SQLNotesStore) CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return n.db.countByUsers(ctx, "notes", userIds)
+}
+
+// ReplaceTag swaps the tag from for to on every note carrying it, or
+// removes it when to is nil
+func (n *SQLNotesStore) ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	notes, err := listDocs[types.Notes](ctx, n.db, "SELECT data FROM notes WHERE "+n.db.dialect.jsonArrayContains("tags"), from.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, note := range notes {
+		note.Tags = retag(note.Tags, from, to)
+		note.UpdatedAt = timestamp()
+		note.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, note); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(notes)), nil
+}
+
+// CountByTags counts the user's live notes per tag
+func (n *SQLNotesStore) CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return n.db.countByTags(ctx, "notes", userId)
+}
+
+// Search finds the notes of the user, or shared with them, matching any of
+// the terms, best matches first
+func (n *SQLNotesStore) Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error) {
+	return searchTable(ctx, n.db, "notes", userId, shared, terms, query, func(note *types.Notes, score float64) *types.SearchResult {
+		return &types.SearchResult{Type: "note", Score: score, Note: note}
+	})
+}
+
+// Get retrieves a single note by ID
+func (n *SQLNotesStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	var note types.Notes
+	err := n.db.findDoc(ctx, &note, "SELECT data FROM notes WHERE id = ? AND deleted_at IS NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetMany retrieves the live notes with the given IDs; unknown and trashed IDs are skipped
+func (n *SQLNotesStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Notes, error) {
+	if len(ids) == 0 {
+		return []*types.Notes{}, nil
+	}
+	query := "SELECT data FROM notes WHERE id IN (" + placeholders(len(ids)) + ") AND deleted_at IS NULL"
+	return listDocs[types.Notes](ctx, n.db, query, hexIds(ids)...)
+}
+
+// Create inserts a new note into the database
+func (n *SQLNotesStore) Create(ctx context.Context, note *types.NotesCreate) (*types.Notes, error) {
+	newNote := types.Notes{
+		Id:        primitive.NewObjectID(),
+		Title:     note.Title,
+		Category:  note.Category,
+		Note:      note.Note,
+		Format:    noteFormat(note.Format),
+		Tags:      idList(note.Tags),
+		Version:   1,
+		UserID:    note.UserID,
+		CreatedAt: timestamp(),
+		CreatedBy: actorFrom(ctx),
+	}
+	newNote.UpdatedAt = newNote.CreatedAt
+	newNote.UpdatedBy = newNote.CreatedBy
+
+	data, err := marshalDoc(newNote)
+	if err != nil {
+		return nil, err
+	}
+	_, err = n.db.exec(ctx, "INSERT INTO notes (id, user_id, created_at, updated_at, data) VALUES (?, ?, ?, ?, ?)",
+		newNote.Id.Hex(), newNote.UserID.Hex(), sqlTime(newNote.CreatedAt), sqlTime(newNote.UpdatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newNote, nil
+}
+
+// Delete moves a note to the trash and returns it
+func (n *SQLNotesStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	note, err := n.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	deletedAt := timestamp()
+	note.DeletedAt = &deletedAt
+	if err := n.save(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Update modifies an existing note based on its ID
+func (n *SQLNotesStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.NotesUpdate) (*types.Notes, error) {
+	note, err := n.Get(ctx, id)
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("no note found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	note.Title = updatedData.Title
+	note.Category = updatedData.Category
+	note.Note = updatedData.Note
+	note.Format = noteFormat(updatedData.Format)
+	note.Tags = idList(updatedData.Tags)
+	note.Version++
+	note.UpdatedAt = timestamp()
+	note.UpdatedBy = actorFrom(ctx)
+
+	if err := n.save(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// DeleteByUser permanently removes every note owned by the user, including
+// trashed ones, and returns how many were removed
+func (n *SQLNotesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM notes WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TransferOwner moves every note owned by fromUserId to toUserId
+func (n *SQLNotesStore) TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error) {
+	notes, err := listDocs[types.Notes](ctx, n.db, "SELECT data FROM notes WHERE user_id = ?", fromUserId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, note := range notes {
+		note.UserID = toUserId
+		note.UpdatedAt = timestamp()
+		note.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, note); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(notes)), nil
+}
+
+// ListTrash retrieves the trashed notes of a user
+func (n *SQLNotesStore) ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error) {
+	return listDocs[types.Notes](ctx, n.db, "SELECT data FROM notes WHERE user_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC", userId.Hex())
+}
+
+// GetTrashed retrieves a single trashed note by ID
+func (n *SQLNotesStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	var note types.Notes
+	err := n.db.findDoc(ctx, &note, "SELECT data FROM notes WHERE id = ? AND deleted_at IS NOT NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// Restore takes a note out of the trash and returns it
+func (n *SQLNotesStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	note, err := n.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	note.DeletedAt = nil
+	if err := n.save(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Purge permanently removes a trashed note and returns it
+func (n *SQLNotesStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.Notes, error) {
+	note, err := n.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = n.db.exec(ctx, "DELETE FROM notes WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// TrashByUser moves every live note of the user to the trash with the given timestamp
+func (n *SQLNotesStore) TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	notes, err := n.List(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	for _, note := range notes {
+		note.DeletedAt = &deletedAt
+		if err := n.save(ctx, note); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(notes)), nil
+}
+
+// RestoreByUser restores the notes of the user that were trashed at deletedAt
+func (n *SQLNotesStore) RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	notes, err := listDocs[types.Notes](ctx, n.db, "SELECT data FROM notes WHERE user_id = ? AND deleted_at = ?", userId.Hex(), sqlTime(deletedAt))
+	if err != nil {
+		return 0, err
+	}
+	for _, note := range notes {
+		note.DeletedAt = nil
+		if err := n.save(ctx, note); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(notes)), nil
+}
+
+// PurgeDeletedBefore permanently removes notes trashed before the given time
+func (n *SQLNotesStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM notes WHERE deleted_at < ?", sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// save writes the note back, keeping the indexed columns in sync with the document
+func (n *SQLNotesStore) save(ctx context.Context, note *types.Notes) error {
+	// CommentCount is computed on reads and never stored
+	doc := *note
+	doc.CommentCount = nil
+	data, err := marshalDoc(doc)
+	if err != nil {
+		return err
+	}
+	_, err = n.db.exec(ctx, "UPDATE notes SET user_id = ?, deleted_at = ?, updated_at = ?, data = ? WHERE id = ?",
+		note.UserID.Hex(), nullableTime(note.DeletedAt), sqlTime(note.UpdatedAt), data, note.Id.Hex())
+	return err
+}
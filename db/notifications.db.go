@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoNotificationsStore struct {
+	collection *mongo.Collection
+}
+
+// Create inserts a new notification and returns it
+func (n *MongoNotificationsStore) Create(ctx context.Context, notification *types.Notification) (*types.Notification, error) {
+	newNotification := *notification
+	newNotification.Id = primitive.NewObjectID()
+	newNotification.ReadAt = nil
+	newNotification.CreatedAt = timestamp()
+	if _, err := n.collection.InsertOne(ctx, newNotification); err != nil {
+		return nil, err
+	}
+	return &newNotification, nil
+}
+
+// ListByUser retrieves the latest notifications of a user, newest first
+func (n *MongoNotificationsStore) ListByUser(ctx context.Context, userId primitive.ObjectID, unreadOnly bool, limit int) ([]*types.Notification, error) {
+	filter := bson.M{"user_id": userId}
+	if unreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := n.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	notifications := []*types.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkRead marks a notification of a user as read and returns it
+func (n *MongoNotificationsStore) MarkRead(ctx context.Context, userId primitive.ObjectID, id primitive.ObjectID) (*types.Notification, error) {
+	var notification types.Notification
+	err := n.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userId}).Decode(&notification)
+	if err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return &notification, nil
+	}
+	readAt := timestamp()
+	if _, err := n.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"read_at": readAt}}); err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &readAt
+	return &notification, nil
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (n *MongoNotificationsStore) MarkAllRead(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	filter := bson.M{"user_id": userId, "read_at": bson.M{"$exists": false}}
+	result, err := n.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read_at": timestamp()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteByItem removes the notifications about an item
+func (n *MongoNotificationsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": itemId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByUser removes every notification sent to a user
+func (n *MongoNotificationsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the notifications about notes and tasks that no
+// longer exist
+func (n *MongoNotificationsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	var deleted int64
+	for itemType, collection := range map[string]string{types.ShareNote: "note", types.ShareTask: "task"} {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"item_type": itemType}}},
+			{{Key: "$group", Value: bson.M{"_id": "$item_id"}}},
+			{{Key: "$lookup", Value: bson.M{"from": collection, "localField": "_id", "foreignField": "_id", "as": "item"}}},
+			{{Key: "$match", Value: bson.M{"item": bson.M{"$size": 0}}}},
+		}
+		cursor, err := n.collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return deleted, err
+		}
+		var orphans []struct {
+			ItemId primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &orphans); err != nil {
+			return deleted, err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		itemIds := make([]primitive.ObjectID, len(orphans))
+		for i, orphan := range orphans {
+			itemIds[i] = orphan.ItemId
+		}
+		result, err := n.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": bson.M{"$in": itemIds}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLNotificationsStore struct {
+	db *sqlDB
+}
+
+// Create inserts a new notification and returns it
+func (n *SQLNotificationsStore) Create(ctx context.Context, notification *types.Notification) (*types.Notification, error) {
+	newNotification := *notification
+	newNotification.Id = primitive.NewObjectID()
+	newNotification.ReadAt = nil
+	newNotification.CreatedAt = timestamp()
+	data, err := marshalDoc(newNotification)
+	if err != nil {
+		return nil, err
+	}
+	_, err = n.db.exec(ctx, "INSERT INTO notifications (id, user_id, item_type, item_id, created_at, data) VALUES (?, ?, ?, ?, ?, ?)",
+		newNotification.Id.Hex(), newNotification.UserID.Hex(), newNotification.ItemType, newNotification.ItemID.Hex(), sqlTime(newNotification.CreatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newNotification, nil
+}
+
+// ListByUser retrieves the latest notifications of a user, newest first
+func (n *SQLNotificationsStore) ListByUser(ctx context.Context, userId primitive.ObjectID, unreadOnly bool, limit int) ([]*types.Notification, error) {
+	query := "SELECT data FROM notifications WHERE user_id = ?"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	return listDocs[types.Notification](ctx, n.db, query, userId.Hex(), limit)
+}
+
+// MarkRead marks a notification of a user as read and returns it
+func (n *SQLNotificationsStore) MarkRead(ctx context.Context, userId primitive.ObjectID, id primitive.ObjectID) (*types.Notification, error) {
+	var notification types.Notification
+	err := n.db.findDoc(ctx, &notification, "SELECT data FROM notifications WHERE id = ? AND user_id = ?", id.Hex(), userId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return &notification, nil
+	}
+	readAt := timestamp()
+	notification.ReadAt = &readAt
+	if err := n.save(ctx, &notification); err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (n *SQLNotificationsStore) MarkAllRead(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	notifications, err := listDocs[types.Notification](ctx, n.db, "SELECT data FROM notifications WHERE user_id = ? AND read_at IS NULL", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	readAt := timestamp()
+	for _, notification := range notifications {
+		notification.ReadAt = &readAt
+		if err := n.save(ctx, notification); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(notifications)), nil
+}
+
+// DeleteByItem removes the notifications about an item
+func (n *SQLNotificationsStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM notifications WHERE item_type = ? AND item_id = ?", itemType, itemId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByUser removes every notification sent to a user
+func (n *SQLNotificationsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM notifications WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the notifications about notes and tasks that no
+// longer exist
+func (n *SQLNotificationsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := n.db.exec(ctx, `DELETE FROM notifications WHERE
+		(item_type = ? AND item_id NOT IN (SELECT id FROM notes)) OR
+		(item_type = ? AND item_id NOT IN (SELECT id FROM tasks))`, types.ShareNote, types.ShareTask)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// save writes the notification back, keeping read_at in sync with the document
+func (n *SQLNotificationsStore) save(ctx context.Context, notification *types.Notification) error {
+	data, err := marshalDoc(notification)
+	if err != nil {
+		return err
+	}
+	_, err = n.db.exec(ctx, "UPDATE notifications SET read_at = ?, data = ? WHERE id = ?", nullableTime(notification.ReadAt), data, notification.Id.Hex())
+	return err
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"log"
+)
+
+// Notify sends a notification on behalf of the acting user, unless they are
+// its recipient. A notification is a side effect of the change that caused
+// it, so failures are logged rather than returned.
+func (s *Store) Notify(ctx context.Context, notification *types.Notification) {
+	if notification.ActorID == nil {
+		notification.ActorID = actorFrom(ctx)
+	}
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return
+	}
+	if _, err := s.Notifications.Create(ctx, notification); err != nil {
+		log.Printf("Failed to notify user %s: %v", notification.UserID.Hex(), err)
+	}
+}
@@ -0,0 +1,397 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-auth/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultListLimit is the page size used when a list query has no limit.
+	DefaultListLimit = 50
+	// MaxListLimit is the largest page size a list query may ask for.
+	MaxListLimit = 200
+)
+
+// ErrInvalidListQuery is returned by the ListPage methods for an unknown sort
+// field or a cursor that was not produced by a previous page.
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// sortField describes a field list endpoints may be sorted on.
+type sortField struct {
+	bson   string // Field name in Mongo documents
+	column string // Column holding the value in SQL tables, empty when it only lives in data
+	json   string // Key of the value in the SQL data column
+	time   bool   // Values are timestamps rather than strings
+	number bool   // Values are integers rather than strings
+}
+
+var (
+	createdAtSort = sortField{bson: "created_at", column: "created_at", time: true}
+	updatedAtSort = sortField{bson: "updated_at", column: "updated_at", time: true}
+)
+
+// noteSortFields, taskSortFields and userSortFields are the fields the list
+// endpoints can be sorted on.
+var (
+	noteSortFields = map[string]sortField{
+		"created_at": createdAtSort,
+		"updated_at": updatedAtSort,
+		"title":      {bson: "title", json: "title"},
+		"category":   {bson: "category", json: "category"},
+	}
+	taskSortFields = map[string]sortField{
+		"created_at": createdAtSort,
+		"updated_at": updatedAtSort,
+		"title":      {bson: "title", json: "title"},
+		"category":   {bson: "category", json: "category"},
+		"priority":   {bson: "priority", column: "priority", number: true},
+		"due_at":     {bson: "due_sort", column: "due_sort", time: true},
+	}
+	userSortFields = map[string]sortField{
+		"created_at": createdAtSort,
+		"updated_at": updatedAtSort,
+		"name":       {bson: "name", json: "name"},
+		"email":      {bson: "email", column: "email"},
+	}
+)
+
+// pageCursor is the position after the last item of a page. It is handed to
+// clients as an opaque base64 string.
+type pageCursor struct {
+	Value string `json:"v"`
+	Id    string `json:"id"`
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Id == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	return &c, nil
+}
+
+// pageRequest is a validated ListQuery.
+type pageRequest struct {
+	*types.ListQuery
+	sort   sortField
+	after  *pageCursor
+	limit  int
+	sortBy string
+}
+
+// resolveListQuery checks q against the allowed sort fields, applies the
+// default and maximum page size and decodes the cursor.
+func resolveListQuery(q *types.ListQuery, fields map[string]sortField) (*pageRequest, error) {
+	if q == nil {
+		q = &types.ListQuery{}
+	}
+	req := &pageRequest{ListQuery: q, sortBy: q.SortBy, limit: q.Limit}
+	if req.sortBy == "" {
+		req.sortBy = "created_at"
+	}
+	field, ok := fields[req.sortBy]
+	if !ok {
+		allowed := make([]string, 0, len(fields))
+		for name := range fields {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		return nil, fmt.Errorf("%w: cannot sort by %q, use one of %s", ErrInvalidListQuery, req.sortBy, strings.Join(allowed, ", "))
+	}
+	req.sort = field
+
+	if req.limit <= 0 {
+		req.limit = DefaultListLimit
+	}
+	if req.limit > MaxListLimit {
+		req.limit = MaxListLimit
+	}
+
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := primitive.ObjectIDFromHex(after.Id); err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+		}
+		req.after = after
+		if _, err := req.cursorValue(); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// pagination builds the envelope for a page, given whether more items follow
+// and the cursor of the last item.
+func (r *pageRequest) pagination(hasMore bool, last pageCursor, total *int64) *types.Pagination {
+	page := &types.Pagination{Limit: r.limit, Total: total}
+	if hasMore {
+		page.NextCursor = last.encode()
+	}
+	return page
+}
+
+// createdRange restricts a Mongo filter to the From/To window of the query.
+func (r *pageRequest) createdRange(filter bson.M) bson.M {
+	created := bson.M{}
+	if r.From != nil {
+		created["$gte"] = *r.From
+	}
+	if r.To != nil {
+		created["$lt"] = *r.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	return filter
+}
+
+// findPage runs a keyset-paginated find on a Mongo collection. Items are
+// ordered by the sort field and then by _id, so the cursor stays stable when
+// several items share a value.
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, req *pageRequest) ([]*T, *types.Pagination, error) {
+	var total *int64
+	if req.after == nil {
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		total = &count
+	}
+
+	direction, compare := 1, "$gt"
+	if req.Desc {
+		direction, compare = -1, "$lt"
+	}
+	if req.after != nil {
+		id, _ := primitive.ObjectIDFromHex(req.after.Id)
+		value, err := req.cursorValue()
+		if err != nil {
+			return nil, nil, err
+		}
+		if at, ok := value.(string); ok && req.sort.time {
+			value, err = time.Parse(time.RFC3339Nano, at)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+			}
+		}
+		filter["$or"] = bson.A{
+			bson.M{req.sort.bson: bson.M{compare: value}},
+			bson.M{req.sort.bson: value, "_id": bson.M{compare: id}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: req.sort.bson, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(req.limit) + 1)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []*T{}
+	var last pageCursor
+	for len(items) < req.limit && cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, &item)
+		last = mongoCursor(cursor.Current, req.sort)
+	}
+	hasMore := cursor.Next(ctx)
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+	return items, req.pagination(hasMore, last, total), nil
+}
+
+// mongoCursor reads the sort value and _id of a raw document.
+func mongoCursor(doc bson.Raw, field sortField) pageCursor {
+	c := pageCursor{}
+	if id, ok := doc.Lookup("_id").ObjectIDOK(); ok {
+		c.Id = id.Hex()
+	}
+	value := doc.Lookup(field.bson)
+	if at, ok := value.TimeOK(); ok {
+		c.Value = at.UTC().Format(time.RFC3339Nano)
+	} else if s, ok := value.StringValueOK(); ok {
+		c.Value = s
+	} else if n, ok := value.AsInt64OK(); ok {
+		c.Value = strconv.FormatInt(n, 10)
+	}
+	return c
+}
+
+// cursorValue is the sort value of the cursor, converted to a number for
+// numeric fields so it compares like the stored values. Timestamps stay text,
+// as SQL stores them, but must parse.
+func (r *pageRequest) cursorValue() (any, error) {
+	switch {
+	case r.sort.number:
+		n, err := strconv.ParseInt(r.after.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+		}
+		return n, nil
+	case r.sort.time:
+		if _, err := time.Parse(time.RFC3339Nano, r.after.Value); err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+		}
+	}
+	return r.after.Value, nil
+}
+
+// sqlPage holds the conditions of a paginated SQL query.
+type sqlPage struct {
+	where []string
+	args  []any
+}
+
+func (p *sqlPage) add(condition string, args ...any) {
+	p.where = append(p.where, condition)
+	p.args = append(p.args, args...)
+}
+
+// createdRange restricts the query to the From/To window of the request.
+func (p *sqlPage) createdRange(req *pageRequest) {
+	if req.From != nil {
+		p.add("created_at >= ?", sqlTime(*req.From))
+	}
+	if req.To != nil {
+		p.add("created_at < ?", sqlTime(*req.To))
+	}
+}
+
+// jsonText returns an expression reading a text value out of the data column.
+// path is a list of object keys; a negative number picks an array element
+// counted from the end.
+func (d sqlDialect) jsonText(path ...any) string {
+	if d == dialectPostgres {
+		var b strings.Builder
+		b.WriteString("(data::jsonb")
+		for i, key := range path {
+			op := "->"
+			if i == len(path)-1 {
+				op = "->>"
+			}
+			switch key := key.(type) {
+			case string:
+				fmt.Fprintf(&b, " %s '%s'", op, key)
+			case int:
+				fmt.Fprintf(&b, " %s %d", op, key)
+			}
+		}
+		b.WriteString(")")
+		return b.String()
+	}
+
+	var b strings.Builder
+	b.WriteString("$")
+	for _, key := range path {
+		switch key := key.(type) {
+		case string:
+			b.WriteString("." + key)
+		case int:
+			fmt.Fprintf(&b, "[#%d]", key)
+		}
+	}
+	return "json_extract(data, '" + b.String() + "')"
+}
+
+// sortExpr returns the SQL expression the request is ordered by.
+func (r *pageRequest) sortExpr(d sqlDialect) string {
+	if r.sort.number {
+		return "COALESCE(" + r.sort.column + ", 0)"
+	}
+	if r.sort.column != "" {
+		return "COALESCE(" + r.sort.column + ", '')"
+	}
+	return "COALESCE(" + d.jsonText(r.sort.json) + ", '')"
+}
+
+// listPage runs a keyset-paginated query on a SQL table, ordered by the sort
+// field and then by id.
+func listPage[T any](ctx context.Context, s *sqlDB, table string, page *sqlPage, req *pageRequest) ([]*T, *types.Pagination, error) {
+	sortExpr := req.sortExpr(s.dialect)
+
+	var total *int64
+	if req.after == nil {
+		var count int64
+		query := "SELECT COUNT(*) FROM " + table + " WHERE " + strings.Join(page.where, " AND ")
+		if err := s.queryRow(ctx, query, page.args...).Scan(&count); err != nil {
+			return nil, nil, err
+		}
+		total = &count
+	}
+
+	direction, compare := "ASC", ">"
+	if req.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if req.after != nil {
+		value, err := req.cursorValue()
+		if err != nil {
+			return nil, nil, err
+		}
+		page.add(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortExpr, compare),
+			value, value, req.after.Id)
+	}
+
+	query := fmt.Sprintf("SELECT id, %s, data FROM %s WHERE %s ORDER BY %s %s, id %s LIMIT ?",
+		sortExpr, table, strings.Join(page.where, " AND "), sortExpr, direction, direction)
+	rows, err := s.query(ctx, query, append(page.args, req.limit+1)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := []*T{}
+	var last pageCursor
+	hasMore := false
+	for rows.Next() {
+		if len(items) == req.limit {
+			hasMore = true
+			break
+		}
+		var id, value, data string
+		if err := rows.Scan(&id, &value, &data); err != nil {
+			return nil, nil, err
+		}
+		var item T
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, &item)
+		last = pageCursor{Value: value, Id: id}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return items, req.pagination(hasMore, last, total), nil
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang-auth/types"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPageCursor(t *testing.T) {
+	want := pageCursor{Value: "2024-05-01T10:00:00.000Z", Id: primitive.NewObjectID().Hex()}
+	got, err := decodeCursor(want.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("decoded cursor %+v, want %+v", *got, want)
+	}
+}
+
+func TestResolveListQueryRejectsCursor(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		sortBy string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"v":"x","id":"` + id + `"}`))},
+		{name: "not json", cursor: raw("not json")},
+		{name: "no id", cursor: raw(`{"v":"2024-05-01T10:00:00.000Z"}`)},
+		{name: "id not an object id", cursor: raw(`{"v":"2024-05-01T10:00:00.000Z","id":"42"}`)},
+		{name: "time not a time", cursor: pageCursor{Value: "yesterday", Id: id}.encode()},
+		{name: "time injected", cursor: pageCursor{Value: "' OR 1=1 --", Id: id}.encode()},
+		{name: "number not a number", sortBy: "priority", cursor: pageCursor{Value: "high", Id: id}.encode()},
+		{name: "truncated", cursor: pageCursor{Value: "2024-05-01T10:00:00.000Z", Id: id}.encode()[:20]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveListQuery(&types.ListQuery{SortBy: tt.sortBy, Cursor: tt.cursor}, taskSortFields)
+			if !errors.Is(err, ErrInvalidListQuery) {
+				t.Errorf("cursor %q returned %v, want ErrInvalidListQuery", tt.cursor, err)
+			}
+		})
+	}
+
+	if _, err := resolveListQuery(&types.ListQuery{SortBy: "password"}, userSortFields); !errors.Is(err, ErrInvalidListQuery) {
+		t.Errorf("sorting on an unknown field returned %v, want ErrInvalidListQuery", err)
+	}
+}
+
+func TestResolveListQueryLimit(t *testing.T) {
+	for limit, want := range map[int]int{0: DefaultListLimit, -1: DefaultListLimit, 10: 10, MaxListLimit + 1: MaxListLimit} {
+		req, err := resolveListQuery(&types.ListQuery{Limit: limit}, noteSortFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.limit != want {
+			t.Errorf("limit %d became %d, want %d", limit, req.limit, want)
+		}
+	}
+}
+
+// TestListPageTies pages through items that share their sort value, which
+// only the id tells apart.
+func TestListPageTies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		user := createTestUser(t, store, "owner")
+		const count = 7
+		var noteIds, taskIds []primitive.ObjectID
+		for i := 0; i < count; i++ {
+			note, err := store.Notes.Create(ctx, &types.NotesCreate{Title: "Same", Note: "body", UserID: user.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			noteIds = append(noteIds, note.Id)
+			task, err := store.Tasks.Create(ctx, &types.TasksCreate{
+				Title:         fmt.Sprintf("Task %d", i),
+				Priority:      types.PriorityLow + types.Priority(i%2),
+				UserID:        user.Id,
+				StatusHistory: []*types.Status{{Status: "todo", UserId: user.Id.Hex()}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			taskIds = append(taskIds, task.Id)
+		}
+
+		for _, desc := range []bool{false, true} {
+			t.Run(fmt.Sprintf("notes by title desc=%v", desc), func(t *testing.T) {
+				got := collectPages(t, func(cursor string) ([]primitive.ObjectID, *types.Pagination, error) {
+					notes, page, err := store.Notes.ListPage(ctx, user.Id, &types.ListQuery{SortBy: "title", Desc: desc, Limit: 3, Cursor: cursor})
+					ids := make([]primitive.ObjectID, len(notes))
+					for i, note := range notes {
+						ids[i] = note.Id
+					}
+					return ids, page, err
+				})
+				checkPages(t, got, noteIds)
+				for i := 1; i < len(got); i++ {
+					if (got[i-1].Hex() < got[i].Hex()) == desc {
+						t.Fatalf("notes of equal title are not ordered by id: %v", got)
+					}
+				}
+			})
+
+			t.Run(fmt.Sprintf("tasks by priority desc=%v", desc), func(t *testing.T) {
+				priorities := map[primitive.ObjectID]types.Priority{}
+				got := collectPages(t, func(cursor string) ([]primitive.ObjectID, *types.Pagination, error) {
+					tasks, page, err := store.Tasks.ListPage(ctx, user.Id, &types.ListQuery{SortBy: "priority", Desc: desc, Limit: 2, Cursor: cursor})
+					ids := make([]primitive.ObjectID, len(tasks))
+					for i, task := range tasks {
+						ids[i] = task.Id
+						priorities[task.Id] = task.Priority
+					}
+					return ids, page, err
+				})
+				checkPages(t, got, taskIds)
+				for i := 1; i < len(got); i++ {
+					prev, cur := priorities[got[i-1]], priorities[got[i]]
+					if (!desc && prev > cur) || (desc && prev < cur) {
+						t.Fatalf("priority %d listed before %d", prev, cur)
+					}
+					if prev == cur && (got[i-1].Hex() < got[i].Hex()) == desc {
+						t.Fatalf("tasks of equal priority are not ordered by id: %v", got)
+					}
+				}
+			})
+		}
+	})
+}
+
+// collectPages follows the cursors of list until the last page and returns
+// the ids of every page, in order.
+func collectPages(t *testing.T, list func(cursor string) ([]primitive.ObjectID, *types.Pagination, error)) []primitive.ObjectID {
+	t.Helper()
+	var all []primitive.ObjectID
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("the pages never end")
+		}
+		ids, page, err := list(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (pages == 0) != (page.Total != nil) {
+			t.Errorf("page %d has total %v, want it on the first page only", pages, page.Total)
+		}
+		all = append(all, ids...)
+		if page.NextCursor == "" {
+			return all
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// checkPages fails unless got lists each of want exactly once.
+func checkPages(t *testing.T, got, want []primitive.ObjectID) {
+	t.Helper()
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range got {
+		if seen[id] {
+			t.Errorf("%s is listed twice", id.Hex())
+		}
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			t.Errorf("%s is missing from the pages", id.Hex())
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("pages list %d items, want %d", len(got), len(want))
+	}
+}
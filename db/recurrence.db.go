@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotRecurring is returned when skipping an occurrence of a task that does
+// not recur.
+var ErrNotRecurring = errors.New("the task does not recur")
+
+// occurrenceComment is recorded with the first status of generated occurrences.
+const occurrenceComment = "Next occurrence of a recurring task"
+
+// recurrenceBatch is how many recurring tasks CreateDueOccurrences handles at
+// a time.
+const recurrenceBatch = 100
+
+// NewTaskRecurrence starts a series that repeats by rule from at, in the IANA
+// zone timezone, UTC when empty. The next occurrence is the first one after
+// both at and now.
+func NewTaskRecurrence(rule string, at time.Time, timezone string, now time.Time) (*types.TaskRecurrence, error) {
+	rrule, err := utils.ParseRRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	at = at.UTC()
+	return &types.TaskRecurrence{
+		Rule:       rrule.String(),
+		Start:      at,
+		Occurrence: 1,
+		At:         at,
+		NextAt:     followingOccurrence(rrule, at, at, now, location),
+	}, nil
+}
+
+// followingOccurrence returns when the occurrence after the one at at is
+// scheduled, skipping those before now, or nil once the series has ended.
+func followingOccurrence(rule *utils.RRule, start time.Time, at time.Time, now time.Time, location *time.Location) *time.Time {
+	after := at
+	if now.After(after) {
+		after = now
+	}
+	next, _, ok := rule.Next(start.In(location), after.In(location))
+	if !ok {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// NextOccurrence creates the next occurrence of a recurring task, due when
+// the rule schedules it, and links the task to it. It returns the task as
+// linked and the new occurrence, which is nil when the series has ended or
+// the occurrence already exists.
+func (s *Store) NextOccurrence(ctx context.Context, task *types.Tasks) (*types.Tasks, *types.Tasks, error) {
+	recurrence := task.Recurrence
+	if recurrence == nil || recurrence.NextID != nil || recurrence.NextAt == nil {
+		return task, nil, nil
+	}
+	rule, err := utils.ParseRRule(recurrence.Rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	location, err := time.LoadLocation(task.DueTimezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	at := *recurrence.NextAt
+	_, position, ok := rule.Next(recurrence.Start.In(location), at.In(location).Add(-time.Nanosecond))
+	if !ok {
+		position = recurrence.Occurrence + 1
+	}
+	workflow, err := s.TaskWorkflow(ctx, task.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var userId string
+	if actor := actorFrom(ctx); actor != nil {
+		userId = actor.Hex()
+	}
+	occurrence := &types.TasksCreate{
+		Id:            primitive.NewObjectID(),
+		Title:         task.Title,
+		Category:      task.Category,
+		Task:          task.Task,
+		UserID:        task.UserID,
+		Assignees:     task.Assignees,
+		AutoComplete:  task.AutoComplete,
+		StatusHistory: []*types.Status{{Status: workflow.Initial, Comment: occurrenceComment, UserId: userId}},
+		Tags:          task.Tags,
+		Priority:      task.Priority,
+		DueTimezone:   task.DueTimezone,
+		Recurrence: &types.TaskRecurrence{
+			Rule:       recurrence.Rule,
+			Start:      recurrence.Start,
+			Occurrence: position,
+			At:         at,
+			NextAt:     followingOccurrence(rule, recurrence.Start, at, s.Now(), location),
+		},
+	}
+	for _, item := range task.Checklist {
+		occurrence.Checklist = append(occurrence.Checklist, &types.TaskChecklistItem{Id: primitive.NewObjectID(), Text: item.Text})
+	}
+	if task.DueAt != nil {
+		occurrence.DueAt = &at
+	}
+	if task.StartAt != nil {
+		startAt := task.StartAt.Add(at.Sub(recurrence.At))
+		occurrence.StartAt = &startAt
+	}
+	if task.ParentID != nil {
+		// Occurrences of a subtask stay under its parent while it is live
+		_, err := s.Tasks.Get(ctx, *task.ParentID)
+		if err != nil && err != ErrNotFound {
+			return nil, nil, err
+		}
+		if err == nil {
+			occurrence.ParentID, occurrence.Ancestors = task.ParentID, task.Ancestors
+		}
+	}
+
+	var linked, next *types.Tasks
+	err = s.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if linked, err = s.Tasks.LinkOccurrence(ctx, task.Id, occurrence.Id); err != nil {
+			return err
+		}
+		next, err = s.Tasks.Create(ctx, occurrence)
+		return err
+	})
+	if err == ErrNotFound {
+		// Another request created the occurrence first
+		return task, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return linked, next, nil
+}
+
+// Recur is called when a task is completed and creates its next occurrence
+// when it recurs. It returns the task as it is afterwards. The occurrence is
+// a side effect of the change, so failures are logged rather than returned.
+func (s *Store) Recur(ctx context.Context, task *types.Tasks) *types.Tasks {
+	linked, _, err := s.NextOccurrence(ctx, task)
+	if err != nil {
+		log.Printf("Failed to create the next occurrence of task %s: %v", task.Id.Hex(), err)
+		return task
+	}
+	return linked
+}
+
+// SkipOccurrence moves an occurrence of a recurring task to the trash after
+// making sure the next one exists, and returns the next occurrence, or nil
+// when the series has ended.
+func (s *Store) SkipOccurrence(ctx context.Context, task *types.Tasks) (*types.Tasks, error) {
+	if task.Recurrence == nil {
+		return nil, ErrNotRecurring
+	}
+	linked, next, err := s.NextOccurrence(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Tasks.Delete(ctx, task.Id); err != nil {
+		return nil, err
+	}
+	if next == nil && linked.Recurrence != nil && linked.Recurrence.NextID != nil {
+		next, err = s.Tasks.Get(ctx, *linked.Recurrence.NextID)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+	}
+	return next, err
+}
+
+// CreateDueOccurrences creates the occurrences whose time has come, for the
+// series whose current occurrence was not completed, and returns how many it
+// created.
+func (s *Store) CreateDueOccurrences(ctx context.Context) (int, error) {
+	created := 0
+	for {
+		tasks, err := s.Tasks.ListRecurring(ctx, s.Now(), recurrenceBatch)
+		if err != nil {
+			return created, err
+		}
+		for _, task := range tasks {
+			_, next, err := s.NextOccurrence(ctx, task)
+			if err != nil {
+				return created, err
+			}
+			if next != nil {
+				created++
+			}
+		}
+		if len(tasks) < recurrenceBatch {
+			return created, nil
+		}
+	}
+}
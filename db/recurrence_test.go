@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// setTestClock makes store read the time from *now, so that tests can move it.
+func setTestClock(store *Store, now *time.Time) {
+	store.Clock = func() time.Time { return *now }
+}
+
+// createRecurringTask adds a task due at at that repeats by rule in UTC.
+func createRecurringTask(t *testing.T, store *Store, userId primitive.ObjectID, rule string, at time.Time) *types.Tasks {
+	t.Helper()
+	recurrence, err := NewTaskRecurrence(rule, at, "UTC", store.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := store.Tasks.Create(context.Background(), &types.TasksCreate{
+		Title:         "Water the plants",
+		UserID:        userId,
+		StatusHistory: []*types.Status{{Status: "todo", UserId: userId.Hex()}},
+		DueAt:         &at,
+		DueTimezone:   "UTC",
+		Recurrence:    recurrence,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func TestNewTaskRecurrence(t *testing.T) {
+	at := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		rule     string
+		timezone string
+		now      time.Time
+		want     *time.Time
+	}{
+		{name: "next after at", rule: "FREQ=DAILY", now: at.Add(-time.Hour), want: ptr(at.AddDate(0, 0, 1))},
+		{name: "skips missed occurrences", rule: "FREQ=DAILY", now: at.AddDate(0, 0, 3), want: ptr(at.AddDate(0, 0, 4))},
+		{name: "series ended", rule: "FREQ=DAILY;COUNT=1", now: at},
+		{name: "ended while missed", rule: "FREQ=DAILY;COUNT=3", now: at.AddDate(0, 0, 3)},
+		{name: "in a time zone", rule: "FREQ=WEEKLY", timezone: "Europe/Paris", now: at, want: ptr(at.AddDate(0, 0, 7))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recurrence, err := NewTaskRecurrence(tt.rule, at, tt.timezone, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recurrence.Occurrence != 1 || !recurrence.Start.Equal(at) || !recurrence.At.Equal(at) {
+				t.Errorf("recurrence starts as %+v, want the first occurrence at %s", recurrence, at)
+			}
+			switch {
+			case tt.want == nil && recurrence.NextAt != nil:
+				t.Errorf("next occurrence is at %s, want none", recurrence.NextAt)
+			case tt.want != nil && (recurrence.NextAt == nil || !recurrence.NextAt.Equal(*tt.want)):
+				t.Errorf("next occurrence is at %v, want %s", recurrence.NextAt, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewTaskRecurrence("FREQ=HOURLY", at, "", at); err == nil {
+		t.Error("NewTaskRecurrence accepted an invalid rule")
+	}
+	if _, err := NewTaskRecurrence("FREQ=DAILY", at, "Nowhere/City", at); err == nil {
+		t.Error("NewTaskRecurrence accepted an unknown time zone")
+	}
+}
+
+func TestCreateDueOccurrences(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	now := time.Date(2026, time.January, 5, 10, 0, 0, 0, time.UTC)
+	setTestClock(store, &now)
+	user := createTestUser(t, store, "owner")
+	at := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	task := createRecurringTask(t, store, user.Id, "FREQ=DAILY", at)
+
+	if created, err := store.CreateDueOccurrences(ctx); err != nil || created != 0 {
+		t.Fatalf("CreateDueOccurrences before the next occurrence = %d, %v, want 0", created, err)
+	}
+
+	// Three days pass without the task being completed
+	now = time.Date(2026, time.January, 8, 12, 0, 0, 0, time.UTC)
+	if created, err := store.CreateDueOccurrences(ctx); err != nil || created != 1 {
+		t.Fatalf("CreateDueOccurrences once due = %d, %v, want 1", created, err)
+	}
+	if created, err := store.CreateDueOccurrences(ctx); err != nil || created != 0 {
+		t.Fatalf("CreateDueOccurrences run again = %d, %v, want 0", created, err)
+	}
+
+	linked, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.Recurrence.NextID == nil {
+		t.Fatal("the task is not linked to its next occurrence")
+	}
+	next, err := store.Tasks.Get(ctx, *linked.Recurrence.NextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurrence := next.Recurrence
+	if want := at.AddDate(0, 0, 1); recurrence.Occurrence != 2 || !recurrence.At.Equal(want) || next.DueAt == nil || !next.DueAt.Equal(want) {
+		t.Errorf("occurrence %d is at %s and due %v, want occurrence 2 due at %s", recurrence.Occurrence, recurrence.At, next.DueAt, want)
+	}
+	// The occurrences of January 7 and 8 were missed
+	if want := at.AddDate(0, 0, 4); recurrence.NextAt == nil || !recurrence.NextAt.Equal(want) {
+		t.Errorf("the following occurrence is at %v, want %s", recurrence.NextAt, want)
+	}
+	if next.CurrentStatus != "todo" {
+		t.Errorf("occurrence has status %q, want todo", next.CurrentStatus)
+	}
+}
+
+func TestSkipOccurrence(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	now := time.Date(2026, time.January, 5, 10, 0, 0, 0, time.UTC)
+	setTestClock(store, &now)
+	user := createTestUser(t, store, "owner")
+	at := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+
+	t.Run("returns the next occurrence", func(t *testing.T) {
+		task := createRecurringTask(t, store, user.Id, "FREQ=WEEKLY", at)
+		next, err := store.SkipOccurrence(ctx, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == nil || next.Recurrence.Occurrence != 2 || !next.Recurrence.At.Equal(at.AddDate(0, 0, 7)) {
+			t.Fatalf("SkipOccurrence returned %+v, want the occurrence a week later", next)
+		}
+		if _, err := store.Tasks.Get(ctx, task.Id); err != ErrNotFound {
+			t.Errorf("the skipped occurrence is still live: %v", err)
+		}
+		// Skipping again after the next one was created moves on from it
+		again, err := store.SkipOccurrence(ctx, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again == nil || again.Recurrence.Occurrence != 3 {
+			t.Errorf("skipping the second occurrence returned %+v, want the third", again)
+		}
+	})
+	t.Run("last occurrence", func(t *testing.T) {
+		task := createRecurringTask(t, store, user.Id, "FREQ=DAILY;COUNT=1", at)
+		next, err := store.SkipOccurrence(ctx, task)
+		if err != nil || next != nil {
+			t.Fatalf("SkipOccurrence of the last occurrence = %+v, %v, want nil", next, err)
+		}
+		if _, err := store.Tasks.Get(ctx, task.Id); err != ErrNotFound {
+			t.Errorf("the skipped occurrence is still live: %v", err)
+		}
+	})
+	t.Run("not recurring", func(t *testing.T) {
+		task := createTestTask(t, store, user.Id, "Once")
+		if _, err := store.SkipOccurrence(ctx, task); err != ErrNotRecurring {
+			t.Errorf("SkipOccurrence of a plain task returned %v, want ErrNotRecurring", err)
+		}
+	})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoNoteRevisionsStore struct {
+	collection *mongo.Collection
+}
+
+// Create saves a revision; it returns ErrNoteVersionConflict when the note
+// already has a revision with the same version
+func (r *MongoNoteRevisionsStore) Create(ctx context.Context, revision *types.NoteRevision) error {
+	revision.Id = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, revision)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrNoteVersionConflict
+	}
+	return err
+}
+
+// List retrieves the revisions of a note, newest first
+func (r *MongoNoteRevisionsStore) List(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"note_id": noteId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	revisions := []*types.NoteRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Get retrieves a single version of a note
+func (r *MongoNoteRevisionsStore) Get(ctx context.Context, noteId primitive.ObjectID, version int) (*types.NoteRevision, error) {
+	var revision types.NoteRevision
+	err := r.collection.FindOne(ctx, bson.M{"note_id": noteId, "version": version}).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Prune keeps the newest keep revisions of a note and removes the rest
+func (r *MongoNoteRevisionsStore) Prune(ctx context.Context, noteId primitive.ObjectID, keep int) (int64, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetSkip(int64(keep)).
+		SetProjection(bson.M{"version": 1})
+	var oldest types.NoteRevision
+	err := r.collection.FindOne(ctx, bson.M{"note_id": noteId}, opts).Decode(&oldest)
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.collection.DeleteMany(ctx, bson.M{"note_id": noteId, "version": bson.M{"$lte": oldest.Version}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByNote removes every revision of a note
+func (r *MongoNoteRevisionsStore) DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"note_id": noteId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the revisions of notes that no longer exist
+func (r *MongoNoteRevisionsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$note_id"}}},
+		{{Key: "$lookup", Value: bson.M{"from": "note", "localField": "_id", "foreignField": "_id", "as": "note"}}},
+		{{Key: "$match", Value: bson.M{"note": bson.M{"$size": 0}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var orphans []struct {
+		NoteId primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &orphans); err != nil {
+		return 0, err
+	}
+	if len(orphans) == 0 {
+		return 0, nil
+	}
+	noteIds := make([]primitive.ObjectID, len(orphans))
+	for i, orphan := range orphans {
+		noteIds[i] = orphan.NoteId
+	}
+	result, err := r.collection.DeleteMany(ctx, bson.M{"note_id": bson.M{"$in": noteIds}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLNoteRevisionsStore struct {
+	db *sqlDB
+}
+
+// Create saves a revision; it returns ErrNoteVersionConflict when the note
+// already has a revision with the same version
+func (r *SQLNoteRevisionsStore) Create(ctx context.Context, revision *types.NoteRevision) error {
+	revision.Id = primitive.NewObjectID()
+	data, err := marshalDoc(revision)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.exec(ctx, "INSERT INTO note_revisions (id, note_id, version, created_at, data) VALUES (?, ?, ?, ?, ?)",
+		revision.Id.Hex(), revision.NoteID.Hex(), revision.Version, sqlTime(revision.CreatedAt), data)
+	if isUniqueViolation(err) {
+		return ErrNoteVersionConflict
+	}
+	return err
+}
+
+// List retrieves the revisions of a note, newest first
+func (r *SQLNoteRevisionsStore) List(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteRevision, error) {
+	return listDocs[types.NoteRevision](ctx, r.db, "SELECT data FROM note_revisions WHERE note_id = ? ORDER BY version DESC", noteId.Hex())
+}
+
+// Get retrieves a single version of a note
+func (r *SQLNoteRevisionsStore) Get(ctx context.Context, noteId primitive.ObjectID, version int) (*types.NoteRevision, error) {
+	var revision types.NoteRevision
+	err := r.db.findDoc(ctx, &revision, "SELECT data FROM note_revisions WHERE note_id = ? AND version = ?", noteId.Hex(), version)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Prune keeps the newest keep revisions of a note and removes the rest
+func (r *SQLNoteRevisionsStore) Prune(ctx context.Context, noteId primitive.ObjectID, keep int) (int64, error) {
+	var oldest int
+	err := r.db.queryRow(ctx, "SELECT version FROM note_revisions WHERE note_id = ? ORDER BY version DESC LIMIT 1 OFFSET ?",
+		noteId.Hex(), keep).Scan(&oldest)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.db.exec(ctx, "DELETE FROM note_revisions WHERE note_id = ? AND version <= ?", noteId.Hex(), oldest)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByNote removes every revision of a note
+func (r *SQLNoteRevisionsStore) DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error) {
+	result, err := r.db.exec(ctx, "DELETE FROM note_revisions WHERE note_id = ?", noteId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the revisions of notes that no longer exist
+func (r *SQLNoteRevisionsStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := r.db.exec(ctx, "DELETE FROM note_revisions WHERE note_id NOT IN (SELECT id FROM notes)")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultSearchLimit is the number of results returned when a search has no limit.
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the largest number of results a search may ask for.
+	MaxSearchLimit = 100
+
+	// snippetRadius is how many characters of context a highlight keeps on
+	// each side of the first match.
+	snippetRadius  = 60
+	maxSearchTerms = 16
+)
+
+// ErrEmptySearch is returned when the search text contains no words.
+var ErrEmptySearch = errors.New("search text must contain at least one word")
+
+// Search runs a full-text search over the notes and tasks of a user and the
+// ones shared with them, subtasks of shared tasks included, and returns the
+// best matches first, with highlighted snippets of the fields that matched.
+// Any word of the query may match; items matching more words, or matching in
+// the title, rank higher. Items in the trash are left out.
+func (s *Store) Search(ctx context.Context, userId primitive.ObjectID, query *types.SearchQuery) ([]*types.SearchResult, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultSearchLimit
+	}
+	if query.Limit > MaxSearchLimit {
+		query.Limit = MaxSearchLimit
+	}
+
+	shares, err := s.Shares.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	var sharedNotes, sharedTasks []primitive.ObjectID
+	for _, share := range shares {
+		switch share.ItemType {
+		case types.ShareNote:
+			sharedNotes = append(sharedNotes, share.ItemID)
+		case types.ShareTask:
+			sharedTasks = append(sharedTasks, share.ItemID)
+		}
+	}
+
+	results := []*types.SearchResult{}
+	if query.Type == "" || query.Type == "notes" {
+		notes, err := s.Notes.Search(ctx, userId, sharedNotes, terms, query)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, notes...)
+	}
+	if query.Type == "" || query.Type == "tasks" {
+		tasks, err := s.Tasks.Search(ctx, userId, sharedTasks, terms, query)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, tasks...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	for _, result := range results {
+		result.Highlights = highlightResult(result, terms)
+	}
+	return results, nil
+}
+
+// searchTerms splits the search text into lower-case words. Everything that
+// is not a letter or a digit separates words, so the terms are safe to pass
+// to every backend's query syntax.
+func searchTerms(text string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if seen[word] || len(terms) == maxSearchTerms {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+func highlightResult(result *types.SearchResult, terms []string) map[string]string {
+	fields := map[string]string{}
+	if result.Note != nil {
+		fields["title"], fields["note"], fields["category"] = result.Note.Title, result.Note.Note, result.Note.Category
+	}
+	if result.Task != nil {
+		fields["title"], fields["task"], fields["category"] = result.Task.Title, result.Task.Task, result.Task.Category
+	}
+
+	highlights := map[string]string{}
+	for field, text := range fields {
+		if snippet, ok := highlight(text, terms); ok {
+			highlights[field] = snippet
+		}
+	}
+	return highlights
+}
+
+// highlight returns the part of text around the first matching term, with
+// every match wrapped in <mark>. Terms match the start of a word, so "run"
+// also marks "running" the way the stemming indexes match it.
+func highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length, fall back to a case-sensitive match
+		lower = runes
+	}
+
+	type match struct{ start, end int }
+	var matches []match
+	for i := 0; i < len(lower); i++ {
+		if i > 0 && (unicode.IsLetter(lower[i-1]) || unicode.IsDigit(lower[i-1])) {
+			continue
+		}
+		for _, term := range terms {
+			t := []rune(term)
+			if i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == term {
+				matches = append(matches, match{i, i + len(t)})
+				i += len(t) - 1
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := max(matches[0].start-snippetRadius, 0)
+	end := min(matches[0].end+snippetRadius, len(runes))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		b.WriteString(string(runes[pos:m.start]))
+		b.WriteString("<mark>" + string(runes[m.start:m.end]) + "</mark>")
+		pos = m.end
+	}
+	b.WriteString(string(runes[pos:end]))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// searchOwners matches the documents of a user and the ones in shared.
+// With subtasks, documents below one in shared match too.
+func searchOwners(userId primitive.ObjectID, shared []primitive.ObjectID, subtasks bool) bson.A {
+	owners := bson.A{bson.M{"user_id": userId}}
+	if len(shared) > 0 {
+		owners = append(owners, bson.M{"_id": bson.M{"$in": shared}})
+		if subtasks {
+			owners = append(owners, bson.M{"ancestors": bson.M{"$in": shared}})
+		}
+	}
+	return owners
+}
+
+// searchFilter builds the Mongo filter shared by note and task searches,
+// matching documents of any of owners.
+func searchFilter(owners bson.A, terms []string, query *types.SearchQuery) bson.M {
+	filter := notDeleted(bson.M{
+		"$or":   owners,
+		"$text": bson.M{"$search": strings.Join(terms, " ")},
+	})
+	if query.Category != "" {
+		filter["category"] = query.Category
+	}
+	created := bson.M{}
+	if query.From != nil {
+		created["$gte"] = *query.From
+	}
+	if query.To != nil {
+		created["$lt"] = *query.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	return filter
+}
+
+// searchCollection runs a $text search and decodes each match into T along
+// with its text score.
+func searchCollection[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, limit int, result func(*T, float64) *types.SearchResult) ([]*types.SearchResult, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []*types.SearchResult{}
+	for cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		results = append(results, result(&item, cursor.Current.Lookup("score").Double()))
+	}
+	return results, cursor.Err()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang-auth/types"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// searchTables maps the searchable tables to the JSON key of their body text.
+var searchTables = map[string]string{
+	"notes": "note",
+	"tasks": "task",
+}
+
+// createSQLSearchIndexes sets up full-text search for the SQL backends.
+//
+// SQLite keeps a separate FTS5 table per searchable table, filled by triggers
+// so the stores do not need to know about it. PostgreSQL indexes a weighted
+// tsvector expression over the data column, which needs no extra table.
+func createSQLSearchIndexes(ctx context.Context, s *sqlDB) error {
+	for table, body := range searchTables {
+		var statements []string
+		if s.dialect == dialectPostgres {
+			statements = []string{
+				fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_search_idx ON %s USING GIN (%s)", table, table, postgresSearchVector(body)),
+			}
+		} else {
+			columns := fmt.Sprintf("json_extract(%%[1]s.data, '$.title'), json_extract(%%[1]s.data, '$.%s'), json_extract(%%[1]s.data, '$.category')", body)
+			insert := fmt.Sprintf("INSERT INTO %s_search (id, title, body, category) SELECT %%[1]s.id, %s", table, columns)
+			statements = []string{
+				fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s_search USING fts5(id UNINDEXED, title, body, category, tokenize = 'porter unicode61')", table),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_search_insert AFTER INSERT ON %[1]s BEGIN %[2]s; END", table, fmt.Sprintf(insert, "new")),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_search_update AFTER UPDATE OF data ON %[1]s BEGIN DELETE FROM %[1]s_search WHERE id = old.id; %[2]s; END", table, fmt.Sprintf(insert, "new")),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_search_delete AFTER DELETE ON %[1]s BEGIN DELETE FROM %[1]s_search WHERE id = old.id; END", table),
+				fmt.Sprintf(insert, table) + " FROM " + table,
+			}
+		}
+		for _, stmt := range statements {
+			if _, err := s.exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// postgresSearchVector is the tsvector indexed and queried for a table. The
+// title weighs most, then the category, then the body, as in the Mongo index.
+func postgresSearchVector(body string) string {
+	field := func(key, weight string) string {
+		return fmt.Sprintf("setweight(to_tsvector('english', COALESCE(data::jsonb ->> '%s', '')), '%s')", key, weight)
+	}
+	return "(" + field("title", "A") + " || " + field("category", "B") + " || " + field(body, "C") + ")"
+}
+
+// searchTable finds the rows of table owned by userId, or in shared, matching
+// any of the terms, best matches first. Tasks below a shared one match too.
+func searchTable[T any](ctx context.Context, s *sqlDB, table string, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery, result func(*T, float64) *types.SearchResult) ([]*types.SearchResult, error) {
+	page := &sqlPage{}
+	var from string
+	if s.dialect == dialectPostgres {
+		vector := postgresSearchVector(searchTables[table])
+		from = fmt.Sprintf("SELECT data, ts_rank(%s, to_tsquery('english', ?)) AS score FROM %s", vector, table)
+		tsquery := strings.Join(terms, " | ")
+		page.args = append(page.args, tsquery)
+		page.add(vector+" @@ to_tsquery('english', ?)", tsquery)
+	} else {
+		// bm25 is lower for better matches; the weights follow the columns
+		// id, title, body and category
+		from = fmt.Sprintf("SELECT %[1]s.data, -bm25(%[1]s_search, 0, 10.0, 1.0, 2.0) AS score FROM %[1]s_search JOIN %[1]s ON %[1]s.id = %[1]s_search.id", table)
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + term + `"`
+		}
+		page.add(table+"_search MATCH ?", strings.Join(quoted, " OR "))
+	}
+	if len(shared) == 0 {
+		page.add(table+".user_id = ?", userId.Hex())
+	} else {
+		owners := table + ".user_id = ? OR " + table + ".id IN (" + placeholders(len(shared)) + ")"
+		args := append([]any{userId.Hex()}, hexIds(shared)...)
+		if table == "tasks" {
+			owners += " OR " + s.dialect.jsonArrayContainsAny(table, "ancestors", len(shared))
+			args = append(args, hexIds(shared)...)
+		}
+		page.add("("+owners+")", args...)
+	}
+	page.add(table + ".deleted_at IS NULL")
+	if query.Category != "" {
+		page.add(s.dialect.jsonText("category")+" = ?", query.Category)
+	}
+	if query.From != nil {
+		page.add(table+".created_at >= ?", sqlTime(*query.From))
+	}
+	if query.To != nil {
+		page.add(table+".created_at < ?", sqlTime(*query.To))
+	}
+
+	rows, err := s.query(ctx, from+" WHERE "+strings.Join(page.where, " AND ")+" ORDER BY score DESC LIMIT ?",
+		append(page.args, query.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*types.SearchResult{}
+	for rows.Next() {
+		var data string
+		var score float64
+		if err := rows.Scan(&data, &score); err != nil {
+			return nil, err
+		}
+		var item T
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, err
+		}
+		results = append(results, result(&item, score))
+	}
+	return results, rows.Err()
+}
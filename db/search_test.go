@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// searchTitles searches as userId and returns the titles found, sorted.
+func searchTitles(t *testing.T, store *Store, userId primitive.ObjectID, query *types.SearchQuery) []string {
+	t.Helper()
+	results, err := store.Search(context.Background(), userId, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, result := range results {
+		switch {
+		case result.Note != nil:
+			titles = append(titles, result.Note.Title)
+		case result.Task != nil:
+			titles = append(titles, result.Task.Title)
+		}
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestSearchScope(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		me, other := createTestUser(t, store, "me"), createTestUser(t, store, "other")
+		share := func(itemType string, itemId primitive.ObjectID) {
+			t.Helper()
+			_, err := store.Shares.Upsert(ctx, &types.Share{ItemType: itemType, ItemID: itemId, UserID: me.Id, Permission: types.PermissionView})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		createTestNote(t, store, me.Id, "apple pie")
+		if _, err := store.Notes.Delete(ctx, createTestNote(t, store, me.Id, "apple trashed").Id); err != nil {
+			t.Fatal(err)
+		}
+		createTestTask(t, store, me.Id, "apple chores")
+		createTestNote(t, store, other.Id, "apple secret")
+		createTestTask(t, store, other.Id, "apple private")
+		share(types.ShareNote, createTestNote(t, store, other.Id, "apple shared").Id)
+		gone := createTestNote(t, store, other.Id, "apple shared then trashed")
+		share(types.ShareNote, gone.Id)
+		if _, err := store.Notes.Delete(ctx, gone.Id); err != nil {
+			t.Fatal(err)
+		}
+		project := createTestTask(t, store, other.Id, "apple project")
+		share(types.ShareTask, project.Id)
+		if _, err := store.CreateSubtask(ctx, project, &types.TasksCreate{
+			Title:         "apple step",
+			UserID:        other.Id,
+			StatusHistory: []*types.Status{{Status: "todo", UserId: other.Id.Hex()}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			name  string
+			user  primitive.ObjectID
+			query types.SearchQuery
+			want  []string
+		}{
+			{"own and shared", me.Id, types.SearchQuery{Text: "apple"},
+				[]string{"apple chores", "apple pie", "apple project", "apple shared", "apple step"}},
+			{"own and shared notes", me.Id, types.SearchQuery{Text: "apple", Type: "notes"},
+				[]string{"apple pie", "apple shared"}},
+			{"own and shared tasks", me.Id, types.SearchQuery{Text: "apple", Type: "tasks"},
+				[]string{"apple chores", "apple project", "apple step"}},
+			{"trashed", me.Id, types.SearchQuery{Text: "trashed"}, []string{}},
+			{"not shared", me.Id, types.SearchQuery{Text: "secret private"}, []string{}},
+			{"owner of the shared items", other.Id, types.SearchQuery{Text: "apple"},
+				[]string{"apple private", "apple project", "apple secret", "apple shared", "apple step"}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				query := tt.query
+				if got := searchTitles(t, store, tt.user, &query); !slices.Equal(got, tt.want) {
+					t.Errorf("searching %q found %v, want %v", tt.query.Text, got, tt.want)
+				}
+			})
+		}
+
+		t.Run("revoked", func(t *testing.T) {
+			if _, err := store.Shares.Delete(ctx, types.ShareTask, project.Id, me.Id); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"apple chores", "apple pie", "apple shared"}
+			if got := searchTitles(t, store, me.Id, &types.SearchQuery{Text: "apple"}); !slices.Equal(got, want) {
+				t.Errorf("after the share was revoked searching found %v, want %v", got, want)
+			}
+		})
+	})
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoSharesStore struct {
+	collection *mongo.Collection
+}
+
+// Upsert shares an item with a user, or changes the permission when it is
+// already shared with them, and returns the share
+func (s *MongoSharesStore) Upsert(ctx context.Context, share *types.Share) (*types.Share, error) {
+	now, actor := timestamp(), actorFrom(ctx)
+	filter := bson.M{"item_type": share.ItemType, "item_id": share.ItemID, "user_id": share.UserID}
+	update := bson.M{
+		"$set":         bson.M{"permission": share.Permission, "updated_at": now, "updated_by": actor},
+		"$setOnInsert": bson.M{"created_at": now, "created_by": actor},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved types.Share
+	if err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// Get retrieves the share of an item with a user
+func (s *MongoSharesStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error) {
+	var share types.Share
+	err := s.collection.FindOne(ctx, bson.M{"item_type": itemType, "item_id": itemId, "user_id": userId}).Decode(&share)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListByItem retrieves everyone an item is shared with, oldest share first
+func (s *MongoSharesStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Share, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	return s.find(ctx, bson.M{"item_type": itemType, "item_id": itemId}, opts)
+}
+
+// ListByUser retrieves the items shared with a user, newest share first
+func (s *MongoSharesStore) ListByUser(ctx context.Context, userId primitive.ObjectID) ([]*types.Share, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return s.find(ctx, bson.M{"user_id": userId}, opts)
+}
+
+// Delete stops sharing an item with a user and returns the removed share
+func (s *MongoSharesStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error) {
+	var share types.Share
+	err := s.collection.FindOneAndDelete(ctx, bson.M{"item_type": itemType, "item_id": itemId, "user_id": userId}).Decode(&share)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// DeleteByItem stops sharing an item with anyone
+func (s *MongoSharesStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": itemId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByUser removes every share granted to a user
+func (s *MongoSharesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the shares of notes and tasks that no longer exist
+func (s *MongoSharesStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	var deleted int64
+	for itemType, collection := range map[string]string{types.ShareNote: "note", types.ShareTask: "task"} {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"item_type": itemType}}},
+			{{Key: "$group", Value: bson.M{"_id": "$item_id"}}},
+			{{Key: "$lookup", Value: bson.M{"from": collection, "localField": "_id", "foreignField": "_id", "as": "item"}}},
+			{{Key: "$match", Value: bson.M{"item": bson.M{"$size": 0}}}},
+		}
+		cursor, err := s.collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return deleted, err
+		}
+		var orphans []struct {
+			ItemId primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &orphans); err != nil {
+			return deleted, err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		itemIds := make([]primitive.ObjectID, len(orphans))
+		for i, orphan := range orphans {
+			itemIds[i] = orphan.ItemId
+		}
+		result, err := s.collection.DeleteMany(ctx, bson.M{"item_type": itemType, "item_id": bson.M{"$in": itemIds}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
+
+func (s *MongoSharesStore) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*types.Share, error) {
+	cursor, err := s.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	shares := []*types.Share{}
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
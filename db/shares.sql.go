@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLSharesStore struct {
+	db *sqlDB
+}
+
+// Upsert shares an item with a user, or changes the permission when it is
+// already shared with them, and returns the share
+func (s *SQLSharesStore) Upsert(ctx context.Context, share *types.Share) (*types.Share, error) {
+	now, actor := timestamp(), actorFrom(ctx)
+	existing, err := s.Get(ctx, share.ItemType, share.ItemID, share.UserID)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		existing.Permission = share.Permission
+		existing.UpdatedAt = now
+		existing.UpdatedBy = actor
+		data, err := marshalDoc(existing)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.db.exec(ctx, "UPDATE shares SET data = ? WHERE id = ?", data, existing.Id.Hex()); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	newShare := types.Share{
+		Id:         primitive.NewObjectID(),
+		ItemType:   share.ItemType,
+		ItemID:     share.ItemID,
+		UserID:     share.UserID,
+		Permission: share.Permission,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		CreatedBy:  actor,
+		UpdatedBy:  actor,
+	}
+	data, err := marshalDoc(newShare)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.exec(ctx, "INSERT INTO shares (id, item_type, item_id, user_id, created_at, data) VALUES (?, ?, ?, ?, ?, ?)",
+		newShare.Id.Hex(), newShare.ItemType, newShare.ItemID.Hex(), newShare.UserID.Hex(), sqlTime(newShare.CreatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newShare, nil
+}
+
+// Get retrieves the share of an item with a user
+func (s *SQLSharesStore) Get(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error) {
+	var share types.Share
+	err := s.db.findDoc(ctx, &share, "SELECT data FROM shares WHERE item_type = ? AND item_id = ? AND user_id = ?",
+		itemType, itemId.Hex(), userId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListByItem retrieves everyone an item is shared with, oldest share first
+func (s *SQLSharesStore) ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Share, error) {
+	return listDocs[types.Share](ctx, s.db, "SELECT data FROM shares WHERE item_type = ? AND item_id = ? ORDER BY created_at, id",
+		itemType, itemId.Hex())
+}
+
+// ListByUser retrieves the items shared with a user, newest share first
+func (s *SQLSharesStore) ListByUser(ctx context.Context, userId primitive.ObjectID) ([]*types.Share, error) {
+	return listDocs[types.Share](ctx, s.db, "SELECT data FROM shares WHERE user_id = ? ORDER BY created_at DESC, id DESC", userId.Hex())
+}
+
+// Delete stops sharing an item with a user and returns the removed share
+func (s *SQLSharesStore) Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error) {
+	share, err := s.Get(ctx, itemType, itemId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.exec(ctx, "DELETE FROM shares WHERE id = ?", share.Id.Hex()); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// DeleteByItem stops sharing an item with anyone
+func (s *SQLSharesStore) DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error) {
+	result, err := s.db.exec(ctx, "DELETE FROM shares WHERE item_type = ? AND item_id = ?", itemType, itemId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByUser removes every share granted to a user
+func (s *SQLSharesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := s.db.exec(ctx, "DELETE FROM shares WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the shares of notes and tasks that no longer exist
+func (s *SQLSharesStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := s.db.exec(ctx, `DELETE FROM shares WHERE
+		(item_type = ? AND item_id NOT IN (SELECT id FROM notes)) OR
+		(item_type = ? AND item_id NOT IN (SELECT id FROM tasks))`, types.ShareNote, types.ShareTask)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
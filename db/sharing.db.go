@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListShares retrieves everyone an item is shared with, along with the user
+// each share was granted to. Shares of users in the trash are left out.
+func (s *Store) ListShares(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Share, error) {
+	shares, err := s.Shares.ListByItem(ctx, itemType, itemId)
+	if err != nil {
+		return nil, err
+	}
+	visible := []*types.Share{}
+	for _, share := range shares {
+		user, err := s.User.Get(ctx, share.UserID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		share.User = user
+		visible = append(visible, share)
+	}
+	return visible, nil
+}
+
+// SharedWith retrieves the notes and tasks other users shared with userId,
+// newest share first. itemType limits the result to types.ShareNote or
+// types.ShareTask; an empty itemType returns both. Items in the trash are
+// left out until they are restored.
+func (s *Store) SharedWith(ctx context.Context, userId primitive.ObjectID, itemType string) ([]*types.SharedItem, error) {
+	shares, err := s.Shares.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var noteIds, taskIds []primitive.ObjectID
+	for _, share := range shares {
+		switch {
+		case share.ItemType == types.ShareNote && itemType != types.ShareTask:
+			noteIds = append(noteIds, share.ItemID)
+		case share.ItemType == types.ShareTask && itemType != types.ShareNote:
+			taskIds = append(taskIds, share.ItemID)
+		}
+	}
+
+	notes := map[primitive.ObjectID]*types.Notes{}
+	if len(noteIds) > 0 {
+		found, err := s.Notes.GetMany(ctx, noteIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range found {
+			notes[note.Id] = note
+		}
+	}
+	tasks := map[primitive.ObjectID]*types.Tasks{}
+	if len(taskIds) > 0 {
+		found, err := s.Tasks.GetMany(ctx, taskIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range found {
+			tasks[task.Id] = task
+		}
+	}
+
+	items := []*types.SharedItem{}
+	for _, share := range shares {
+		item := &types.SharedItem{Type: share.ItemType, Permission: share.Permission, SharedAt: share.CreatedAt}
+		switch share.ItemType {
+		case types.ShareNote:
+			item.Note = notes[share.ItemID]
+		case types.ShareTask:
+			item.Task = tasks[share.ItemID]
+		}
+		if item.Note == nil && item.Task == nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
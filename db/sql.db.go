@@ -0,0 +1,916 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-auth/storage"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+)
+
+// defaultSQLitePath is used when DB_DRIVER=sqlite and DATABASE_URL is empty.
+const defaultSQLitePath = "go-lang-auth-db.sqlite"
+
+// sqlDialect hides the differences between the supported SQL engines.
+type sqlDialect string
+
+const (
+	dialectSQLite   sqlDialect = "sqlite"
+	dialectPostgres sqlDialect = "postgres"
+)
+
+// rebind rewrites ? placeholders into the $n form expected by PostgreSQL.
+func (d sqlDialect) rebind(query string) string {
+	if d != dialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// forUpdate locks the rows a SELECT reads until the end of the transaction.
+// SQLite needs no lock since its single connection runs one transaction at
+// a time.
+func (d sqlDialect) forUpdate() string {
+	if d == dialectPostgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// sqlConn is satisfied by both *sql.DB and *sql.Tx.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqlDB wraps the connection pool together with its dialect.
+// Every table keeps the full record as JSON in a data column, next to the
+// columns needed for lookups and indexes, so records keep the same shape as
+// the Mongo documents.
+type sqlDB struct {
+	pool    *sql.DB
+	dialect sqlDialect
+}
+
+// sqlTxKey is the context key under which withTransaction stores the open *sql.Tx.
+type sqlTxKey struct{}
+
+// conn returns the transaction carried by ctx, or the pool outside of one.
+func (s *sqlDB) conn(ctx context.Context) sqlConn {
+	if tx, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.pool
+}
+
+func (s *sqlDB) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.conn(ctx).ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *sqlDB) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.conn(ctx).QueryContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *sqlDB) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.conn(ctx).QueryRowContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// withTransaction runs fn inside a transaction, committing when it returns nil.
+// Store calls made with the context passed to fn join the transaction.
+func (s *sqlDB) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		// Already inside a transaction
+		return fn(ctx)
+	}
+	tx, err := s.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, sqlTxKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockUser locks the row of a user until the end of the transaction, so that
+// transactions reading every record of the user run one after the other.
+func (s *sqlDB) lockUser(ctx context.Context, id primitive.ObjectID) error {
+	var locked string
+	err := s.queryRow(ctx, "SELECT id FROM users WHERE id = ?"+s.dialect.forUpdate(), id.Hex()).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// findDoc decodes the data column of the first row returned by query into dst.
+func (s *sqlDB) findDoc(ctx context.Context, dst any, query string, args ...any) error {
+	var data string
+	err := s.queryRow(ctx, query, args...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), dst)
+}
+
+// getDoc loads the record with the given id from table into dst.
+func (s *sqlDB) getDoc(ctx context.Context, table string, id string, dst any) error {
+	return s.findDoc(ctx, dst, "SELECT data FROM "+table+" WHERE id = ?", id)
+}
+
+// listDocs decodes the data column of every row returned by query.
+func listDocs[T any](ctx context.Context, s *sqlDB, query string, args ...any) ([]*T, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []*T{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var doc T
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+	return docs, rows.Err()
+}
+
+// placeholders returns n comma separated ? placeholders for an IN list.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// hexIds converts ObjectIDs to the text stored in id columns.
+func hexIds(ids []primitive.ObjectID) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id.Hex()
+	}
+	return args
+}
+
+// countByUsers groups the live rows of table by owner.
+func (s *sqlDB) countByUsers(ctx context.Context, table string, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	counts := map[primitive.ObjectID]int64{}
+	if len(userIds) == 0 {
+		return counts, nil
+	}
+	rows, err := s.query(ctx, "SELECT user_id, COUNT(*) FROM "+table+" WHERE user_id IN ("+placeholders(len(userIds))+") AND deleted_at IS NULL GROUP BY user_id", hexIds(userIds)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		var count int64
+		if err := rows.Scan(&userId, &count); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(userId)
+		if err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+// sqlTimeFormat has a fixed width so timestamp columns sort and compare as text.
+const sqlTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// sqlTime formats t for a timestamp column.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+// nullableTime formats an optional timestamp, returning nil for SQL NULL.
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqlTime(*t)
+}
+
+// marshalDoc encodes a record for the data column.
+func marshalDoc(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sqlMigration is one versioned step of the SQL schema. Up statements run
+// first, followed by the optional Backfill, all inside one transaction.
+type sqlMigration struct {
+	Version  int
+	Name     string
+	Up       []string
+	Backfill func(ctx context.Context, s *sqlDB) error
+}
+
+// sqlMigrations must only ever be appended to; applied versions are recorded
+// in schema_migrations and skipped on the next start.
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "create users, notes and tasks",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id TEXT PRIMARY KEY,
+				email TEXT NOT NULL UNIQUE,
+				data TEXT NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS notes (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS notes_user_id_idx ON notes (user_id)`,
+			`CREATE TABLE IF NOT EXISTS tasks (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_idx ON tasks (user_id)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add deleted_at for the trash",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN deleted_at TEXT`,
+			`ALTER TABLE notes ADD COLUMN deleted_at TEXT`,
+			`ALTER TABLE tasks ADD COLUMN deleted_at TEXT`,
+			`CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at)`,
+			`CREATE INDEX IF NOT EXISTS notes_deleted_at_idx ON notes (deleted_at)`,
+			`CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add created_at and updated_at",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN created_at TEXT`,
+			`ALTER TABLE users ADD COLUMN updated_at TEXT`,
+			`ALTER TABLE notes ADD COLUMN created_at TEXT`,
+			`ALTER TABLE notes ADD COLUMN updated_at TEXT`,
+			`ALTER TABLE tasks ADD COLUMN created_at TEXT`,
+			`ALTER TABLE tasks ADD COLUMN updated_at TEXT`,
+			`CREATE INDEX IF NOT EXISTS notes_created_at_idx ON notes (created_at)`,
+			`CREATE INDEX IF NOT EXISTS tasks_created_at_idx ON tasks (created_at)`,
+		},
+		Backfill: backfillSQLTimestamps,
+	},
+	{
+		Version: 4,
+		Name:    "add created_at indexes for paginated lists",
+		Up: []string{
+			`CREATE INDEX IF NOT EXISTS notes_user_id_created_at_idx ON notes (user_id, created_at, id)`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_created_at_idx ON tasks (user_id, created_at, id)`,
+			`CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id)`,
+		},
+	},
+	{
+		Version:  5,
+		Name:     "add full-text search indexes",
+		Backfill: createSQLSearchIndexes,
+	},
+	{
+		Version: 6,
+		Name:    "create tags",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS tags (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				key TEXT NOT NULL,
+				created_at TEXT,
+				updated_at TEXT,
+				data TEXT NOT NULL,
+				UNIQUE (user_id, key)
+			)`,
+		},
+		Backfill: backfillSQLTags,
+	},
+	{
+		Version: 7,
+		Name:    "create note revisions",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS note_revisions (
+				id TEXT PRIMARY KEY,
+				note_id TEXT NOT NULL,
+				version INTEGER NOT NULL,
+				created_at TEXT,
+				data TEXT NOT NULL,
+				UNIQUE (note_id, version)
+			)`,
+		},
+		Backfill: backfillSQLNoteVersions,
+	},
+	{
+		Version: 8,
+		Name:    "create shares",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS shares (
+				id TEXT PRIMARY KEY,
+				item_type TEXT NOT NULL,
+				item_id TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				created_at TEXT,
+				data TEXT NOT NULL,
+				UNIQUE (item_type, item_id, user_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS shares_user_id_idx ON shares (user_id, created_at)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create note links",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS note_links (
+				id TEXT PRIMARY KEY,
+				note_id TEXT NOT NULL,
+				token TEXT NOT NULL UNIQUE,
+				created_at TEXT,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS note_links_note_id_idx ON note_links (note_id)`,
+		},
+	},
+	{
+		Version:  10,
+		Name:     "mark existing notes as plain text",
+		Backfill: backfillSQLNoteFormats,
+	},
+	{
+		Version: 11,
+		Name:    "create attachments",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS attachments (
+				id TEXT PRIMARY KEY,
+				item_type TEXT NOT NULL,
+				item_id TEXT NOT NULL,
+				created_at TEXT,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS attachments_item_idx ON attachments (item_type, item_id, created_at)`,
+		},
+	},
+	{
+		Version:  12,
+		Name:     "store the current status of tasks",
+		Backfill: backfillSQLTaskStatuses,
+	},
+	{
+		Version: 13,
+		Name:    "create workflows",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS workflows (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL UNIQUE,
+				updated_at TEXT,
+				data TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:  14,
+		Name:     "backfill previous status of task status history",
+		Backfill: backfillSQLPreviousStatuses,
+	},
+	{
+		Version: 15,
+		Name:    "add task priorities and due dates",
+		Up: []string{
+			`ALTER TABLE tasks ADD COLUMN priority INTEGER`,
+			`ALTER TABLE tasks ADD COLUMN due_sort TEXT`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_priority_idx ON tasks (user_id, priority, id)`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_due_sort_idx ON tasks (user_id, due_sort, id)`,
+		},
+		Backfill: backfillSQLTaskDueDates,
+	},
+	{
+		Version: 16,
+		Name:    "add task assignees and notifications",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS notifications (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				item_type TEXT NOT NULL,
+				item_id TEXT NOT NULL,
+				created_at TEXT,
+				read_at TEXT,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS notifications_item_idx ON notifications (item_type, item_id)`,
+		},
+		Backfill: backfillSQLTaskAssignees,
+	},
+	{
+		Version:  17,
+		Name:     "add subtasks and checklists",
+		Backfill: backfillSQLSubtasks,
+	},
+	{
+		Version:  18,
+		Name:     "add task dependencies",
+		Backfill: backfillSQLTaskBlockers,
+	},
+	{
+		Version: 19,
+		Name:    "add recurring tasks",
+		Up: []string{
+			`ALTER TABLE tasks ADD COLUMN recur_at TEXT`,
+			`CREATE INDEX IF NOT EXISTS tasks_recur_at_idx ON tasks (recur_at)`,
+		},
+	},
+	{
+		Version: 20,
+		Name:    "add task board ranks",
+		Up: []string{
+			`ALTER TABLE tasks ADD COLUMN rank TEXT`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_rank_idx ON tasks (user_id, rank)`,
+		},
+		Backfill: backfillSQLTaskRanks,
+	},
+	{
+		Version: 21,
+		Name:    "add comments",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS comments (
+				id TEXT PRIMARY KEY,
+				item_type TEXT NOT NULL,
+				item_id TEXT NOT NULL,
+				parent_id TEXT,
+				user_id TEXT NOT NULL,
+				created_at TEXT,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS comments_item_idx ON comments (item_type, item_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments (parent_id)`,
+			`CREATE INDEX IF NOT EXISTS comments_user_id_idx ON comments (user_id)`,
+		},
+	},
+	{
+		Version: 22,
+		Name:    "add time tracking",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS time_entries (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				started_at TEXT NOT NULL,
+				ended_at TEXT,
+				duration INTEGER NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS time_entries_task_id_idx ON time_entries (task_id, started_at)`,
+			`CREATE INDEX IF NOT EXISTS time_entries_user_id_idx ON time_entries (user_id, started_at)`,
+			// A user has at most one running timer
+			`CREATE UNIQUE INDEX IF NOT EXISTS time_entries_running_idx ON time_entries (user_id) WHERE ended_at IS NULL`,
+		},
+	},
+	{
+		Version: 23,
+		Name:    "add owner indexes covering live counts",
+		Up: []string{
+			// Counting live items per owner reads only the index, not the
+			// rows that follow the data column
+			`CREATE INDEX IF NOT EXISTS notes_user_id_deleted_at_idx ON notes (user_id, deleted_at)`,
+			`CREATE INDEX IF NOT EXISTS tasks_user_id_deleted_at_idx ON tasks (user_id, deleted_at)`,
+		},
+	},
+	{
+		Version:  24,
+		Name:     "normalize user emails",
+		Backfill: normalizeSQLEmails,
+	},
+}
+
+// normalizeSQLEmails stores every user email in its normalized form, so the
+// unique email column also tells apart addresses by case.
+func normalizeSQLEmails(ctx context.Context, s *sqlDB) error {
+	rows, err := s.query(ctx, "SELECT id, email FROM users")
+	if err != nil {
+		return err
+	}
+	var users []userEmail
+	for rows.Next() {
+		var user userEmail
+		if err := rows.Scan(&user.Id, &user.Email); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	changed, err := normalizedEmails(users)
+	if err != nil {
+		return err
+	}
+	for _, user := range changed {
+		docs, err := s.rawDocs(ctx, "SELECT id, data FROM users WHERE id = ?", user.Id)
+		if err != nil {
+			return err
+		}
+		doc := docs[user.Id]
+		doc["email"] = user.Email
+		data, err := marshalDoc(doc)
+		if err != nil {
+			return err
+		}
+		if _, err := s.exec(ctx, "UPDATE users SET email = ?, data = ? WHERE id = ?", user.Email, data, user.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSQLNoteVersions numbers existing notes as their first version.
+func backfillSQLNoteVersions(ctx context.Context, s *sqlDB) error {
+	query := `UPDATE notes SET data = json_set(data, '$.version', 1) WHERE json_type(data, '$.version') IS NULL`
+	if s.dialect == dialectPostgres {
+		query = `UPDATE notes SET data = jsonb_set(data::jsonb, '{version}', '1'::jsonb)::text WHERE data::jsonb -> 'version' IS NULL`
+	}
+	_, err := s.exec(ctx, query)
+	return err
+}
+
+// backfillSQLNoteFormats marks existing notes as plain text.
+func backfillSQLNoteFormats(ctx context.Context, s *sqlDB) error {
+	query := `UPDATE notes SET data = json_set(data, '$.format', 'plain') WHERE json_type(data, '$.format') IS NULL`
+	if s.dialect == dialectPostgres {
+		query = `UPDATE notes SET data = jsonb_set(data::jsonb, '{format}', '"plain"'::jsonb)::text WHERE data::jsonb -> 'format' IS NULL`
+	}
+	_, err := s.exec(ctx, query)
+	return err
+}
+
+// backfillSQLTaskStatuses copies the last status history entry of existing
+// tasks to current_status.
+func backfillSQLTaskStatuses(ctx context.Context, s *sqlDB) error {
+	last := "COALESCE(" + s.dialect.jsonText("status_history", -1, "status") + ", '')"
+	query := `UPDATE tasks SET data = json_set(data, '$.current_status', ` + last + `) WHERE json_type(data, '$.current_status') IS NULL`
+	if s.dialect == dialectPostgres {
+		query = `UPDATE tasks SET data = jsonb_set(data::jsonb, '{current_status}', to_jsonb(` + last + `))::text WHERE data::jsonb -> 'current_status' IS NULL`
+	}
+	_, err := s.exec(ctx, query)
+	return err
+}
+
+// backfillSQLTaskDueDates fills the priority and due date columns of
+// existing tasks, which have neither.
+func backfillSQLTaskDueDates(ctx context.Context, s *sqlDB) error {
+	_, err := s.exec(ctx, "UPDATE tasks SET priority = 0, due_sort = ? WHERE due_sort IS NULL", sqlTime(types.NoDueDate))
+	return err
+}
+
+// backfillSQLTaskAssignees gives existing tasks an empty assignee list.
+func backfillSQLTaskAssignees(ctx context.Context, s *sqlDB) error {
+	return s.backfillEmptyList(ctx, "tasks", "assignees")
+}
+
+// backfillSQLSubtasks gives existing tasks no ancestors and an empty checklist.
+func backfillSQLSubtasks(ctx context.Context, s *sqlDB) error {
+	if err := s.backfillEmptyList(ctx, "tasks", "ancestors"); err != nil {
+		return err
+	}
+	return s.backfillEmptyList(ctx, "tasks", "checklist")
+}
+
+// backfillSQLTaskBlockers gives existing tasks no blockers.
+func backfillSQLTaskBlockers(ctx context.Context, s *sqlDB) error {
+	return s.backfillEmptyList(ctx, "tasks", "blocked_by")
+}
+
+// backfillSQLTaskRanks ranks the existing tasks of each user on their board
+// in the order they were created.
+func backfillSQLTaskRanks(ctx context.Context, s *sqlDB) error {
+	rows, err := s.query(ctx, "SELECT id, user_id FROM tasks WHERE rank IS NULL ORDER BY user_id, created_at, id")
+	if err != nil {
+		return err
+	}
+	var ids, userIds []string
+	for rows.Next() {
+		var id, userId string
+		if err := rows.Scan(&id, &userId); err != nil {
+			rows.Close()
+			return err
+		}
+		ids, userIds = append(ids, id), append(userIds, userId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	docs, err := s.rawDocs(ctx, "SELECT id, data FROM tasks WHERE rank IS NULL")
+	if err != nil {
+		return err
+	}
+
+	ranks := map[string]string{}
+	for i, id := range ids {
+		rank, err := utils.RankBetween(ranks[userIds[i]], "")
+		if err != nil {
+			return err
+		}
+		ranks[userIds[i]] = rank
+		doc := docs[id]
+		doc["rank"] = rank
+		data, err := marshalDoc(doc)
+		if err != nil {
+			return err
+		}
+		if _, err := s.exec(ctx, "UPDATE tasks SET rank = ?, data = ? WHERE id = ?", rank, data, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillEmptyList sets key to an empty array on the rows of table that lack it.
+func (s *sqlDB) backfillEmptyList(ctx context.Context, table string, key string) error {
+	query := `UPDATE ` + table + ` SET data = json_set(data, '$.` + key + `', json('[]')) WHERE json_type(data, '$.` + key + `') IS NULL`
+	if s.dialect == dialectPostgres {
+		query = `UPDATE ` + table + ` SET data = jsonb_set(data::jsonb, '{` + key + `}', '[]'::jsonb)::text WHERE data::jsonb -> '` + key + `' IS NULL`
+	}
+	_, err := s.exec(ctx, query)
+	return err
+}
+
+// backfillSQLPreviousStatuses records on every status history entry after
+// the first the status of the entry before it.
+func backfillSQLPreviousStatuses(ctx context.Context, s *sqlDB) error {
+	docs, err := s.rawDocs(ctx, "SELECT id, data FROM tasks")
+	if err != nil {
+		return err
+	}
+	for id, doc := range docs {
+		history, _ := doc["status_history"].([]any)
+		changed := false
+		for i := 1; i < len(history); i++ {
+			entry, ok := history[i].(map[string]any)
+			previous, _ := history[i-1].(map[string]any)
+			if ok && previous != nil && entry["previous_status"] == nil {
+				if status, _ := previous["status"].(string); status != "" {
+					entry["previous_status"] = status
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			continue
+		}
+		data, err := marshalDoc(doc)
+		if err != nil {
+			return err
+		}
+		if _, err := s.exec(ctx, "UPDATE tasks SET data = ? WHERE id = ?", data, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSQLTags gives existing notes and tasks an empty tag list.
+func backfillSQLTags(ctx context.Context, s *sqlDB) error {
+	set := `json_set(data, '$.tags', json('[]'))`
+	missing := `json_type(data, '$.tags') IS NULL`
+	if s.dialect == dialectPostgres {
+		set = `jsonb_set(data::jsonb, '{tags}', '[]'::jsonb)::text`
+		missing = `data::jsonb -> 'tags' IS NULL`
+	}
+	for _, table := range []string{"notes", "tasks"} {
+		if _, err := s.exec(ctx, "UPDATE "+table+" SET data = "+set+" WHERE "+missing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSQLTimestamps derives created_at and updated_at of existing rows
+// from the creation time embedded in their ObjectID, and stamps status
+// history entries that have no change time with the same value.
+func backfillSQLTimestamps(ctx context.Context, s *sqlDB) error {
+	for _, table := range []string{"users", "notes", "tasks"} {
+		docs, err := s.rawDocs(ctx, "SELECT id, data FROM "+table+" WHERE created_at IS NULL")
+		if err != nil {
+			return err
+		}
+		for id, doc := range docs {
+			objectId, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return err
+			}
+			createdAt := objectId.Timestamp().UTC()
+			doc["created_at"] = createdAt
+			doc["updated_at"] = createdAt
+			if history, ok := doc["status_history"].([]any); ok {
+				for _, entry := range history {
+					status, ok := entry.(map[string]any)
+					if ok && (status["changed_at"] == nil || status["changed_at"] == "0001-01-01T00:00:00Z") {
+						status["changed_at"] = createdAt
+					}
+				}
+			}
+			data, err := marshalDoc(doc)
+			if err != nil {
+				return err
+			}
+			_, err = s.exec(ctx, "UPDATE "+table+" SET created_at = ?, updated_at = ?, data = ? WHERE id = ?",
+				sqlTime(createdAt), sqlTime(createdAt), data, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rawDocs reads the id and data columns selected by query, decoding the data
+// without a Go type so backfills only touch the fields they fill in.
+func (s *sqlDB) rawDocs(ctx context.Context, query string, args ...any) (map[string]map[string]any, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := map[string]map[string]any{}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, err
+		}
+		docs[id] = doc
+	}
+	return docs, rows.Err()
+}
+
+func (s *sqlDB) migrate(ctx context.Context) error {
+	_, err := s.exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sqlMigrations {
+		var applied int
+		err := s.queryRow(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+		err = s.withTransaction(ctx, func(ctx context.Context) error {
+			for _, stmt := range m.Up {
+				if _, err := s.exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			if m.Backfill != nil {
+				if err := m.Backfill(ctx, s); err != nil {
+					return err
+				}
+			}
+			_, err := s.exec(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return migrationError(m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqlDB) appliedMigrations(ctx context.Context) ([]MigrationRecord, error) {
+	rows, err := s.query(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []MigrationRecord{}
+	for rows.Next() {
+		var record MigrationRecord
+		var appliedAt string
+		if err := rows.Scan(&record.Version, &record.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+		record.AppliedAt, _ = time.Parse(time.RFC3339, appliedAt)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// isUniqueViolation reports whether err was caused by a UNIQUE constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// NewSQLStore opens a SQLite or PostgreSQL database and returns a Store
+// backed by it. Call Migrate before use to create or upgrade the schema.
+func NewSQLStore(driver string, dsn string) (*Store, error) {
+	var sqlDriver string
+	switch sqlDialect(driver) {
+	case dialectSQLite:
+		sqlDriver = "sqlite"
+		if dsn == "" {
+			dsn = defaultSQLitePath
+		}
+	case dialectPostgres:
+		sqlDriver = "pgx"
+		if dsn == "" {
+			return nil, fmt.Errorf("DATABASE_URL not set")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+
+	pool, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if sqlDialect(driver) == dialectSQLite {
+		// SQLite only allows a single writer at a time
+		pool.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := pool.PingContext(ctx); err != nil {
+		return nil, err
+	}
+
+	s := &sqlDB{pool: pool, dialect: sqlDialect(driver)}
+	return &Store{
+		User:              &SQLUserStore{db: s},
+		Notes:             &SQLNotesStore{db: s},
+		Tasks:             &SQLTasksStore{db: s},
+		Tags:              &SQLTagsStore{db: s},
+		NoteRevisions:     &SQLNoteRevisionsStore{db: s},
+		Shares:            &SQLSharesStore{db: s},
+		NoteLinks:         &SQLNoteLinksStore{db: s},
+		Attachments:       &SQLAttachmentsStore{db: s},
+		Workflows:         &SQLWorkflowsStore{db: s},
+		Notifications:     &SQLNotificationsStore{db: s},
+		Comments:          &SQLCommentsStore{db: s},
+		TimeEntries:       &SQLTimeEntriesStore{db: s},
+		Blobs:             storage.NewLocal(storage.DefaultLocalDir),
+		NoteRevisionLimit: DefaultNoteRevisionLimit,
+		AttachmentMaxSize: DefaultAttachmentMaxSize,
+		AttachmentTypes:   DefaultAttachmentTypes,
+		backend:           s,
+	}, nil
+}
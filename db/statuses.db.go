@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultWorkflow returns the workflow of users who have not set their own:
+// todo → in_progress → review → done, with tasks able to go back one step and
+// done tasks able to be reopened.
+func DefaultWorkflow() *types.Workflow {
+	return &types.Workflow{
+		Initial:  "todo",
+		Statuses: []string{"todo", "in_progress", "review", "done"},
+		Transitions: map[string][]string{
+			"todo":        {"in_progress"},
+			"in_progress": {"todo", "review"},
+			"review":      {"in_progress", "done"},
+			"done":        {"in_progress"},
+		},
+		Done:    []string{"done"},
+		Default: true,
+	}
+}
+
+// TaskWorkflow returns the workflow that applies to the tasks owned by a
+// user: their own one, or DefaultWorkflow.
+func (s *Store) TaskWorkflow(ctx context.Context, userId primitive.ObjectID) (*types.Workflow, error) {
+	workflow, err := s.Workflows.Get(ctx, userId)
+	if err == ErrNotFound {
+		workflow = DefaultWorkflow()
+		workflow.UserID = userId
+		return workflow, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
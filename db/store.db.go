@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserStore is implemented by every backend that can persist users.
+//
+// Delete only moves a user to the trash; FindByEmail, List, Get and Update
+// ignore trashed users until they are restored. Purge removes them for good.
+type UserStore interface {
+	FindByEmail(email string) (*types.User, error)
+	List(ctx context.Context) ([]*types.UserResponse, error)
+	ListPage(ctx context.Context, query *types.ListQuery) ([]*types.UserResponse, *types.Pagination, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error)
+	Create(ctx context.Context, user *types.UserCreate) (*types.UserResponse, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error)
+	Update(ctx context.Context, id primitive.ObjectID, updateData *types.UserUpdate) (*types.UserResponse, error)
+	ListTrash(ctx context.Context) ([]*types.UserResponse, error)
+	ListDeletedBefore(ctx context.Context, before time.Time) ([]*types.UserResponse, error)
+	GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error)
+	Restore(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error)
+	Purge(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error)
+}
+
+// NotesStore is implemented by every backend that can persist notes.
+//
+// Delete only moves a note to the trash; List, Get and Update ignore trashed
+// notes until they are restored. Purge removes them for good.
+type NotesStore interface {
+	List(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error)
+	ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Notes, *types.Pagination, error)
+	ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Notes, error)
+	CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error)
+	ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error)
+	CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*types.Notes, error)
+	GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Notes, error)
+	Create(ctx context.Context, note *types.NotesCreate) (*types.Notes, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*types.Notes, error)
+	Update(ctx context.Context, id primitive.ObjectID, updatedData *types.NotesUpdate) (*types.Notes, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error)
+	ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Notes, error)
+	GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Notes, error)
+	Restore(ctx context.Context, id primitive.ObjectID) (*types.Notes, error)
+	Purge(ctx context.Context, id primitive.ObjectID) (*types.Notes, error)
+	TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error)
+	RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error)
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TasksStore is implemented by every backend that can persist tasks.
+//
+// Delete only moves a task to the trash; List, Get and Update ignore trashed
+// tasks until they are restored. Create ranks tasks after every other task of
+// their owner unless they come with a rank. Purge removes them for good. Delete, Restore
+// and Purge carry the subtasks of the task along, and Move its whole subtree.
+// UpdateChecklist hands change the current checklist of the task and stores
+// the one it returns, unless it returns an error; change may run more than
+// once when the task changes meanwhile.
+// Update appends its status only to a task still in the status it moves
+// from, and changes the recurrence only of a task whose next occurrence does
+// not exist yet; otherwise it returns ErrConcurrentUpdate.
+// Unassign returns ErrNotFound when the task is not assigned to the user,
+// Unblock when the task is not blocked by the other one, and LinkOccurrence
+// when the task does not recur or its next occurrence is already linked.
+type TasksStore interface {
+	List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error)
+	ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Tasks, *types.Pagination, error)
+	ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Tasks, error)
+	CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error)
+	ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error)
+	CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error)
+	GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tasks, error)
+	Create(ctx context.Context, task *types.TasksCreate) (*types.Tasks, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error)
+	Update(ctx context.Context, id primitive.ObjectID, updatedData *types.TasksUpdate) (*types.Tasks, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error)
+	ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error)
+	GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error)
+	Restore(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error)
+	Purge(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error)
+	TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error)
+	RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error)
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	Assign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error)
+	Unassign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error)
+	UnassignUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	ListDescendants(ctx context.Context, id primitive.ObjectID) ([]*types.Tasks, error)
+	Move(ctx context.Context, id primitive.ObjectID, ancestors []primitive.ObjectID) (*types.Tasks, error)
+	UpdateChecklist(ctx context.Context, id primitive.ObjectID, change func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error)) (*types.Tasks, error)
+	AppendStatus(ctx context.Context, id primitive.ObjectID, status *types.Status) (*types.Tasks, error)
+	Block(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error)
+	Unblock(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error)
+	UnblockAll(ctx context.Context, blockerId primitive.ObjectID) (int64, error)
+	DeleteDanglingBlockers(ctx context.Context) (int64, error)
+	ListBlocked(ctx context.Context, blockerId primitive.ObjectID) ([]*types.Tasks, error)
+	LinkOccurrence(ctx context.Context, id primitive.ObjectID, nextId primitive.ObjectID) (*types.Tasks, error)
+	ListRecurring(ctx context.Context, before time.Time, limit int) ([]*types.Tasks, error)
+	LastRank(ctx context.Context, userId primitive.ObjectID) (string, error)
+	SetRank(ctx context.Context, id primitive.ObjectID, rank string, status *types.Status) (*types.Tasks, error)
+}
+
+// TagsStore is implemented by every backend that can persist tags.
+//
+// Tag names are unique per user regardless of case; Create and Update return
+// ErrDuplicateTag when the name is taken.
+type TagsStore interface {
+	List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tag, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*types.Tag, error)
+	GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tag, error)
+	FindByKey(ctx context.Context, userId primitive.ObjectID, key string) (*types.Tag, error)
+	Create(ctx context.Context, tag *types.TagCreate) (*types.Tag, error)
+	Update(ctx context.Context, id primitive.ObjectID, updateData *types.TagUpdate) (*types.Tag, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*types.Tag, error)
+	SetOwner(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) error
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+}
+
+// NoteRevisionsStore is implemented by every backend that can persist the
+// earlier versions of notes.
+//
+// A note has at most one revision per version; Create returns
+// ErrNoteVersionConflict when the version is already saved.
+type NoteRevisionsStore interface {
+	Create(ctx context.Context, revision *types.NoteRevision) error
+	List(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteRevision, error)
+	Get(ctx context.Context, noteId primitive.ObjectID, version int) (*types.NoteRevision, error)
+	Prune(ctx context.Context, noteId primitive.ObjectID, keep int) (int64, error)
+	DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// SharesStore is implemented by every backend that can persist the shares of
+// notes and tasks. An item is shared at most once with each user.
+type SharesStore interface {
+	Upsert(ctx context.Context, share *types.Share) (*types.Share, error)
+	Get(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error)
+	ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Share, error)
+	ListByUser(ctx context.Context, userId primitive.ObjectID) ([]*types.Share, error)
+	Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, userId primitive.ObjectID) (*types.Share, error)
+	DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// NoteLinksStore is implemented by every backend that can persist public
+// links to notes. Tokens are unique across all notes. RecordFailedAttempt
+// locks a link for LinkLockout once MaxLinkPasswordAttempts wrong passwords
+// were given in a row, and RecordView starts the count over.
+type NoteLinksStore interface {
+	Create(ctx context.Context, link *types.NoteLink) (*types.NoteLink, error)
+	ListByNote(ctx context.Context, noteId primitive.ObjectID) ([]*types.NoteLink, error)
+	GetByToken(ctx context.Context, token string) (*types.NoteLink, error)
+	RecordView(ctx context.Context, id primitive.ObjectID) (*types.NoteLink, error)
+	RecordFailedAttempt(ctx context.Context, id primitive.ObjectID, now time.Time) (*types.NoteLink, error)
+	Delete(ctx context.Context, noteId primitive.ObjectID, id primitive.ObjectID) (*types.NoteLink, error)
+	DeleteByNote(ctx context.Context, noteId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// AttachmentsStore is implemented by every backend that can persist the
+// metadata of files attached to notes and tasks. The Delete methods return
+// the removed attachments so their contents can be removed from blob storage.
+type AttachmentsStore interface {
+	Create(ctx context.Context, attachment *types.Attachment) (*types.Attachment, error)
+	Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error)
+	ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error)
+	Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Attachment, error)
+	DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Attachment, error)
+	DeleteOrphans(ctx context.Context) ([]*types.Attachment, error)
+}
+
+// CommentsStore is implemented by every backend that can persist comments on
+// notes and tasks. Deleting a comment also deletes the replies to it.
+type CommentsStore interface {
+	Create(ctx context.Context, comment *types.Comment) (*types.Comment, error)
+	Get(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (*types.Comment, error)
+	ListByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) ([]*types.Comment, error)
+	Update(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID, body string, mentions []primitive.ObjectID) (*types.Comment, error)
+	Delete(ctx context.Context, itemType string, itemId primitive.ObjectID, id primitive.ObjectID) (int64, error)
+	CountByItems(ctx context.Context, itemType string, itemIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// TimeEntriesStore is implemented by every backend that can persist the time
+// users spend on tasks. Create returns ErrTimerRunning when starting a timer
+// for a user who already has one running.
+type TimeEntriesStore interface {
+	Create(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error)
+	Get(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error)
+	GetRunning(ctx context.Context, userId primitive.ObjectID) (*types.TimeEntry, error)
+	Stop(ctx context.Context, id primitive.ObjectID, endedAt time.Time, note *string) (*types.TimeEntry, error)
+	Update(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error)
+	Delete(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error)
+	ListByTask(ctx context.Context, taskId primitive.ObjectID) ([]*types.TimeEntry, error)
+	ListEnded(ctx context.Context, taskIds []primitive.ObjectID, userId primitive.ObjectID, from time.Time, to time.Time) ([]*types.TimeEntry, error)
+	TotalByTasks(ctx context.Context, taskIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	DeleteByTask(ctx context.Context, taskId primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// WorkflowsStore is implemented by every backend that can persist custom task
+// workflows. A user has at most one workflow.
+type WorkflowsStore interface {
+	Get(ctx context.Context, userId primitive.ObjectID) (*types.Workflow, error)
+	Upsert(ctx context.Context, workflow *types.Workflow) (*types.Workflow, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+}
+
+// NotificationsStore is implemented by every backend that can persist the
+// notifications of users. MarkRead only finds the notifications of the given
+// user.
+type NotificationsStore interface {
+	Create(ctx context.Context, notification *types.Notification) (*types.Notification, error)
+	ListByUser(ctx context.Context, userId primitive.ObjectID, unreadOnly bool, limit int) ([]*types.Notification, error)
+	MarkRead(ctx context.Context, userId primitive.ObjectID, id primitive.ObjectID) (*types.Notification, error)
+	MarkAllRead(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteByItem(ctx context.Context, itemType string, itemId primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteOrphans(ctx context.Context) (int64, error)
+}
+
+// timestamp returns the time recorded by the store for creations, updates and
+// deletions. It is truncated to milliseconds so it compares equal after a round
+// trip through any backend.
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// actorKey is the context key under which WithActor stores the acting user.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that records userId as the author of the
+// documents created or updated with it.
+func WithActor(ctx context.Context, userId primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userId)
+}
+
+// actorFrom returns the user set with WithActor, or nil when there is none
+// (for example during signup).
+func actorFrom(ctx context.Context) *primitive.ObjectID {
+	if userId, ok := ctx.Value(actorKey{}).(primitive.ObjectID); ok {
+		return &userId
+	}
+	return nil
+}
+
+// stampStatusHistory records the change time on status entries that do not have one yet.
+func stampStatusHistory(history []*types.Status, at time.Time) {
+	for _, status := range history {
+		if status != nil && status.ChangedAt.IsZero() {
+			status.ChangedAt = at
+		}
+	}
+}
+
+// dueSort is the value tasks are sorted and filtered on by due date.
+func dueSort(dueAt *time.Time) time.Time {
+	if dueAt == nil {
+		return types.NoDueDate
+	}
+	return dueAt.UTC()
+}
+
+// currentStatus is the status a task is in: the last entry of its history.
+func currentStatus(history []*types.Status) string {
+	if len(history) == 0 || history[len(history)-1] == nil {
+		return ""
+	}
+	return history[len(history)-1].Status
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-auth/types"
+	"log"
+	"math"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrSubtaskDepth is returned when a subtask would nest deeper than
+	// types.MaxSubtaskDepth.
+	ErrSubtaskDepth = fmt.Errorf("subtasks can nest at most %d levels deep", types.MaxSubtaskDepth)
+
+	// ErrSubtaskCycle is returned when a task would be moved under itself or
+	// one of its own subtasks.
+	ErrSubtaskCycle = errors.New("a task cannot be moved under itself or one of its subtasks")
+
+	// ErrSubtaskOwner is returned when a task would be moved under a task of
+	// another user.
+	ErrSubtaskOwner = errors.New("a subtask must belong to the owner of its parent task")
+)
+
+// autoCompleteComment is recorded with the status change of auto-completed tasks.
+const autoCompleteComment = "All subtasks and checklist items are done"
+
+// CreateSubtask creates a task under parent. Subtasks belong to the owner of
+// the top-level task whoever creates them.
+func (s *Store) CreateSubtask(ctx context.Context, parent *types.Tasks, task *types.TasksCreate) (*types.Tasks, error) {
+	ancestors := slices.Concat(parent.Ancestors, []primitive.ObjectID{parent.Id})
+	if len(ancestors) > types.MaxSubtaskDepth {
+		return nil, ErrSubtaskDepth
+	}
+	task.UserID = parent.UserID
+	task.ParentID = &parent.Id
+	task.Ancestors = ancestors
+	return s.Tasks.Create(ctx, task)
+}
+
+// MoveTask moves a task and its subtasks under parent, or to the top level
+// when parent is nil.
+func (s *Store) MoveTask(ctx context.Context, task *types.Tasks, parent *types.Tasks) (*types.Tasks, error) {
+	var ancestors []primitive.ObjectID
+	if parent != nil {
+		if parent.UserID != task.UserID {
+			return nil, ErrSubtaskOwner
+		}
+		if parent.Id == task.Id || slices.Contains(parent.Ancestors, task.Id) {
+			return nil, ErrSubtaskCycle
+		}
+		ancestors = slices.Concat(parent.Ancestors, []primitive.ObjectID{parent.Id})
+	}
+
+	// The deepest subtask moves along with the task
+	subtasks, err := s.Tasks.ListDescendants(ctx, task.Id)
+	if err != nil {
+		return nil, err
+	}
+	height := 0
+	for _, subtask := range subtasks {
+		height = max(height, len(subtask.Ancestors)-len(task.Ancestors))
+	}
+	if len(ancestors)+height > types.MaxSubtaskDepth {
+		return nil, ErrSubtaskDepth
+	}
+	return s.Tasks.Move(ctx, task.Id, ancestors)
+}
+
+// LoadSubtasks computes the progress of a task and of its live subtasks
+// against the workflow of its owner, and returns its direct subtasks, oldest
+// first.
+func (s *Store) LoadSubtasks(ctx context.Context, task *types.Tasks) ([]*types.Tasks, error) {
+	descendants, err := s.Tasks.ListDescendants(ctx, task.Id)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := s.TaskWorkflow(ctx, task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	children := map[primitive.ObjectID][]*types.Tasks{}
+	for _, subtask := range descendants {
+		if subtask.DeletedAt == nil && subtask.ParentID != nil {
+			children[*subtask.ParentID] = append(children[*subtask.ParentID], subtask)
+		}
+	}
+	taskProgress(task, children, workflow)
+
+	subtasks := children[task.Id]
+	if subtasks == nil {
+		subtasks = []*types.Tasks{}
+	}
+	return subtasks, nil
+}
+
+// taskProgress sets the progress of a task and its subtasks and returns the
+// fraction of the task that is done. Tasks without subtasks or checklist
+// items are done or not by their status.
+func taskProgress(task *types.Tasks, children map[primitive.ObjectID][]*types.Tasks, workflow *types.Workflow) float64 {
+	progress := &types.TaskProgress{
+		Subtasks:       len(children[task.Id]),
+		ChecklistItems: len(task.Checklist),
+	}
+	task.Progress = progress
+
+	var done float64
+	for _, item := range task.Checklist {
+		if item.Done {
+			progress.ChecklistDone++
+			done++
+		}
+	}
+	for _, subtask := range children[task.Id] {
+		fraction := taskProgress(subtask, children, workflow)
+		if workflow.IsDone(subtask.CurrentStatus) {
+			progress.SubtasksDone++
+			fraction = 1
+		}
+		done += fraction
+	}
+
+	units := progress.Subtasks + progress.ChecklistItems
+	if units == 0 {
+		if workflow.IsDone(task.CurrentStatus) {
+			progress.Percent = 100
+		}
+		return float64(progress.Percent) / 100
+	}
+	progress.Percent = int(math.Round(100 * done / float64(units)))
+	return done / float64(units)
+}
+
+// AutoComplete is called after a task changed. It completes the task when it
+// asks for it and all its subtasks and checklist items are done, then checks
+// its parent, and so on for as long as tasks get completed. It returns the
+// task as it is afterwards. Auto-completion is a side effect of the change,
+// so failures are logged rather than returned.
+func (s *Store) AutoComplete(ctx context.Context, task *types.Tasks) *types.Tasks {
+	changed := task
+	for current, first := task, true; ; first = false {
+		completed, err := s.completeTask(ctx, current)
+		if err != nil {
+			log.Printf("Failed to auto-complete task %s: %v", current.Id.Hex(), err)
+			return changed
+		}
+		if completed != nil && first {
+			changed = completed
+		}
+		if current.ParentID == nil || (completed == nil && !first) {
+			return changed
+		}
+		parentId := *current.ParentID
+		if current, err = s.Tasks.Get(ctx, parentId); err != nil {
+			if err != ErrNotFound {
+				log.Printf("Failed to auto-complete task %s: %v", parentId.Hex(), err)
+			}
+			return changed
+		}
+	}
+}
+
+// completeTask moves a task to a done status of the workflow of its owner
+// when it asks for it and all its subtasks and checklist items are done. The
+// first done status the workflow allows from the current one is preferred,
+// but the task is completed even where the workflow has no such transition,
+// since no user made the move. It returns the completed task, or nil when it
+// was left as is.
+func (s *Store) completeTask(ctx context.Context, task *types.Tasks) (*types.Tasks, error) {
+	if !task.AutoComplete {
+		return nil, nil
+	}
+	workflow, err := s.TaskWorkflow(ctx, task.UserID)
+	if err != nil || workflow.IsDone(task.CurrentStatus) {
+		return nil, err
+	}
+	if _, err := s.LoadSubtasks(ctx, task); err != nil {
+		return nil, err
+	}
+	if !task.Progress.Done() {
+		return nil, nil
+	}
+	if len(workflow.Done) == 0 {
+		return nil, nil
+	}
+	status := workflow.Done[0]
+	for _, done := range workflow.Done {
+		if workflow.CheckTransition(task.CurrentStatus, done) == nil {
+			status = done
+			break
+		}
+	}
+	var userId string
+	if actor := actorFrom(ctx); actor != nil {
+		userId = actor.Hex()
+	}
+	completed, err := s.Tasks.AppendStatus(ctx, task.Id, &types.Status{
+		Status:         status,
+		PreviousStatus: task.CurrentStatus,
+		Comment:        autoCompleteComment,
+		UserId:         userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Recur(ctx, completed), nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AttachUserContent sets the note and task counts of every user and embeds
+// the notes and tasks selected by include. It runs a fixed number of queries
+// whatever the number of users, instead of one per user.
+func (s *Store) AttachUserContent(ctx context.Context, users []*types.UserResponse, include types.UserInclude) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIds := make([]primitive.ObjectID, len(users))
+	byId := make(map[primitive.ObjectID]*types.UserResponse, len(users))
+	for i, user := range users {
+		userIds[i] = user.Id
+		byId[user.Id] = user
+	}
+
+	var noteCounts, taskCounts map[primitive.ObjectID]int64
+	var err error
+	if include.Notes {
+		notes, err := s.Notes.ListByUsers(ctx, userIds)
+		if err != nil {
+			return err
+		}
+		noteCounts = map[primitive.ObjectID]int64{}
+		for _, user := range users {
+			user.Notes = []*types.Notes{}
+		}
+		for _, note := range notes {
+			if user, ok := byId[note.UserID]; ok {
+				user.Notes = append(user.Notes, note)
+				noteCounts[note.UserID]++
+			}
+		}
+	} else if noteCounts, err = s.Notes.CountByUsers(ctx, userIds); err != nil {
+		return err
+	}
+
+	if include.Tasks {
+		tasks, err := s.Tasks.ListByUsers(ctx, userIds)
+		if err != nil {
+			return err
+		}
+		taskCounts = map[primitive.ObjectID]int64{}
+		for _, user := range users {
+			user.Tasks = []*types.Tasks{}
+		}
+		for _, task := range tasks {
+			if user, ok := byId[task.UserID]; ok {
+				user.Tasks = append(user.Tasks, task)
+				taskCounts[task.UserID]++
+			}
+		}
+	} else if taskCounts, err = s.Tasks.CountByUsers(ctx, userIds); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		notesCount, tasksCount := noteCounts[user.Id], taskCounts[user.Id]
+		user.NotesCount = &notesCount
+		user.TasksCount = &tasksCount
+	}
+	return nil
+}
+
+// countByUsers groups the live documents of a collection by owner.
+func countByUsers(ctx context.Context, collection *mongo.Collection, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"user_id": bson.M{"$in": userIds}})}},
+		{{Key: "$group", Value: bson.M{"_id": "$user_id", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		UserId primitive.ObjectID `bson:"_id"`
+		Count  int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserId] = row.Count
+	}
+	return counts, nil
+}
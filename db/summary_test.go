@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"testing"
+)
+
+const (
+	benchUsers        = 200
+	benchItemsPerUser = 20
+	benchUsersPerPage = 50
+)
+
+// seedUserContent creates users that each own notes and tasks and returns
+// the first page of them.
+func seedUserContent(b *testing.B, store *Store) []*types.UserResponse {
+	b.Helper()
+	ctx := context.Background()
+	var users []*types.UserResponse
+	for i := 0; i < benchUsers; i++ {
+		user, err := store.User.Create(ctx, &types.UserCreate{
+			Name:     fmt.Sprintf("User %d", i),
+			Email:    fmt.Sprintf("user%d@example.com", i),
+			Password: "password",
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < benchItemsPerUser; j++ {
+			if _, err := store.Notes.Create(ctx, &types.NotesCreate{
+				Title:  fmt.Sprintf("Note %d", j),
+				Note:   "Some text to make the document a realistic size for decoding.",
+				UserID: user.Id,
+			}); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := store.Tasks.Create(ctx, &types.TasksCreate{
+				Title:    fmt.Sprintf("Task %d", j),
+				Category: "work",
+				Task:     "Some text to make the document a realistic size for decoding.",
+				UserID:   user.Id,
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		users = append(users, user)
+	}
+	return users[:benchUsersPerPage]
+}
+
+// attachPerUser is how user lists loaded their content before
+// AttachUserContent: two queries per user.
+func attachPerUser(ctx context.Context, store *Store, users []*types.UserResponse) error {
+	for _, user := range users {
+		notes, err := store.Notes.List(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+		tasks, err := store.Tasks.List(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+		user.Notes = notes
+		user.Tasks = tasks
+	}
+	return nil
+}
+
+// BenchmarkAttachUserContent compares loading a page of users one at a time
+// with the batched queries. Embedding decodes as many documents as the old
+// loop, which bounds how much faster it can get on an in-process database.
+func BenchmarkAttachUserContent(b *testing.B) {
+	store := newTestSQLStore(b)
+	users := seedUserContent(b, store)
+	ctx := context.Background()
+
+	b.Run("per-user", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := attachPerUser(ctx, store, users); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("counts", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := store.AttachUserContent(ctx, users, types.UserInclude{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("include", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := store.AttachUserContent(ctx, users, types.UserInclude{Notes: true, Tasks: true}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
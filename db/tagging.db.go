@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTagOwnerMismatch is returned when merging tags of two different users.
+var ErrTagOwnerMismatch = errors.New("tags belong to different users")
+
+// ListTags retrieves the tags of a user with the number of live notes and
+// tasks carrying each of them.
+func (s *Store) ListTags(ctx context.Context, userId primitive.ObjectID) ([]*types.Tag, error) {
+	tags, err := s.Tags.List(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	noteCounts, err := s.Notes.CountByTags(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	taskCounts, err := s.Tasks.CountByTags(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		notesCount, tasksCount := noteCounts[tag.Id], taskCounts[tag.Id]
+		tag.NotesCount = &notesCount
+		tag.TasksCount = &tasksCount
+	}
+	return tags, nil
+}
+
+// DeleteTag removes a tag and detaches it from every note and task.
+func (s *Store) DeleteTag(ctx context.Context, id primitive.ObjectID) (*types.Tag, error) {
+	var deleted *types.Tag
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = s.Tags.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Notes.ReplaceTag(ctx, id, nil); err != nil {
+			return err
+		}
+		_, err = s.Tasks.ReplaceTag(ctx, id, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// MergeTags moves every note and task tagged with from over to into and
+// removes from. Both tags must belong to the same user.
+func (s *Store) MergeTags(ctx context.Context, from primitive.ObjectID, into primitive.ObjectID) (*types.Tag, error) {
+	var merged *types.Tag
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		source, err := s.Tags.Get(ctx, from)
+		if err != nil {
+			return err
+		}
+		merged, err = s.Tags.Get(ctx, into)
+		if err != nil {
+			return err
+		}
+		if source.UserID != merged.UserID {
+			return ErrTagOwnerMismatch
+		}
+		if _, err := s.Notes.ReplaceTag(ctx, from, &into); err != nil {
+			return err
+		}
+		if _, err := s.Tasks.ReplaceTag(ctx, from, &into); err != nil {
+			return err
+		}
+		_, err = s.Tags.Delete(ctx, from)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// transferTags hands the tags of one user over to another, merging tags the
+// receiving user already has under the same name. It runs after the notes and
+// tasks themselves have been transferred.
+func (s *Store) transferTags(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) error {
+	tags, err := s.Tags.List(ctx, fromUserId)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		existing, err := s.Tags.FindByKey(ctx, toUserId, tagKey(tag.Name))
+		if err == ErrNotFound {
+			if err := s.Tags.SetOwner(ctx, tag.Id, toUserId); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.Notes.ReplaceTag(ctx, tag.Id, &existing.Id); err != nil {
+			return err
+		}
+		if _, err := s.Tasks.ReplaceTag(ctx, tag.Id, &existing.Id); err != nil {
+			return err
+		}
+		if _, err := s.Tags.Delete(ctx, tag.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagsOwnedBy reports whether every one of the tag IDs exists and belongs to userId.
+func (s *Store) TagsOwnedBy(ctx context.Context, userId primitive.ObjectID, ids []primitive.ObjectID) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	unique := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+	tags, err := s.Tags.GetMany(ctx, ids)
+	if err != nil {
+		return false, err
+	}
+	owned := 0
+	for _, tag := range tags {
+		if tag.UserID == userId {
+			owned++
+		}
+	}
+	return owned == len(unique), nil
+}
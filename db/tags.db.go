@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoTagsStore struct {
+	collection *mongo.Collection
+}
+
+// List retrieves the tags of a user, sorted by name
+func (t *MongoTagsStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tag, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "key", Value: 1}})
+	return t.find(ctx, bson.M{"user_id": userId}, opts)
+}
+
+// Get retrieves a single tag by ID
+func (t *MongoTagsStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tag, error) {
+	return t.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetMany retrieves the tags with the given IDs; unknown IDs are skipped
+func (t *MongoTagsStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tag, error) {
+	return t.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// FindByKey retrieves the tag of a user with the given normalized name
+func (t *MongoTagsStore) FindByKey(ctx context.Context, userId primitive.ObjectID, key string) (*types.Tag, error) {
+	return t.findOne(ctx, bson.M{"user_id": userId, "key": key})
+}
+
+// Create inserts a new tag and returns it
+func (t *MongoTagsStore) Create(ctx context.Context, tag *types.TagCreate) (*types.Tag, error) {
+	tag.Key = tagKey(tag.Name)
+	tag.CreatedAt = timestamp()
+	tag.UpdatedAt = tag.CreatedAt
+	tag.CreatedBy = actorFrom(ctx)
+	tag.UpdatedBy = tag.CreatedBy
+
+	result, err := t.collection.InsertOne(ctx, tag)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateTag
+	}
+	if err != nil {
+		return nil, err
+	}
+	newTag := types.Tag{
+		Id:        result.InsertedID.(primitive.ObjectID),
+		Name:      tag.Name,
+		Color:     tag.Color,
+		Key:       tag.Key,
+		UserID:    tag.UserID,
+		CreatedAt: tag.CreatedAt,
+		UpdatedAt: tag.UpdatedAt,
+		CreatedBy: tag.CreatedBy,
+		UpdatedBy: tag.UpdatedBy,
+	}
+	return &newTag, nil
+}
+
+// Update renames or recolors a tag and returns it
+func (t *MongoTagsStore) Update(ctx context.Context, id primitive.ObjectID, updateData *types.TagUpdate) (*types.Tag, error) {
+	updateData.Key = tagKey(updateData.Name)
+	updateData.UpdatedAt = timestamp()
+	updateData.UpdatedBy = actorFrom(ctx)
+
+	var tag types.Tag
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := t.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": updateData}, opts).Decode(&tag)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateTag
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Delete removes a tag and returns it. Items still referencing it are left to the caller.
+func (t *MongoTagsStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Tag, error) {
+	var tag types.Tag
+	err := t.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&tag)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// SetOwner moves a tag to another user
+func (t *MongoTagsStore) SetOwner(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) error {
+	update := bson.M{
+		"$set": bson.M{"user_id": userId, "updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := t.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUser removes every tag of the user and returns how many were removed
+func (t *MongoTagsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := t.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (t *MongoTagsStore) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*types.Tag, error) {
+	cursor, err := t.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tags := []*types.Tag{}
+	err = cursor.All(ctx, &tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (t *MongoTagsStore) findOne(ctx context.Context, filter bson.M) (*types.Tag, error) {
+	var tag types.Tag
+	err := t.collection.FindOne(ctx, filter).Decode(&tag)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// tagKey normalizes a tag name so "Work", "work " and "WORK" are the same tag.
+func tagKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// idList stores a missing list of IDs, such as tags, as an empty array rather
+// than null.
+func idList(ids []primitive.ObjectID) []primitive.ObjectID {
+	if ids == nil {
+		return []primitive.ObjectID{}
+	}
+	return ids
+}
+
+// replaceTag rewrites the tags array of every document carrying from.
+func replaceTag(ctx context.Context, collection *mongo.Collection, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	set := bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)}
+	var tags any = bson.M{"$setDifference": bson.A{"$tags", bson.A{from}}}
+	if to != nil {
+		tags = bson.M{"$setUnion": bson.A{tags, bson.A{*to}}}
+	}
+	set["tags"] = tags
+	result, err := collection.UpdateMany(ctx, bson.M{"tags": from}, mongo.Pipeline{{{Key: "$set", Value: set}}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// countByTags counts the live documents of a user per tag.
+func countByTags(ctx context.Context, collection *mongo.Collection, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"user_id": userId})}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TagId primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagId] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLTagsStore struct {
+	db *sqlDB
+}
+
+// List retrieves the tags of a user, sorted by name
+func (t *SQLTagsStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tag, error) {
+	return listDocs[types.Tag](ctx, t.db, "SELECT data FROM tags WHERE user_id = ? ORDER BY key", userId.Hex())
+}
+
+// Get retrieves a single tag by ID
+func (t *SQLTagsStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tag, error) {
+	var tag types.Tag
+	if err := t.db.getDoc(ctx, "tags", id.Hex(), &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetMany retrieves the tags with the given IDs; unknown IDs are skipped
+func (t *SQLTagsStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tag, error) {
+	if len(ids) == 0 {
+		return []*types.Tag{}, nil
+	}
+	return listDocs[types.Tag](ctx, t.db, "SELECT data FROM tags WHERE id IN ("+placeholders(len(ids))+")", hexIds(ids)...)
+}
+
+// FindByKey retrieves the tag of a user with the given normalized name
+func (t *SQLTagsStore) FindByKey(ctx context.Context, userId primitive.ObjectID, key string) (*types.Tag, error) {
+	var tag types.Tag
+	err := t.db.findDoc(ctx, &tag, "SELECT data FROM tags WHERE user_id = ? AND key = ?", userId.Hex(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Create inserts a new tag and returns it
+func (t *SQLTagsStore) Create(ctx context.Context, tag *types.TagCreate) (*types.Tag, error) {
+	newTag := types.Tag{
+		Id:        primitive.NewObjectID(),
+		Name:      tag.Name,
+		Color:     tag.Color,
+		Key:       tagKey(tag.Name),
+		UserID:    tag.UserID,
+		CreatedAt: timestamp(),
+		CreatedBy: actorFrom(ctx),
+	}
+	newTag.UpdatedAt = newTag.CreatedAt
+	newTag.UpdatedBy = newTag.CreatedBy
+
+	data, err := marshalDoc(newTag)
+	if err != nil {
+		return nil, err
+	}
+	_, err = t.db.exec(ctx, "INSERT INTO tags (id, user_id, key, created_at, updated_at, data) VALUES (?, ?, ?, ?, ?, ?)",
+		newTag.Id.Hex(), newTag.UserID.Hex(), newTag.Key, sqlTime(newTag.CreatedAt), sqlTime(newTag.UpdatedAt), data)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateTag
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &newTag, nil
+}
+
+// Update renames or recolors a tag and returns it
+func (t *SQLTagsStore) Update(ctx context.Context, id primitive.ObjectID, updateData *types.TagUpdate) (*types.Tag, error) {
+	tag, err := t.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tag.Name = updateData.Name
+	tag.Color = updateData.Color
+	tag.UpdatedAt = timestamp()
+	tag.UpdatedBy = actorFrom(ctx)
+	if err := t.save(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Delete removes a tag and returns it. Items still referencing it are left to the caller.
+func (t *SQLTagsStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Tag, error) {
+	tag, err := t.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.db.exec(ctx, "DELETE FROM tags WHERE id = ?", id.Hex()); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// SetOwner moves a tag to another user
+func (t *SQLTagsStore) SetOwner(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) error {
+	tag, err := t.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	tag.UserID = userId
+	tag.UpdatedAt = timestamp()
+	tag.UpdatedBy = actorFrom(ctx)
+	return t.save(ctx, tag)
+}
+
+// DeleteByUser removes every tag of the user and returns how many were removed
+func (t *SQLTagsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := t.db.exec(ctx, "DELETE FROM tags WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// save writes the tag back, keeping the indexed columns in sync with the document
+func (t *SQLTagsStore) save(ctx context.Context, tag *types.Tag) error {
+	data, err := marshalDoc(tag)
+	if err != nil {
+		return err
+	}
+	_, err = t.db.exec(ctx, "UPDATE tags SET user_id = ?, key = ?, updated_at = ?, data = ? WHERE id = ?",
+		tag.UserID.Hex(), tagKey(tag.Name), sqlTime(tag.UpdatedAt), data, tag.Id.Hex())
+	if isUniqueViolation(err) {
+		return ErrDuplicateTag
+	}
+	return err
+}
+
+// retag removes from a tag list and adds to, when set, without duplicating it.
+func retag(tags []primitive.ObjectID, from primitive.ObjectID, to *primitive.ObjectID) []primitive.ObjectID {
+	result := []primitive.ObjectID{}
+	for _, tag := range tags {
+		if tag != from && (to == nil || tag != *to) {
+			result = append(result, tag)
+		}
+	}
+	if to != nil {
+		result = append(result, *to)
+	}
+	return result
+}
+
+// jsonArrayContains returns a condition, taking one placeholder, that holds
+// when the array under key in the data column contains the value.
+func (d sqlDialect) jsonArrayContains(key string) string {
+	if d == dialectPostgres {
+		return "(data::jsonb -> '" + key + "') @> jsonb_build_array(?::text)"
+	}
+	return "EXISTS (SELECT 1 FROM json_each(data, '$." + key + "') WHERE json_each.value = ?)"
+}
+
+// jsonArrayContainsAny returns a condition, taking n placeholders, that holds
+// when the array under key in the data column of table contains any of them.
+func (d sqlDialect) jsonArrayContainsAny(table, key string, n int) string {
+	if d == dialectPostgres {
+		return "EXISTS (SELECT 1 FROM jsonb_array_elements_text(" + table + ".data::jsonb -> '" + key + "') AS item(value) WHERE item.value IN (" + placeholders(n) + "))"
+	}
+	return "EXISTS (SELECT 1 FROM json_each(" + table + ".data, '$." + key + "') AS item WHERE item.value IN (" + placeholders(n) + "))"
+}
+
+// countByTags counts the live rows of a user in table per tag.
+func (s *sqlDB) countByTags(ctx context.Context, table string, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	query := "SELECT tag.value, COUNT(*) FROM " + table + ", json_each(" + table + ".data, '$.tags') AS tag"
+	if s.dialect == dialectPostgres {
+		query = "SELECT tag.value, COUNT(*) FROM " + table + ", jsonb_array_elements_text(" + table + ".data::jsonb -> 'tags') AS tag(value)"
+	}
+	query += " WHERE " + table + ".user_id = ? AND " + table + ".deleted_at IS NULL GROUP BY tag.value"
+
+	rows, err := s.query(ctx, query, userId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[primitive.ObjectID]int64{}
+	for rows.Next() {
+		var tagId string
+		var count int64
+		if err := rows.Scan(&tagId, &count); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(tagId)
+		if err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
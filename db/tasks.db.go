@@ -4,35 +4,108 @@ import (
 	"context"
 	"fmt"
 	"golang-auth/types"
+	"golang-auth/utils"
+	"slices"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type TasksStore struct {
+type MongoTasksStore struct {
 	collection *mongo.Collection
 }
 
 // List retrieves all tasks for a specific user
-func (n *TasksStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error) {
-	filter := bson.M{"user_id": userId}
-	cursor, err := n.collection.Find(ctx, filter)
+func (n *MongoTasksStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error) {
+	filter := notDeleted(bson.M{"user_id": userId})
+	return n.find(ctx, filter)
+}
+
+// ListPage retrieves one page of a user's tasks matching the query
+func (n *MongoTasksStore) ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Tasks, *types.Pagination, error) {
+	req, err := resolveListQuery(query, taskSortFields)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var tasks []*types.Tasks
-	err = cursor.All(ctx, &tasks)
+	filter := req.createdRange(notDeleted(taskScope(userId, req.Scope)))
+	if req.Category != "" {
+		filter["category"] = req.Category
+	}
+	if len(req.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": req.Tags}
+	}
+	status := bson.M{}
+	if req.Status != "" {
+		status["$eq"] = req.Status
+	}
+	if len(req.NotStatuses) > 0 {
+		status["$nin"] = req.NotStatuses
+	}
+	if len(status) > 0 {
+		filter["current_status"] = status
+	}
+	due := bson.M{}
+	if req.DueFrom != nil {
+		due["$gte"] = *req.DueFrom
+	}
+	if req.DueTo != nil {
+		due["$lt"] = *req.DueTo
+	}
+	if len(due) > 0 {
+		filter["due_sort"] = due
+	}
+	return findPage[types.Tasks](ctx, n.collection, filter, req)
+}
+
+// ListByUsers retrieves the tasks of several users with a single query
+func (n *MongoTasksStore) ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Tasks, error) {
+	filter := notDeleted(bson.M{"user_id": bson.M{"$in": userIds}})
+	// Sorting by owner first lets the user_id_created_at index return the
+	// documents in order
+	opts := options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := n.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
+	tasks := []*types.Tasks{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
 	return tasks, nil
 }
 
+// CountByUsers counts the live tasks of several users with a single aggregation
+func (n *MongoTasksStore) CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countByUsers(ctx, n.collection, userIds)
+}
+
+// ReplaceTag swaps the tag from for to on every task carrying it, or
+// removes it when to is nil
+func (n *MongoTasksStore) ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	return replaceTag(ctx, n.collection, from, to)
+}
+
+// CountByTags counts the user's live tasks per tag
+func (n *MongoTasksStore) CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countByTags(ctx, n.collection, userId)
+}
+
+// Search finds the tasks of the user, or shared with them along with their
+// subtasks, matching any of the terms, best matches first
+func (n *MongoTasksStore) Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error) {
+	filter := searchFilter(searchOwners(userId, shared, true), terms, query)
+	return searchCollection(ctx, n.collection, filter, query.Limit, func(task *types.Tasks, score float64) *types.SearchResult {
+		return &types.SearchResult{Type: "task", Score: score, Task: task}
+	})
+}
+
 // Get retrieves a single task by ID
-func (n *TasksStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+func (n *MongoTasksStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
 	var task *types.Tasks
-	filter := bson.M{"_id": id}
+	filter := notDeleted(bson.M{"_id": id})
 	err := n.collection.FindOne(ctx, filter).Decode(&task)
 	if err != nil {
 		return nil, err
@@ -40,8 +113,37 @@ func (n *TasksStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tas
 	return task, nil
 }
 
+// GetMany retrieves the live tasks with the given IDs; unknown and trashed IDs are skipped
+func (n *MongoTasksStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tasks, error) {
+	return n.find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+}
+
 // Create inserts a new task into the database
-func (n *TasksStore) Create(ctx context.Context, task *types.TasksCreate) (*types.Tasks, error) {
+func (n *MongoTasksStore) Create(ctx context.Context, task *types.TasksCreate) (*types.Tasks, error) {
+	task.CreatedAt = timestamp()
+	task.UpdatedAt = task.CreatedAt
+	task.CreatedBy = actorFrom(ctx)
+	task.UpdatedBy = task.CreatedBy
+	stampStatusHistory(task.StatusHistory, task.CreatedAt)
+	task.CurrentStatus = currentStatus(task.StatusHistory)
+	task.DueSort = dueSort(task.DueAt)
+	task.Tags = idList(task.Tags)
+	task.Assignees = idList(task.Assignees)
+	task.Ancestors = idList(task.Ancestors)
+	if task.Checklist == nil {
+		task.Checklist = []*types.TaskChecklistItem{}
+	}
+	task.BlockedBy = idList(task.BlockedBy)
+	if task.Rank == "" {
+		last, err := n.LastRank(ctx, task.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if task.Rank, err = utils.RankBetween(last, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	result, err := n.collection.InsertOne(ctx, task)
 	if err != nil {
 		return nil, err
@@ -52,36 +154,81 @@ func (n *TasksStore) Create(ctx context.Context, task *types.TasksCreate) (*type
 		Category:      task.Category,
 		Task:          task.Task,
 		UserID:        task.UserID,
+		Assignees:     task.Assignees,
+		ParentID:      task.ParentID,
+		Ancestors:     task.Ancestors,
+		Checklist:     task.Checklist,
+		AutoComplete:  task.AutoComplete,
+		BlockedBy:     task.BlockedBy,
+		Recurrence:    task.Recurrence,
+		Rank:          task.Rank,
+		CurrentStatus: task.CurrentStatus,
 		StatusHistory: task.StatusHistory,
+		Tags:          task.Tags,
+		Priority:      task.Priority,
+		DueAt:         task.DueAt,
+		DueTimezone:   task.DueTimezone,
+		StartAt:       task.StartAt,
+		DueSort:       task.DueSort,
+		CreatedAt:     task.CreatedAt,
+		UpdatedAt:     task.UpdatedAt,
+		CreatedBy:     task.CreatedBy,
+		UpdatedBy:     task.UpdatedBy,
 	}
 
 	return &newTask, nil
 }
 
-// Delete removes a task by ID and returns the deleted task
-func (n *TasksStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
-	var deletedTask *types.Tasks
-	err := n.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&deletedTask)
+// Delete moves a task and its live subtasks to the trash and returns the task
+func (n *MongoTasksStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": timestamp()},
+	}
+	task, err := n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
 	if err != nil {
 		return nil, err
 	}
-	_, err = n.collection.DeleteOne(ctx, bson.M{"_id": id})
-	if err != nil {
+	if _, err := n.collection.UpdateMany(ctx, notDeleted(bson.M{"ancestors": id}), update); err != nil {
 		return nil, err
 	}
-	return deletedTask, nil
+	return task, nil
 }
 
 // Update modifies an existing task based on its ID
-func (n *TasksStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.TasksUpdate) (*types.Tasks, error) {
-	update := bson.M{
-		"$set": updatedData,
+func (n *MongoTasksStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.TasksUpdate) (*types.Tasks, error) {
+	updatedData.UpdatedAt = timestamp()
+	updatedData.UpdatedBy = actorFrom(ctx)
+	updatedData.DueSort = dueSort(updatedData.DueAt)
+	updatedData.Tags = idList(updatedData.Tags)
+	filter := notDeleted(bson.M{"_id": id})
+	update := bson.M{}
+	if status := updatedData.Status; status != nil {
+		stampStatusHistory([]*types.Status{status}, updatedData.UpdatedAt)
+		updatedData.CurrentStatus = status.Status
+		filter["current_status"] = status.PreviousStatus
+		update["$push"] = bson.M{"statushistory": status}
+	}
+	if updatedData.Recurrence != nil || updatedData.ClearRecurrence {
+		filter["recurrence.next_id"] = nil
+		if updatedData.ClearRecurrence {
+			updatedData.Recurrence = nil
+			update["$unset"] = bson.M{"recurrence": ""}
+		}
 	}
-	result, err := n.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	update["$set"] = updatedData
+
+	result, err := n.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return nil, err
 	}
 	if result.MatchedCount == 0 {
+		count, err := n.collection.CountDocuments(ctx, notDeleted(bson.M{"_id": id}))
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrConcurrentUpdate
+		}
 		return nil, fmt.Errorf("no task found")
 	}
 
@@ -93,3 +240,394 @@ func (n *TasksStore) Update(ctx context.Context, id primitive.ObjectID, updatedD
 	}
 	return updatedTask, nil
 }
+
+// DeleteByUser permanently removes every task owned by the user, including
+// trashed ones, and returns how many were removed
+func (n *MongoTasksStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// TransferOwner moves every task owned by fromUserId to toUserId
+func (n *MongoTasksStore) TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"user_id": toUserId, "updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"user_id": fromUserId}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListTrash retrieves the trashed tasks of a user
+func (n *MongoTasksStore) ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error) {
+	return n.find(ctx, inTrash(bson.M{"user_id": userId}))
+}
+
+// GetTrashed retrieves a single trashed task by ID
+func (n *MongoTasksStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	var task *types.Tasks
+	err := n.collection.FindOne(ctx, inTrash(bson.M{"_id": id})).Decode(&task)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Restore takes a task out of the trash, along with the subtasks trashed with
+// it, and returns the task
+func (n *MongoTasksStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	trashed, err := n.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	task, err := n.findOneAndUpdate(ctx, inTrash(bson.M{"_id": id}), update)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.collection.UpdateMany(ctx, bson.M{"ancestors": id, "deleted_at": *trashed.DeletedAt}, update); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Purge permanently removes a trashed task and its subtasks and returns the task
+func (n *MongoTasksStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	var purgedTask *types.Tasks
+	err := n.collection.FindOneAndDelete(ctx, inTrash(bson.M{"_id": id})).Decode(&purgedTask)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.collection.DeleteMany(ctx, bson.M{"ancestors": id}); err != nil {
+		return nil, err
+	}
+	return purgedTask, nil
+}
+
+// TrashByUser moves every live task of the user to the trash with the given timestamp
+func (n *MongoTasksStore) TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": deletedAt},
+	}
+	result, err := n.collection.UpdateMany(ctx, notDeleted(bson.M{"user_id": userId}), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RestoreByUser restores the tasks of the user that were trashed at deletedAt
+func (n *MongoTasksStore) RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"user_id": userId, "deleted_at": deletedAt}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// PurgeDeletedBefore permanently removes tasks trashed before the given time
+func (n *MongoTasksStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := n.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Assign adds a user to the assignees of a task and returns it
+func (n *MongoTasksStore) Assign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error) {
+	update := bson.M{
+		"$addToSet": bson.M{"assignees": userId},
+		"$set":      bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
+}
+
+// Unassign removes a user from the assignees of a task and returns it
+func (n *MongoTasksStore) Unassign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error) {
+	update := bson.M{
+		"$pull": bson.M{"assignees": userId},
+		"$set":  bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id, "assignees": userId}), update)
+}
+
+// UnassignUser removes a user from the assignees of every task, including
+// trashed ones
+func (n *MongoTasksStore) UnassignUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$pull": bson.M{"assignees": userId},
+		"$set":  bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"assignees": userId}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListDescendants retrieves the subtasks of a task at every depth, including
+// trashed ones, oldest first
+func (n *MongoTasksStore) ListDescendants(ctx context.Context, id primitive.ObjectID) ([]*types.Tasks, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := n.collection.Find(ctx, bson.M{"ancestors": id}, opts)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []*types.Tasks{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Move places a task under the last of ancestors, or at the top level when
+// there are none, rewrites the ancestors of its subtasks and returns it
+func (n *MongoTasksStore) Move(ctx context.Context, id primitive.ObjectID, ancestors []primitive.ObjectID) (*types.Tasks, error) {
+	set := bson.M{"ancestors": idList(ancestors), "updated_at": timestamp(), "updated_by": actorFrom(ctx)}
+	update := bson.M{"$set": set}
+	if len(ancestors) > 0 {
+		set["parent_id"] = ancestors[len(ancestors)-1]
+	} else {
+		update["$unset"] = bson.M{"parent_id": ""}
+	}
+	task, err := n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subtasks keep the part of their ancestors below the task
+	below := bson.M{"$slice": bson.A{
+		"$ancestors",
+		bson.M{"$add": bson.A{bson.M{"$indexOfArray": bson.A{"$ancestors", id}}, 1}},
+		bson.M{"$size": "$ancestors"},
+	}}
+	path := bson.M{"$concatArrays": bson.A{slices.Concat(ancestors, []primitive.ObjectID{id}), below}}
+	_, err = n.collection.UpdateMany(ctx, bson.M{"ancestors": id}, bson.A{bson.M{"$set": bson.M{"ancestors": path}}})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// UpdateChecklist replaces the checklist of a task with the one change makes
+// of it and returns the task. The write only applies when the task was not
+// updated since it was read, and starts over otherwise.
+func (n *MongoTasksStore) UpdateChecklist(ctx context.Context, id primitive.ObjectID, change func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error)) (*types.Tasks, error) {
+	for attempt := 1; ; attempt++ {
+		task, err := n.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		checklist, err := change(task.Checklist)
+		if err != nil {
+			return nil, err
+		}
+		// updated_at must move forward for the next writer to notice
+		updatedAt := timestamp()
+		if !updatedAt.After(task.UpdatedAt) {
+			updatedAt = task.UpdatedAt.Add(time.Millisecond)
+		}
+		update := bson.M{
+			"$set": bson.M{"checklist": checklist, "updated_at": updatedAt, "updated_by": actorFrom(ctx)},
+		}
+		updated, err := n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id, "updated_at": task.UpdatedAt}), update)
+		if err != ErrNotFound {
+			return updated, err
+		}
+		if attempt == maxUpdateAttempts {
+			return nil, ErrConcurrentUpdate
+		}
+	}
+}
+
+// AppendStatus adds an entry to the status history of a task and returns the task
+func (n *MongoTasksStore) AppendStatus(ctx context.Context, id primitive.ObjectID, status *types.Status) (*types.Tasks, error) {
+	now := timestamp()
+	stampStatusHistory([]*types.Status{status}, now)
+	update := bson.M{
+		"$push": bson.M{"statushistory": status},
+		"$set":  bson.M{"current_status": status.Status, "updated_at": now, "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
+}
+
+// Block adds a task to the blockers of another one and returns it
+func (n *MongoTasksStore) Block(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error) {
+	update := bson.M{
+		"$addToSet": bson.M{"blocked_by": blockerId},
+		"$set":      bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
+}
+
+// Unblock removes a task from the blockers of another one and returns it
+func (n *MongoTasksStore) Unblock(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error) {
+	update := bson.M{
+		"$pull": bson.M{"blocked_by": blockerId},
+		"$set":  bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id, "blocked_by": blockerId}), update)
+}
+
+// UnblockAll removes a task from the blockers of every task, including
+// trashed ones
+func (n *MongoTasksStore) UnblockAll(ctx context.Context, blockerId primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$pull": bson.M{"blocked_by": blockerId},
+		"$set":  bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"blocked_by": blockerId}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteDanglingBlockers removes the tasks that no longer exist from the
+// blockers of every task, including trashed ones, and returns how many tasks
+// were changed
+func (n *MongoTasksStore) DeleteDanglingBlockers(ctx context.Context) (int64, error) {
+	blockers, err := n.collection.Distinct(ctx, "blocked_by", bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	if len(blockers) == 0 {
+		return 0, nil
+	}
+	existing, err := n.collection.Distinct(ctx, "_id", bson.M{"_id": bson.M{"$in": blockers}})
+	if err != nil {
+		return 0, err
+	}
+	var dangling []interface{}
+	for _, blocker := range blockers {
+		if !slices.Contains(existing, blocker) {
+			dangling = append(dangling, blocker)
+		}
+	}
+	if len(dangling) == 0 {
+		return 0, nil
+	}
+	update := bson.M{
+		"$pull": bson.M{"blocked_by": bson.M{"$in": dangling}},
+		"$set":  bson.M{"updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	result, err := n.collection.UpdateMany(ctx, bson.M{"blocked_by": bson.M{"$in": dangling}}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListBlocked retrieves the live tasks blocked by a task, oldest first
+func (n *MongoTasksStore) ListBlocked(ctx context.Context, blockerId primitive.ObjectID) ([]*types.Tasks, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := n.collection.Find(ctx, notDeleted(bson.M{"blocked_by": blockerId}), opts)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []*types.Tasks{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// LinkOccurrence records the next occurrence of a recurring task, unless one
+// is already recorded, and returns the task
+func (n *MongoTasksStore) LinkOccurrence(ctx context.Context, id primitive.ObjectID, nextId primitive.ObjectID) (*types.Tasks, error) {
+	filter := notDeleted(bson.M{"_id": id, "recurrence": bson.M{"$ne": nil}, "recurrence.next_id": bson.M{"$exists": false}})
+	update := bson.M{
+		"$set": bson.M{"recurrence.next_id": nextId, "updated_at": timestamp(), "updated_by": actorFrom(ctx)},
+	}
+	return n.findOneAndUpdate(ctx, filter, update)
+}
+
+// ListRecurring retrieves live recurring tasks whose next occurrence is due
+// by before and not created yet, the most overdue first
+func (n *MongoTasksStore) ListRecurring(ctx context.Context, before time.Time, limit int) ([]*types.Tasks, error) {
+	filter := notDeleted(bson.M{"recurrence.next_at": bson.M{"$lte": before}, "recurrence.next_id": bson.M{"$exists": false}})
+	opts := options.Find().SetSort(bson.D{{Key: "recurrence.next_at", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := n.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []*types.Tasks{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// LastRank returns the highest rank among the tasks of a user, trashed ones
+// included, or "" when they have none
+func (n *MongoTasksStore) LastRank(ctx context.Context, userId primitive.ObjectID) (string, error) {
+	var task types.Tasks
+	opts := options.FindOne().SetSort(bson.D{{Key: "rank", Value: -1}}).SetProjection(bson.M{"rank": 1})
+	err := n.collection.FindOne(ctx, bson.M{"user_id": userId}, opts).Decode(&task)
+	if err == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return task.Rank, nil
+}
+
+// SetRank moves a task to another place on the board, and to another column
+// when status is set, and returns the task
+func (n *MongoTasksStore) SetRank(ctx context.Context, id primitive.ObjectID, rank string, status *types.Status) (*types.Tasks, error) {
+	now := timestamp()
+	set := bson.M{"rank": rank, "updated_at": now, "updated_by": actorFrom(ctx)}
+	update := bson.M{"$set": set}
+	if status != nil {
+		stampStatusHistory([]*types.Status{status}, now)
+		set["current_status"] = status.Status
+		update["$push"] = bson.M{"statushistory": status}
+	}
+	return n.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
+}
+
+// taskScope matches the tasks of a user in one of the types.Scope* scopes
+func taskScope(userId primitive.ObjectID, scope string) bson.M {
+	switch scope {
+	case types.ScopeAssigned:
+		return bson.M{"assignees": userId}
+	case types.ScopeCreated:
+		return bson.M{"created_by": userId}
+	}
+	return bson.M{"user_id": userId}
+}
+
+func (n *MongoTasksStore) find(ctx context.Context, filter bson.M) ([]*types.Tasks, error) {
+	cursor, err := n.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*types.Tasks
+	err = cursor.All(ctx, &tasks)
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (n *MongoTasksStore) findOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*types.Tasks, error) {
+	var task *types.Tasks
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := n.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&task)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,655 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"golang-auth/types"
+	"golang-auth/utils"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLTasksStore struct {
+	db *sqlDB
+}
+
+// List retrieves all tasks for a specific user
+func (n *SQLTasksStore) List(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error) {
+	return listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE user_id = ? AND deleted_at IS NULL ORDER BY id", userId.Hex())
+}
+
+// ListPage retrieves one page of a user's tasks matching the query
+func (n *SQLTasksStore) ListPage(ctx context.Context, userId primitive.ObjectID, query *types.ListQuery) ([]*types.Tasks, *types.Pagination, error) {
+	req, err := resolveListQuery(query, taskSortFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	page := &sqlPage{}
+	switch req.Scope {
+	case types.ScopeAssigned:
+		page.add(n.db.dialect.jsonArrayContains("assignees"), userId.Hex())
+	case types.ScopeCreated:
+		page.add(n.db.dialect.jsonText("created_by")+" = ?", userId.Hex())
+	default:
+		page.add("user_id = ?", userId.Hex())
+	}
+	page.add("deleted_at IS NULL")
+	page.createdRange(req)
+	if req.Category != "" {
+		page.add(n.db.dialect.jsonText("category")+" = ?", req.Category)
+	}
+	for _, tag := range req.Tags {
+		page.add(n.db.dialect.jsonArrayContains("tags"), tag.Hex())
+	}
+	if req.Status != "" {
+		page.add(n.db.dialect.jsonText("current_status")+" = ?", req.Status)
+	}
+	if len(req.NotStatuses) > 0 {
+		args := make([]any, len(req.NotStatuses))
+		for i, status := range req.NotStatuses {
+			args[i] = status
+		}
+		page.add(n.db.dialect.jsonText("current_status")+" NOT IN ("+placeholders(len(args))+")", args...)
+	}
+	if req.DueFrom != nil {
+		page.add("due_sort >= ?", sqlTime(*req.DueFrom))
+	}
+	if req.DueTo != nil {
+		page.add("due_sort < ?", sqlTime(*req.DueTo))
+	}
+	return listPage[types.Tasks](ctx, n.db, "tasks", page, req)
+}
+
+// ListByUsers retrieves the tasks of several users with a single query
+func (n *SQLTasksStore) ListByUsers(ctx context.Context, userIds []primitive.ObjectID) ([]*types.Tasks, error) {
+	if len(userIds) == 0 {
+		return []*types.Tasks{}, nil
+	}
+	query := "SELECT data FROM tasks WHERE user_id IN (" + placeholders(len(userIds)) + ") AND deleted_at IS NULL ORDER BY user_id, created_at, id"
+	return listDocs[types.Tasks](ctx, n.db, query, hexIds(userIds)...)
+}
+
+// CountByUsers counts the live tasks of several users with a single query
+func (n *SQLTasksStore) CountByUsers(ctx context.Context, userIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return n.db.countByUsers(ctx, "tasks", userIds)
+}
+
+// ReplaceTag swaps the tag from for to on every task carrying it, or
+// removes it when to is nil
+func (n *SQLTasksStore) ReplaceTag(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	tasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE "+n.db.dialect.jsonArrayContains("tags"), from.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.Tags = retag(task.Tags, from, to)
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// CountByTags counts the user's live tasks per tag
+func (n *SQLTasksStore) CountByTags(ctx context.Context, userId primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return n.db.countByTags(ctx, "tasks", userId)
+}
+
+// Search finds the tasks of the user, or shared with them along with their
+// subtasks, matching any of the terms, best matches first
+func (n *SQLTasksStore) Search(ctx context.Context, userId primitive.ObjectID, shared []primitive.ObjectID, terms []string, query *types.SearchQuery) ([]*types.SearchResult, error) {
+	return searchTable(ctx, n.db, "tasks", userId, shared, terms, query, func(task *types.Tasks, score float64) *types.SearchResult {
+		return &types.SearchResult{Type: "task", Score: score, Task: task}
+	})
+}
+
+// Get retrieves a single task by ID
+func (n *SQLTasksStore) Get(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	var task types.Tasks
+	err := n.db.findDoc(ctx, &task, "SELECT data FROM tasks WHERE id = ? AND deleted_at IS NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetMany retrieves the live tasks with the given IDs; unknown and trashed IDs are skipped
+func (n *SQLTasksStore) GetMany(ctx context.Context, ids []primitive.ObjectID) ([]*types.Tasks, error) {
+	if len(ids) == 0 {
+		return []*types.Tasks{}, nil
+	}
+	query := "SELECT data FROM tasks WHERE id IN (" + placeholders(len(ids)) + ") AND deleted_at IS NULL"
+	return listDocs[types.Tasks](ctx, n.db, query, hexIds(ids)...)
+}
+
+// Create inserts a new task into the database
+func (n *SQLTasksStore) Create(ctx context.Context, task *types.TasksCreate) (*types.Tasks, error) {
+	newTask := types.Tasks{
+		Id:            task.Id,
+		Title:         task.Title,
+		Category:      task.Category,
+		Task:          task.Task,
+		Tags:          idList(task.Tags),
+		UserID:        task.UserID,
+		Assignees:     idList(task.Assignees),
+		ParentID:      task.ParentID,
+		Ancestors:     idList(task.Ancestors),
+		Checklist:     task.Checklist,
+		AutoComplete:  task.AutoComplete,
+		BlockedBy:     idList(task.BlockedBy),
+		Recurrence:    task.Recurrence,
+		Rank:          task.Rank,
+		Priority:      task.Priority,
+		DueAt:         task.DueAt,
+		DueTimezone:   task.DueTimezone,
+		StartAt:       task.StartAt,
+		DueSort:       dueSort(task.DueAt),
+		CreatedAt:     timestamp(),
+		CreatedBy:     actorFrom(ctx),
+		StatusHistory: task.StatusHistory,
+	}
+	if newTask.Id.IsZero() {
+		newTask.Id = primitive.NewObjectID()
+	}
+	newTask.UpdatedAt = newTask.CreatedAt
+	newTask.UpdatedBy = newTask.CreatedBy
+	if newTask.Checklist == nil {
+		newTask.Checklist = []*types.TaskChecklistItem{}
+	}
+	if newTask.Rank == "" {
+		last, err := n.LastRank(ctx, newTask.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if newTask.Rank, err = utils.RankBetween(last, ""); err != nil {
+			return nil, err
+		}
+	}
+	stampStatusHistory(newTask.StatusHistory, newTask.CreatedAt)
+	newTask.CurrentStatus = currentStatus(newTask.StatusHistory)
+
+	data, err := marshalDoc(newTask)
+	if err != nil {
+		return nil, err
+	}
+	_, err = n.db.exec(ctx, "INSERT INTO tasks (id, user_id, priority, due_sort, recur_at, rank, created_at, updated_at, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		newTask.Id.Hex(), newTask.UserID.Hex(), int(newTask.Priority), sqlTime(newTask.DueSort), nullableTime(recurAt(&newTask)), newTask.Rank, sqlTime(newTask.CreatedAt), sqlTime(newTask.UpdatedAt), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newTask, nil
+}
+
+// Delete moves a task and its live subtasks to the trash and returns the task
+func (n *SQLTasksStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	task, err := n.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE deleted_at IS NULL AND "+n.db.dialect.jsonArrayContains("ancestors"), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	deletedAt := timestamp()
+	for _, trashed := range append(subtasks, task) {
+		trashed.DeletedAt = &deletedAt
+		if err := n.save(ctx, trashed); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// Update modifies an existing task based on its ID
+func (n *SQLTasksStore) Update(ctx context.Context, id primitive.ObjectID, updatedData *types.TasksUpdate) (*types.Tasks, error) {
+	task, err := n.modify(ctx, id, func(task *types.Tasks) error {
+		if status := updatedData.Status; status != nil {
+			if task.CurrentStatus != status.PreviousStatus {
+				return ErrConcurrentUpdate
+			}
+			stampStatusHistory([]*types.Status{status}, timestamp())
+			task.StatusHistory = append(task.StatusHistory, status)
+			task.CurrentStatus = currentStatus(task.StatusHistory)
+		}
+		if updatedData.Recurrence != nil || updatedData.ClearRecurrence {
+			if task.Recurrence != nil && task.Recurrence.NextID != nil {
+				return ErrConcurrentUpdate
+			}
+			task.Recurrence = updatedData.Recurrence
+			if updatedData.ClearRecurrence {
+				task.Recurrence = nil
+			}
+		}
+		task.Title = updatedData.Title
+		task.Category = updatedData.Category
+		task.Task = updatedData.Task
+		task.Tags = idList(updatedData.Tags)
+		task.Priority = updatedData.Priority
+		task.DueAt = updatedData.DueAt
+		task.DueTimezone = updatedData.DueTimezone
+		task.StartAt = updatedData.StartAt
+		task.AutoComplete = updatedData.AutoComplete
+		return nil
+	})
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("no task found")
+	}
+	return task, err
+}
+
+// DeleteByUser permanently removes every task owned by the user, including
+// trashed ones, and returns how many were removed
+func (n *SQLTasksStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM tasks WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TransferOwner moves every task owned by fromUserId to toUserId
+func (n *SQLTasksStore) TransferOwner(ctx context.Context, fromUserId primitive.ObjectID, toUserId primitive.ObjectID) (int64, error) {
+	tasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE user_id = ?", fromUserId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.UserID = toUserId
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// ListTrash retrieves the trashed tasks of a user
+func (n *SQLTasksStore) ListTrash(ctx context.Context, userId primitive.ObjectID) ([]*types.Tasks, error) {
+	return listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE user_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC", userId.Hex())
+}
+
+// GetTrashed retrieves a single trashed task by ID
+func (n *SQLTasksStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	var task types.Tasks
+	err := n.db.findDoc(ctx, &task, "SELECT data FROM tasks WHERE id = ? AND deleted_at IS NOT NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Restore takes a task out of the trash, along with the subtasks trashed with
+// it, and returns the task
+func (n *SQLTasksStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	task, err := n.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE deleted_at = ? AND "+n.db.dialect.jsonArrayContains("ancestors"),
+		sqlTime(*task.DeletedAt), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	for _, restored := range append(subtasks, task) {
+		restored.DeletedAt = nil
+		if err := n.save(ctx, restored); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// Purge permanently removes a trashed task and its subtasks and returns the task
+func (n *SQLTasksStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.Tasks, error) {
+	task, err := n.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = n.db.exec(ctx, "DELETE FROM tasks WHERE id = ? OR "+n.db.dialect.jsonArrayContains("ancestors"), id.Hex(), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TrashByUser moves every live task of the user to the trash with the given timestamp
+func (n *SQLTasksStore) TrashByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	tasks, err := n.List(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.DeletedAt = &deletedAt
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// RestoreByUser restores the tasks of the user that were trashed at deletedAt
+func (n *SQLTasksStore) RestoreByUser(ctx context.Context, userId primitive.ObjectID, deletedAt time.Time) (int64, error) {
+	tasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE user_id = ? AND deleted_at = ?", userId.Hex(), sqlTime(deletedAt))
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.DeletedAt = nil
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// PurgeDeletedBefore permanently removes tasks trashed before the given time
+func (n *SQLTasksStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := n.db.exec(ctx, "DELETE FROM tasks WHERE deleted_at < ?", sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Assign adds a user to the assignees of a task and returns it
+func (n *SQLTasksStore) Assign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		if !task.HasAssignee(userId) {
+			task.Assignees = append(task.Assignees, userId)
+		}
+		return nil
+	})
+}
+
+// Unassign removes a user from the assignees of a task and returns it
+func (n *SQLTasksStore) Unassign(ctx context.Context, id primitive.ObjectID, userId primitive.ObjectID) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		if !task.HasAssignee(userId) {
+			return ErrNotFound
+		}
+		task.Assignees = slices.DeleteFunc(task.Assignees, func(assignee primitive.ObjectID) bool { return assignee == userId })
+		return nil
+	})
+}
+
+// UnassignUser removes a user from the assignees of every task, including
+// trashed ones
+func (n *SQLTasksStore) UnassignUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	tasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE "+n.db.dialect.jsonArrayContains("assignees"), userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.Assignees = slices.DeleteFunc(task.Assignees, func(assignee primitive.ObjectID) bool { return assignee == userId })
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// ListDescendants retrieves the subtasks of a task at every depth, including
+// trashed ones, oldest first
+func (n *SQLTasksStore) ListDescendants(ctx context.Context, id primitive.ObjectID) ([]*types.Tasks, error) {
+	query := "SELECT data FROM tasks WHERE " + n.db.dialect.jsonArrayContains("ancestors") + " ORDER BY created_at, id"
+	return listDocs[types.Tasks](ctx, n.db, query, id.Hex())
+}
+
+// Move places a task under the last of ancestors, or at the top level when
+// there are none, rewrites the ancestors of its subtasks and returns it
+func (n *SQLTasksStore) Move(ctx context.Context, id primitive.ObjectID, ancestors []primitive.ObjectID) (*types.Tasks, error) {
+	task, err := n.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := n.ListDescendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Ancestors = idList(ancestors)
+	task.ParentID = nil
+	if len(ancestors) > 0 {
+		task.ParentID = &ancestors[len(ancestors)-1]
+	}
+	task.UpdatedAt = timestamp()
+	task.UpdatedBy = actorFrom(ctx)
+	if err := n.save(ctx, task); err != nil {
+		return nil, err
+	}
+
+	// Subtasks keep the part of their ancestors below the task
+	path := slices.Concat(ancestors, []primitive.ObjectID{id})
+	for _, subtask := range subtasks {
+		below := subtask.Ancestors[slices.Index(subtask.Ancestors, id)+1:]
+		subtask.Ancestors = slices.Concat(path, below)
+		if err := n.save(ctx, subtask); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// UpdateChecklist replaces the checklist of a task with the one change makes
+// of it and returns the task
+func (n *SQLTasksStore) UpdateChecklist(ctx context.Context, id primitive.ObjectID, change func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error)) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		checklist, err := change(task.Checklist)
+		if err != nil {
+			return err
+		}
+		task.Checklist = checklist
+		return nil
+	})
+}
+
+// AppendStatus adds an entry to the status history of a task and returns the task
+func (n *SQLTasksStore) AppendStatus(ctx context.Context, id primitive.ObjectID, status *types.Status) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		task.StatusHistory = append(task.StatusHistory, status)
+		stampStatusHistory(task.StatusHistory, timestamp())
+		task.CurrentStatus = currentStatus(task.StatusHistory)
+		return nil
+	})
+}
+
+// Block adds a task to the blockers of another one and returns it
+func (n *SQLTasksStore) Block(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		if !slices.Contains(task.BlockedBy, blockerId) {
+			task.BlockedBy = append(task.BlockedBy, blockerId)
+		}
+		return nil
+	})
+}
+
+// Unblock removes a task from the blockers of another one and returns it
+func (n *SQLTasksStore) Unblock(ctx context.Context, id primitive.ObjectID, blockerId primitive.ObjectID) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		if !slices.Contains(task.BlockedBy, blockerId) {
+			return ErrNotFound
+		}
+		task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(blocker primitive.ObjectID) bool { return blocker == blockerId })
+		return nil
+	})
+}
+
+// UnblockAll removes a task from the blockers of every task, including
+// trashed ones
+func (n *SQLTasksStore) UnblockAll(ctx context.Context, blockerId primitive.ObjectID) (int64, error) {
+	tasks, err := listDocs[types.Tasks](ctx, n.db, "SELECT data FROM tasks WHERE "+n.db.dialect.jsonArrayContains("blocked_by"), blockerId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(blocker primitive.ObjectID) bool { return blocker == blockerId })
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), nil
+}
+
+// DeleteDanglingBlockers removes the tasks that no longer exist from the
+// blockers of every task, including trashed ones, and returns how many tasks
+// were changed
+func (n *SQLTasksStore) DeleteDanglingBlockers(ctx context.Context) (int64, error) {
+	query := "SELECT tasks.id, blocker.value FROM tasks, json_each(tasks.data, '$.blocked_by') AS blocker"
+	if n.db.dialect == dialectPostgres {
+		query = "SELECT tasks.id, blocker.value FROM tasks, jsonb_array_elements_text(tasks.data::jsonb -> 'blocked_by') AS blocker(value)"
+	}
+	query += " WHERE NOT EXISTS (SELECT 1 FROM tasks AS t WHERE t.id = blocker.value)"
+	rows, err := n.db.query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	dangling := map[string][]primitive.ObjectID{}
+	for rows.Next() {
+		var taskId, blockerId string
+		if err := rows.Scan(&taskId, &blockerId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		id, err := primitive.ObjectIDFromHex(blockerId)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dangling[taskId] = append(dangling[taskId], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for taskId, blockers := range dangling {
+		var task types.Tasks
+		if err := n.db.getDoc(ctx, "tasks", taskId, &task); err != nil {
+			return 0, err
+		}
+		task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(blocker primitive.ObjectID) bool { return slices.Contains(blockers, blocker) })
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		if err := n.save(ctx, &task); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(dangling)), nil
+}
+
+// ListBlocked retrieves the live tasks blocked by a task, oldest first
+func (n *SQLTasksStore) ListBlocked(ctx context.Context, blockerId primitive.ObjectID) ([]*types.Tasks, error) {
+	query := "SELECT data FROM tasks WHERE deleted_at IS NULL AND " + n.db.dialect.jsonArrayContains("blocked_by") + " ORDER BY created_at, id"
+	return listDocs[types.Tasks](ctx, n.db, query, blockerId.Hex())
+}
+
+// LinkOccurrence records the next occurrence of a recurring task, unless one
+// is already recorded, and returns the task
+func (n *SQLTasksStore) LinkOccurrence(ctx context.Context, id primitive.ObjectID, nextId primitive.ObjectID) (*types.Tasks, error) {
+	task, err := n.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Recurrence == nil || task.Recurrence.NextID != nil {
+		return nil, ErrNotFound
+	}
+	task.Recurrence.NextID = &nextId
+	task.UpdatedAt = timestamp()
+	task.UpdatedBy = actorFrom(ctx)
+	if err := n.save(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// ListRecurring retrieves live recurring tasks whose next occurrence is due
+// by before and not created yet, the most overdue first
+func (n *SQLTasksStore) ListRecurring(ctx context.Context, before time.Time, limit int) ([]*types.Tasks, error) {
+	query := "SELECT data FROM tasks WHERE deleted_at IS NULL AND recur_at <= ? ORDER BY recur_at, id LIMIT ?"
+	return listDocs[types.Tasks](ctx, n.db, query, sqlTime(before), limit)
+}
+
+// LastRank returns the highest rank among the tasks of a user, trashed ones
+// included, or "" when they have none
+func (n *SQLTasksStore) LastRank(ctx context.Context, userId primitive.ObjectID) (string, error) {
+	var rank sql.NullString
+	if err := n.db.queryRow(ctx, "SELECT MAX(rank) FROM tasks WHERE user_id = ?", userId.Hex()).Scan(&rank); err != nil {
+		return "", err
+	}
+	return rank.String, nil
+}
+
+// SetRank moves a task to another place on the board, and to another column
+// when status is set, and returns the task
+func (n *SQLTasksStore) SetRank(ctx context.Context, id primitive.ObjectID, rank string, status *types.Status) (*types.Tasks, error) {
+	return n.modify(ctx, id, func(task *types.Tasks) error {
+		task.Rank = rank
+		if status != nil {
+			task.StatusHistory = append(task.StatusHistory, status)
+			stampStatusHistory(task.StatusHistory, timestamp())
+			task.CurrentStatus = currentStatus(task.StatusHistory)
+		}
+		return nil
+	})
+}
+
+// modify applies change to a live task and saves it in one transaction,
+// locking the row so that concurrent changes apply one after the other
+// instead of overwriting each other. An error from change leaves the task
+// as it was.
+func (n *SQLTasksStore) modify(ctx context.Context, id primitive.ObjectID, change func(task *types.Tasks) error) (*types.Tasks, error) {
+	var task types.Tasks
+	err := n.db.withTransaction(ctx, func(ctx context.Context) error {
+		query := "SELECT data FROM tasks WHERE id = ? AND deleted_at IS NULL" + n.db.dialect.forUpdate()
+		if err := n.db.findDoc(ctx, &task, query, id.Hex()); err != nil {
+			return err
+		}
+		if err := change(&task); err != nil {
+			return err
+		}
+		task.UpdatedAt = timestamp()
+		task.UpdatedBy = actorFrom(ctx)
+		return n.save(ctx, &task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// save writes the task back, keeping the indexed columns in sync with the document
+func (n *SQLTasksStore) save(ctx context.Context, task *types.Tasks) error {
+	// Progress, Blocked, CommentCount and TimeSpent are computed on reads and
+	// never stored
+	doc := *task
+	doc.Progress = nil
+	doc.Blocked = false
+	doc.CommentCount = nil
+	doc.TimeSpent = nil
+	data, err := marshalDoc(doc)
+	if err != nil {
+		return err
+	}
+	_, err = n.db.exec(ctx, "UPDATE tasks SET user_id = ?, priority = ?, due_sort = ?, recur_at = ?, rank = ?, deleted_at = ?, updated_at = ?, data = ? WHERE id = ?",
+		task.UserID.Hex(), int(task.Priority), sqlTime(dueSort(task.DueAt)), nullableTime(recurAt(task)), task.Rank, nullableTime(task.DeletedAt), sqlTime(task.UpdatedAt), data, task.Id.Hex())
+	return err
+}
+
+// recurAt is when the next occurrence of a task is due to be created, nil
+// when it does not recur or the occurrence already exists
+func recurAt(task *types.Tasks) *time.Time {
+	if task.Recurrence == nil || task.Recurrence.NextID != nil {
+		return nil
+	}
+	return task.Recurrence.NextAt
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-auth/types"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// concurrently runs fn n times at once and reports the errors it returns.
+func concurrently(t *testing.T, n int, fn func(i int) error) {
+	t.Helper()
+	// Run the goroutines in parallel even on a single CPU, which is what
+	// makes lost updates show up
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(i); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestUpdateChecklistConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	task := createTestTask(t, store, user.Id, "Pack")
+
+	concurrently(t, 20, func(i int) error {
+		_, err := store.Tasks.UpdateChecklist(ctx, task.Id, func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error) {
+			item := &types.TaskChecklistItem{Id: primitive.NewObjectID(), Text: fmt.Sprintf("Item %d", i)}
+			return append(slices.Clone(checklist), item), nil
+		})
+		return err
+	})
+	updated, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Checklist) != 20 {
+		t.Errorf("checklist has %d items, want 20", len(updated.Checklist))
+	}
+
+	refused := errors.New("refused")
+	_, err = store.Tasks.UpdateChecklist(ctx, task.Id, func(checklist []*types.TaskChecklistItem) ([]*types.TaskChecklistItem, error) {
+		return nil, refused
+	})
+	if err != refused {
+		t.Errorf("UpdateChecklist returned %v, want the error of the change", err)
+	}
+	unchanged, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unchanged.Checklist) != 20 || !unchanged.UpdatedAt.Equal(updated.UpdatedAt) {
+		t.Error("a refused change modified the task")
+	}
+}
+
+func TestAppendStatusConcurrently(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	task := createTestTask(t, store, user.Id, "Review")
+
+	concurrently(t, 20, func(i int) error {
+		_, err := store.Tasks.AppendStatus(ctx, task.Id, &types.Status{Status: fmt.Sprintf("step %d", i), UserId: user.Id.Hex()})
+		return err
+	})
+	updated, err := store.Tasks.Get(ctx, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The initial status and one entry per append
+	if len(updated.StatusHistory) != 21 {
+		t.Errorf("status history has %d entries, want 21", len(updated.StatusHistory))
+	}
+	if last := updated.StatusHistory[len(updated.StatusHistory)-1]; updated.CurrentStatus != last.Status {
+		t.Errorf("current status is %q, want the last entry %q", updated.CurrentStatus, last.Status)
+	}
+}
+
+// TestUpdateKeepsConcurrentChanges prepares updates from a task as read
+// before other changes land, the way the API does.
+func TestUpdateKeepsConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	user := createTestUser(t, store, "owner")
+	status := func(status string, previous string) *types.Status {
+		return &types.Status{Status: status, PreviousStatus: previous, UserId: user.Id.Hex()}
+	}
+
+	t.Run("status", func(t *testing.T) {
+		task := createTestTask(t, store, user.Id, "Write")
+		if _, err := store.Tasks.AppendStatus(ctx, task.Id, status("done", "todo")); err != nil {
+			t.Fatal(err)
+		}
+		_, err := store.Tasks.Update(ctx, task.Id, &types.TasksUpdate{Title: "Write", Status: status("in_progress", "todo")})
+		if err != ErrConcurrentUpdate {
+			t.Errorf("moving a task out of a status it left returned %v, want ErrConcurrentUpdate", err)
+		}
+		updated, err := store.Tasks.Update(ctx, task.Id, &types.TasksUpdate{Title: "Rewrite"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Title != "Rewrite" || updated.CurrentStatus != "done" || len(updated.StatusHistory) != 2 {
+			t.Errorf("task is %q in %q with %d statuses, want Rewrite in done with 2", updated.Title, updated.CurrentStatus, len(updated.StatusHistory))
+		}
+		moved, err := store.Tasks.Update(ctx, task.Id, &types.TasksUpdate{Title: "Rewrite", Status: status("todo", "done")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moved.CurrentStatus != "todo" || len(moved.StatusHistory) != 3 {
+			t.Errorf("reopened task is in %q with %d statuses, want todo with 3", moved.CurrentStatus, len(moved.StatusHistory))
+		}
+	})
+
+	t.Run("recurrence", func(t *testing.T) {
+		at := store.Now().Add(-time.Hour)
+		task := createRecurringTask(t, store, user.Id, "FREQ=DAILY", at)
+		next := createTestTask(t, store, user.Id, "Next")
+		if _, err := store.Tasks.LinkOccurrence(ctx, task.Id, next.Id); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := store.Tasks.Update(ctx, task.Id, &types.TasksUpdate{Title: "Water the ferns"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Recurrence == nil || updated.Recurrence.NextID == nil || *updated.Recurrence.NextID != next.Id {
+			t.Errorf("updating the title lost the next occurrence: %+v", updated.Recurrence)
+		}
+		if _, err := store.Tasks.Update(ctx, task.Id, &types.TasksUpdate{ClearRecurrence: true}); err != ErrConcurrentUpdate {
+			t.Errorf("stopping a series after its next occurrence returned %v, want ErrConcurrentUpdate", err)
+		}
+
+		other := createRecurringTask(t, store, user.Id, "FREQ=DAILY", at)
+		stopped, err := store.Tasks.Update(ctx, other.Id, &types.TasksUpdate{Title: "Once", ClearRecurrence: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stopped.Recurrence != nil {
+			t.Errorf("stopped series still recurs: %+v", stopped.Recurrence)
+		}
+	})
+}
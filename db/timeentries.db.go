@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoTimeEntriesStore struct {
+	collection *mongo.Collection
+}
+
+// Create inserts a new time entry and returns it
+func (t *MongoTimeEntriesStore) Create(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error) {
+	newEntry := *entry
+	newEntry.Id = primitive.NewObjectID()
+	newEntry.CreatedAt = timestamp()
+	newEntry.UpdatedAt = newEntry.CreatedAt
+	_, err := t.collection.InsertOne(ctx, newEntry)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrTimerRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &newEntry, nil
+}
+
+// Get retrieves a time entry of a task
+func (t *MongoTimeEntriesStore) Get(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.collection.FindOne(ctx, bson.M{"_id": id, "task_id": taskId}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetRunning retrieves the running timer of a user
+func (t *MongoTimeEntriesStore) GetRunning(ctx context.Context, userId primitive.ObjectID) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.collection.FindOne(ctx, bson.M{"user_id": userId, "running": true}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Stop stops a running timer at endedAt, replacing its note when one is
+// given, and returns it
+func (t *MongoTimeEntriesStore) Stop(ctx context.Context, id primitive.ObjectID, endedAt time.Time, note *string) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.collection.FindOne(ctx, bson.M{"_id": id, "running": true}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{
+		"ended_at":   endedAt,
+		"running":    false,
+		"duration":   entryDuration(entry.StartedAt, endedAt),
+		"updated_at": timestamp(),
+	}
+	if note != nil {
+		set["note"] = *note
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var stopped types.TimeEntry
+	err = t.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "running": true}, bson.M{"$set": set}, opts).Decode(&stopped)
+	if err != nil {
+		return nil, err
+	}
+	return &stopped, nil
+}
+
+// Update saves the times and note of a time entry and returns it
+func (t *MongoTimeEntriesStore) Update(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error) {
+	set := bson.M{
+		"started_at": entry.StartedAt,
+		"ended_at":   entry.EndedAt,
+		"duration":   entry.Duration,
+		"note":       entry.Note,
+		"updated_at": timestamp(),
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated types.TimeEntry
+	err := t.collection.FindOneAndUpdate(ctx, bson.M{"_id": entry.Id, "task_id": entry.TaskID}, bson.M{"$set": set}, opts).Decode(&updated)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete removes a time entry of a task and returns it
+func (t *MongoTimeEntriesStore) Delete(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "task_id": taskId}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListByTask retrieves the time entries of a task, oldest first
+func (t *MongoTimeEntriesStore) ListByTask(ctx context.Context, taskId primitive.ObjectID) ([]*types.TimeEntry, error) {
+	return t.find(ctx, bson.M{"task_id": taskId})
+}
+
+// ListEnded retrieves the entries that ended and started in [from, to), on
+// one of the given tasks or spent by the given user, oldest first
+func (t *MongoTimeEntriesStore) ListEnded(ctx context.Context, taskIds []primitive.ObjectID, userId primitive.ObjectID, from time.Time, to time.Time) ([]*types.TimeEntry, error) {
+	return t.find(ctx, bson.M{
+		"running":    false,
+		"started_at": bson.M{"$gte": from, "$lt": to},
+		"$or":        bson.A{bson.M{"task_id": bson.M{"$in": idList(taskIds)}}, bson.M{"user_id": userId}},
+	})
+}
+
+// TotalByTasks adds up the time entries that ended, per task
+func (t *MongoTimeEntriesStore) TotalByTasks(ctx context.Context, taskIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	totals := map[primitive.ObjectID]int64{}
+	if len(taskIds) == 0 {
+		return totals, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"task_id": bson.M{"$in": taskIds}, "running": false}}},
+		{{Key: "$group", Value: bson.M{"_id": "$task_id", "total": bson.M{"$sum": "$duration"}}}},
+	}
+	cursor, err := t.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskId primitive.ObjectID `bson:"_id"`
+		Total  int64              `bson:"total"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		totals[row.TaskId] = row.Total
+	}
+	return totals, nil
+}
+
+// DeleteByTask removes every time entry of a task
+func (t *MongoTimeEntriesStore) DeleteByTask(ctx context.Context, taskId primitive.ObjectID) (int64, error) {
+	result, err := t.collection.DeleteMany(ctx, bson.M{"task_id": taskId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByUser removes every time entry of a user
+func (t *MongoTimeEntriesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := t.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteOrphans removes the time entries of tasks that no longer exist
+func (t *MongoTimeEntriesStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$task_id"}}},
+		{{Key: "$lookup", Value: bson.M{"from": "task", "localField": "_id", "foreignField": "_id", "as": "task"}}},
+		{{Key: "$match", Value: bson.M{"task": bson.M{"$size": 0}}}},
+	}
+	cursor, err := t.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var orphans []struct {
+		TaskId primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &orphans); err != nil {
+		return 0, err
+	}
+	if len(orphans) == 0 {
+		return 0, nil
+	}
+	taskIds := make([]primitive.ObjectID, len(orphans))
+	for i, orphan := range orphans {
+		taskIds[i] = orphan.TaskId
+	}
+	result, err := t.collection.DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": taskIds}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (t *MongoTimeEntriesStore) find(ctx context.Context, filter bson.M) ([]*types.TimeEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := t.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*types.TimeEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
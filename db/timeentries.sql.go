@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLTimeEntriesStore struct {
+	db *sqlDB
+}
+
+// Create inserts a new time entry and returns it
+func (t *SQLTimeEntriesStore) Create(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error) {
+	newEntry := *entry
+	newEntry.Id = primitive.NewObjectID()
+	newEntry.CreatedAt = timestamp()
+	newEntry.UpdatedAt = newEntry.CreatedAt
+	data, err := marshalDoc(newEntry)
+	if err != nil {
+		return nil, err
+	}
+	_, err = t.db.exec(ctx, "INSERT INTO time_entries (id, task_id, user_id, started_at, ended_at, duration, data) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		newEntry.Id.Hex(), newEntry.TaskID.Hex(), newEntry.UserID.Hex(), sqlTime(newEntry.StartedAt), nullableTime(newEntry.EndedAt), newEntry.Duration, data)
+	if isUniqueViolation(err) {
+		return nil, ErrTimerRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &newEntry, nil
+}
+
+// Get retrieves a time entry of a task
+func (t *SQLTimeEntriesStore) Get(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.db.findDoc(ctx, &entry, "SELECT data FROM time_entries WHERE id = ? AND task_id = ?", id.Hex(), taskId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetRunning retrieves the running timer of a user
+func (t *SQLTimeEntriesStore) GetRunning(ctx context.Context, userId primitive.ObjectID) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.db.findDoc(ctx, &entry, "SELECT data FROM time_entries WHERE user_id = ? AND ended_at IS NULL", userId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Stop stops a running timer at endedAt, replacing its note when one is
+// given, and returns it
+func (t *SQLTimeEntriesStore) Stop(ctx context.Context, id primitive.ObjectID, endedAt time.Time, note *string) (*types.TimeEntry, error) {
+	var entry types.TimeEntry
+	err := t.db.findDoc(ctx, &entry, "SELECT data FROM time_entries WHERE id = ? AND ended_at IS NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	entry.EndedAt = &endedAt
+	entry.Running = false
+	entry.Duration = entryDuration(entry.StartedAt, endedAt)
+	if note != nil {
+		entry.Note = *note
+	}
+	if err := t.save(ctx, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Update saves the times and note of a time entry and returns it
+func (t *SQLTimeEntriesStore) Update(ctx context.Context, entry *types.TimeEntry) (*types.TimeEntry, error) {
+	updated, err := t.Get(ctx, entry.TaskID, entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	updated.StartedAt = entry.StartedAt
+	updated.EndedAt = entry.EndedAt
+	updated.Duration = entry.Duration
+	updated.Note = entry.Note
+	if err := t.save(ctx, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete removes a time entry of a task and returns it
+func (t *SQLTimeEntriesStore) Delete(ctx context.Context, taskId primitive.ObjectID, id primitive.ObjectID) (*types.TimeEntry, error) {
+	entry, err := t.Get(ctx, taskId, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.db.exec(ctx, "DELETE FROM time_entries WHERE id = ?", entry.Id.Hex()); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListByTask retrieves the time entries of a task, oldest first
+func (t *SQLTimeEntriesStore) ListByTask(ctx context.Context, taskId primitive.ObjectID) ([]*types.TimeEntry, error) {
+	return listDocs[types.TimeEntry](ctx, t.db, "SELECT data FROM time_entries WHERE task_id = ? ORDER BY started_at, id", taskId.Hex())
+}
+
+// ListEnded retrieves the entries that ended and started in [from, to), on
+// one of the given tasks or spent by the given user, oldest first
+func (t *SQLTimeEntriesStore) ListEnded(ctx context.Context, taskIds []primitive.ObjectID, userId primitive.ObjectID, from time.Time, to time.Time) ([]*types.TimeEntry, error) {
+	query := "SELECT data FROM time_entries WHERE ended_at IS NOT NULL AND started_at >= ? AND started_at < ? AND (user_id = ?"
+	args := []any{sqlTime(from), sqlTime(to), userId.Hex()}
+	if len(taskIds) > 0 {
+		query += " OR task_id IN (" + placeholders(len(taskIds)) + ")"
+		args = append(args, hexIds(taskIds)...)
+	}
+	return listDocs[types.TimeEntry](ctx, t.db, query+") ORDER BY started_at, id", args...)
+}
+
+// TotalByTasks adds up the time entries that ended, per task
+func (t *SQLTimeEntriesStore) TotalByTasks(ctx context.Context, taskIds []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	totals := map[primitive.ObjectID]int64{}
+	if len(taskIds) == 0 {
+		return totals, nil
+	}
+	rows, err := t.db.query(ctx, "SELECT task_id, SUM(duration) FROM time_entries WHERE task_id IN ("+placeholders(len(taskIds))+") AND ended_at IS NOT NULL GROUP BY task_id", hexIds(taskIds)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskId string
+		var total int64
+		if err := rows.Scan(&taskId, &total); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(taskId)
+		if err != nil {
+			return nil, err
+		}
+		totals[id] = total
+	}
+	return totals, rows.Err()
+}
+
+// DeleteByTask removes every time entry of a task
+func (t *SQLTimeEntriesStore) DeleteByTask(ctx context.Context, taskId primitive.ObjectID) (int64, error) {
+	result, err := t.db.exec(ctx, "DELETE FROM time_entries WHERE task_id = ?", taskId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByUser removes every time entry of a user
+func (t *SQLTimeEntriesStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := t.db.exec(ctx, "DELETE FROM time_entries WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOrphans removes the time entries of tasks that no longer exist
+func (t *SQLTimeEntriesStore) DeleteOrphans(ctx context.Context) (int64, error) {
+	result, err := t.db.exec(ctx, "DELETE FROM time_entries WHERE task_id NOT IN (SELECT id FROM tasks)")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// save writes the time entry back, keeping the indexed columns in sync with the document
+func (t *SQLTimeEntriesStore) save(ctx context.Context, entry *types.TimeEntry) error {
+	entry.UpdatedAt = timestamp()
+	data, err := marshalDoc(entry)
+	if err != nil {
+		return err
+	}
+	_, err = t.db.exec(ctx, "UPDATE time_entries SET started_at = ?, ended_at = ?, duration = ?, data = ? WHERE id = ?",
+		sqlTime(entry.StartedAt), nullableTime(entry.EndedAt), entry.Duration, data, entry.Id.Hex())
+	return err
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"golang-auth/types"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTimerRunning is returned when starting a timer for a user who already
+// has one running.
+var ErrTimerRunning = errors.New("a timer is already running; stop it first")
+
+// StartTimer starts timing the work of a user on a task.
+func (s *Store) StartTimer(ctx context.Context, task *types.Tasks, userId primitive.ObjectID, note string) (*types.TimeEntry, error) {
+	return s.TimeEntries.Create(ctx, &types.TimeEntry{
+		TaskID:    task.Id,
+		UserID:    userId,
+		StartedAt: s.Now(),
+		Running:   true,
+		Note:      note,
+	})
+}
+
+// StopTimer stops the running timer of a user. When taskId is set the timer
+// must be running on that task.
+func (s *Store) StopTimer(ctx context.Context, userId primitive.ObjectID, taskId *primitive.ObjectID, note *string) (*types.TimeEntry, error) {
+	running, err := s.TimeEntries.GetRunning(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if taskId != nil && running.TaskID != *taskId {
+		return nil, ErrNotFound
+	}
+	return s.TimeEntries.Stop(ctx, running.Id, s.Now(), note)
+}
+
+// TaskTime retrieves the time entries of a task and their total.
+func (s *Store) TaskTime(ctx context.Context, taskId primitive.ObjectID) (*types.TaskTime, error) {
+	entries, err := s.TimeEntries.ListByTask(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	taskTime := &types.TaskTime{Entries: entries}
+	for _, entry := range entries {
+		if !entry.Running {
+			taskTime.Total += entry.Duration
+		}
+	}
+	return taskTime, nil
+}
+
+// LoadTimeSpent sets the time tracked on each task by the entries that ended.
+func (s *Store) LoadTimeSpent(ctx context.Context, tasks ...*types.Tasks) error {
+	ids := make([]primitive.ObjectID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	totals, err := s.TimeEntries.TotalByTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		total := totals[task.Id]
+		task.TimeSpent = &total
+	}
+	return nil
+}
+
+// TimeReport adds up, per user and category, the time spent on the user's
+// live tasks and on the tasks shared with them, along with the time the user
+// spent on other live tasks. Running timers are left out.
+func (s *Store) TimeReport(ctx context.Context, userId primitive.ObjectID, query *types.TimeReportQuery) (*types.TimeReport, error) {
+	tasks, err := s.Tasks.List(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := s.SharedWith(ctx, userId, types.ShareTask)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shared {
+		if item.Task != nil {
+			tasks = append(tasks, item.Task)
+		}
+	}
+	categories := map[primitive.ObjectID]string{}
+	taskIds := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		categories[task.Id] = task.Category
+		taskIds = append(taskIds, task.Id)
+	}
+
+	entries, err := s.TimeEntries.ListEnded(ctx, taskIds, userId, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	// The entries of the user on tasks of others need those tasks' categories
+	var others []primitive.ObjectID
+	for _, entry := range entries {
+		if _, ok := categories[entry.TaskID]; !ok {
+			others = append(others, entry.TaskID)
+		}
+	}
+	if len(others) > 0 {
+		found, err := s.Tasks.GetMany(ctx, others)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range found {
+			categories[task.Id] = task.Category
+		}
+	}
+
+	type rowKey struct {
+		userId   primitive.ObjectID
+		category string
+	}
+	report := &types.TimeReport{From: query.From, To: query.To, Rows: []*types.TimeReportRow{}}
+	rows := map[rowKey]*types.TimeReportRow{}
+	for _, entry := range entries {
+		category, ok := categories[entry.TaskID]
+		if !ok {
+			continue
+		}
+		if query.UserID != nil && entry.UserID != *query.UserID || query.Category != nil && category != *query.Category {
+			continue
+		}
+		key := rowKey{entry.UserID, category}
+		row, ok := rows[key]
+		if !ok {
+			row = &types.TimeReportRow{UserID: entry.UserID, Category: category}
+			rows[key] = row
+			report.Rows = append(report.Rows, row)
+		}
+		row.Total += entry.Duration
+		row.Entries++
+		report.Total += entry.Duration
+		report.Entries++
+	}
+
+	users := map[primitive.ObjectID]*types.UserResponse{}
+	for _, row := range report.Rows {
+		user, ok := users[row.UserID]
+		if !ok {
+			user, err = s.User.Get(ctx, row.UserID)
+			if err != nil && err != ErrNotFound {
+				return nil, err
+			}
+			users[row.UserID] = user
+		}
+		if user != nil {
+			row.UserName, row.UserEmail = user.Name, user.Email
+		}
+		row.Hours = hours(row.Total)
+	}
+	report.Hours = hours(report.Total)
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.UserName != b.UserName {
+			return a.UserName < b.UserName
+		}
+		if a.UserID != b.UserID {
+			return a.UserID.Hex() < b.UserID.Hex()
+		}
+		return a.Category < b.Category
+	})
+	return report, nil
+}
+
+// entryDuration is the time between start and end in whole seconds, counting
+// negative spans as 0.
+func entryDuration(start time.Time, end time.Time) int64 {
+	return *seconds(end.Sub(start))
+}
+
+// hours converts seconds to hours rounded to hundredths.
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
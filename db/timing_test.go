@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTimerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	now := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	setTestClock(store, &now)
+	user := createTestUser(t, store, "owner")
+	writing := createTestTask(t, store, user.Id, "Write")
+	review := createTestTask(t, store, user.Id, "Review")
+
+	if _, err := store.StopTimer(ctx, user.Id, nil, nil); err != ErrNotFound {
+		t.Errorf("stopping without a running timer returned %v, want ErrNotFound", err)
+	}
+	started, err := store.StartTimer(ctx, writing, user.Id, "draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !started.Running || !started.StartedAt.Equal(now) {
+		t.Errorf("started timer is %+v, want running since %s", started, now)
+	}
+	if _, err := store.StartTimer(ctx, review, user.Id, ""); err != ErrTimerRunning {
+		t.Errorf("starting a second timer returned %v, want ErrTimerRunning", err)
+	}
+	// Another user can time the same task meanwhile
+	other := createTestUser(t, store, "other")
+	if _, err := store.StartTimer(ctx, writing, other.Id, ""); err != nil {
+		t.Errorf("starting the timer of another user returned %v", err)
+	}
+
+	now = now.Add(90 * time.Minute)
+	if _, err := store.StopTimer(ctx, user.Id, &review.Id, nil); err != ErrNotFound {
+		t.Errorf("stopping the timer of another task returned %v, want ErrNotFound", err)
+	}
+	note := "first draft"
+	stopped, err := store.StopTimer(ctx, user.Id, &writing.Id, &note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Running || stopped.EndedAt == nil || !stopped.EndedAt.Equal(now) || stopped.Duration != 90*60 || stopped.Note != note {
+		t.Errorf("stopped timer is %+v, want 5400 seconds ending at %s with note %q", stopped, now, note)
+	}
+	if _, err := store.StopTimer(ctx, user.Id, nil, nil); err != ErrNotFound {
+		t.Errorf("stopping a stopped timer returned %v, want ErrNotFound", err)
+	}
+
+	// The running timer of the other user does not count yet
+	taskTime, err := store.TaskTime(ctx, writing.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskTime.Total != 90*60 || len(taskTime.Entries) != 2 {
+		t.Errorf("task time is %d seconds in %d entries, want 5400 in 2", taskTime.Total, len(taskTime.Entries))
+	}
+	if _, err := store.StartTimer(ctx, review, user.Id, ""); err != nil {
+		t.Errorf("starting a timer after stopping one returned %v", err)
+	}
+}
+
+// createTestCategoryTask adds a task of category to the board of userId.
+func createTestCategoryTask(t *testing.T, store *Store, userId primitive.ObjectID, category string) *types.Tasks {
+	t.Helper()
+	task, err := store.Tasks.Create(context.Background(), &types.TasksCreate{
+		Title:         category + " task",
+		Category:      category,
+		UserID:        userId,
+		StatusHistory: []*types.Status{{Status: "todo", UserId: userId.Hex()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// addTestTimeEntry records that userId spent minutes on task from start.
+func addTestTimeEntry(t *testing.T, store *Store, task *types.Tasks, userId primitive.ObjectID, start time.Time, minutes int) {
+	t.Helper()
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	if _, err := store.TimeEntries.Create(context.Background(), &types.TimeEntry{
+		TaskID:    task.Id,
+		UserID:    userId,
+		StartedAt: start,
+		EndedAt:   &end,
+		Duration:  entryDuration(start, end),
+		Manual:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTimeReport(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	owner := createTestUser(t, store, "owner")
+	helper := createTestUser(t, store, "helper")
+	work := createTestCategoryTask(t, store, owner.Id, "work")
+	home := createTestCategoryTask(t, store, owner.Id, "home")
+	elsewhere := createTestCategoryTask(t, store, helper.Id, "garden")
+
+	day := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	addTestTimeEntry(t, store, work, owner.Id, day, 60)
+	addTestTimeEntry(t, store, work, owner.Id, day.Add(2*time.Hour), 30)
+	addTestTimeEntry(t, store, home, owner.Id, day.AddDate(0, 0, 1), 20)
+	// Time of others on the owner's tasks, and of the owner on their tasks
+	addTestTimeEntry(t, store, work, helper.Id, day, 45)
+	addTestTimeEntry(t, store, elsewhere, owner.Id, day, 15)
+	// Time of others on their own tasks and time out of range are left out
+	addTestTimeEntry(t, store, elsewhere, helper.Id, day, 600)
+	addTestTimeEntry(t, store, work, owner.Id, day.AddDate(0, -1, 0), 600)
+	// Running timers are left out
+	if _, err := store.TimeEntries.Create(ctx, &types.TimeEntry{TaskID: home.Id, UserID: helper.Id, StartedAt: day, Running: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	march := types.TimeReportQuery{
+		From: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+	}
+	category := "work"
+	type row struct {
+		user     string
+		category string
+		minutes  int64
+	}
+	tests := []struct {
+		name    string
+		query   types.TimeReportQuery
+		rows    []row
+		total   int64
+		entries int
+	}{
+		{
+			name:    "month",
+			query:   march,
+			rows:    []row{{"helper", "work", 45}, {"owner", "garden", 15}, {"owner", "home", 20}, {"owner", "work", 90}},
+			total:   170 * 60,
+			entries: 5,
+		},
+		{
+			name:    "one user",
+			query:   types.TimeReportQuery{From: march.From, To: march.To, UserID: &helper.Id},
+			rows:    []row{{"helper", "work", 45}},
+			total:   45 * 60,
+			entries: 1,
+		},
+		{
+			name:    "one category",
+			query:   types.TimeReportQuery{From: march.From, To: march.To, Category: &category},
+			rows:    []row{{"helper", "work", 45}, {"owner", "work", 90}},
+			total:   135 * 60,
+			entries: 3,
+		},
+		{
+			name:    "one day",
+			query:   types.TimeReportQuery{From: day.Add(-time.Hour), To: day.Add(23 * time.Hour)},
+			rows:    []row{{"helper", "work", 45}, {"owner", "garden", 15}, {"owner", "work", 90}},
+			total:   150 * 60,
+			entries: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := store.TimeReport(ctx, owner.Id, &tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows []row
+			for _, r := range report.Rows {
+				rows = append(rows, row{r.UserName, r.Category, r.Total / 60})
+				if r.UserEmail != r.UserName+"@example.com" || r.Hours != hours(r.Total) {
+					t.Errorf("row %+v has the wrong email or hours", r)
+				}
+			}
+			if !slices.Equal(rows, tt.rows) {
+				t.Errorf("rows are %v, want %v", rows, tt.rows)
+			}
+			if report.Total != tt.total || report.Entries != tt.entries || report.Hours != hours(tt.total) {
+				t.Errorf("report totals %d seconds in %d entries, want %d in %d", report.Total, report.Entries, tt.total, tt.entries)
+			}
+		})
+	}
+}
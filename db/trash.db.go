@@ -0,0 +1,18 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// notDeleted restricts a Mongo filter to documents that are not in the trash.
+// A nil value matches both a missing and an explicit null deleted_at.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// inTrash restricts a Mongo filter to documents that have been moved to the trash.
+func inTrash(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$ne": nil}
+	return filter
+}
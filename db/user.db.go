@@ -4,45 +4,47 @@ import (
 	"context"
 	"fmt"
 	"golang-auth/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type UserStore struct {
+type MongoUserStore struct {
 	collection *mongo.Collection
 }
 
 // List retrieves all users from the database
 
-func (u *UserStore) FindByEmail(email string) (*types.User, error) {
+func (u *MongoUserStore) FindByEmail(email string) (*types.User, error) {
 	var user types.User
-	err := u.collection.FindOne(context.TODO(), bson.M{"email": email}).Decode(&user)
+	err := u.collection.FindOne(context.TODO(), notDeleted(bson.M{"email": normalizeEmail(email)})).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
-func (u *UserStore) List(ctx context.Context) ([]*types.UserResponse, error) {
-	cursor, err := u.collection.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	var users []*types.UserResponse
-	err = cursor.All(ctx, &users)
-	if err != nil {
-		return nil, err
+func (u *MongoUserStore) List(ctx context.Context) ([]*types.UserResponse, error) {
+	return u.find(ctx, notDeleted(bson.M{}))
+}
 
+// ListPage retrieves one page of users matching the query
+func (u *MongoUserStore) ListPage(ctx context.Context, query *types.ListQuery) ([]*types.UserResponse, *types.Pagination, error) {
+	req, err := resolveListQuery(query, userSortFields)
+	if err != nil {
+		return nil, nil, err
 	}
-	return users, nil
+	filter := req.createdRange(notDeleted(bson.M{}))
+	return findPage[types.UserResponse](ctx, u.collection, filter, req)
 }
 
 // Get retrieves a single user by ID and returns added user and error
-func (u *UserStore) Get(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+func (u *MongoUserStore) Get(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
 	var user types.UserResponse
 
-	err := u.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	err := u.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&user)
 	if err != nil {
 
 		return nil, err
@@ -52,45 +54,57 @@ func (u *UserStore) Get(ctx context.Context, id primitive.ObjectID) (*types.User
 }
 
 // Create creates a new user and returns added user and error
-func (u *UserStore) Create(ctx context.Context, user *types.UserCreate) (*types.UserResponse, error) {
-	result, err := u.collection.InsertOne(ctx, user)
+func (u *MongoUserStore) Create(ctx context.Context, user *types.UserCreate) (*types.UserResponse, error) {
+	user.Email = normalizeEmail(user.Email)
+	user.CreatedAt = timestamp()
+	user.UpdatedAt = user.CreatedAt
+	user.CreatedBy = actorFrom(ctx)
+	user.UpdatedBy = user.CreatedBy
 
+	result, err := u.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, u.duplicateEmail(ctx, user.Email)
+	}
 	if err != nil {
 		return nil, err
 	}
 	userResponse := types.UserResponse{
-		Name:  user.Name,
-		Email: user.Email,
-		Id:    result.InsertedID.(primitive.ObjectID),
+		Name:      user.Name,
+		Email:     user.Email,
+		Id:        result.InsertedID.(primitive.ObjectID),
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		CreatedBy: user.CreatedBy,
+		UpdatedBy: user.UpdatedBy,
 	}
 
 	return &userResponse, nil
 }
 
-// Delete deletes a user and returns an deleted object and an error
-func (u *UserStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
-	var user *types.UserResponse
-	err := u.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
-	if err != nil {
-		return nil, err
-	}
-	_, err = u.collection.DeleteOne(ctx, bson.M{"_id": id})
-
-	if err != nil {
-		return nil, err
+// Delete moves a user to the trash and returns the trashed user and an error
+func (u *MongoUserStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": timestamp()},
 	}
-	return user, nil
+	return u.findOneAndUpdate(ctx, notDeleted(bson.M{"_id": id}), update)
 }
 
 // Update modifies an existing user
 // func (u *UserStore) Update(ctx context.Context, id primitive.ObjectID, updatedUser types.User) (*types.UserResponse, error) {
-func (u *UserStore) Update(ctx context.Context, id primitive.ObjectID, updateData *types.UserUpdate) (*types.UserResponse, error) {
+func (u *MongoUserStore) Update(ctx context.Context, id primitive.ObjectID, updateData *types.UserUpdate) (*types.UserResponse, error) {
+	updateData.Email = normalizeEmail(updateData.Email)
+	updateData.UpdatedAt = timestamp()
+	updateData.UpdatedBy = actorFrom(ctx)
+
 	// Prepare the update document, using $set to update only specific fields
 	update := bson.M{
 		"$set": updateData,
 	}
 
-	result, err := u.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := u.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), update)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, u.duplicateEmail(ctx, updateData.Email)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -110,3 +124,76 @@ func (u *UserStore) Update(ctx context.Context, id primitive.ObjectID, updateDat
 	// return u.Get(ctx, id)
 
 }
+
+// ListTrash retrieves every trashed user
+func (u *MongoUserStore) ListTrash(ctx context.Context) ([]*types.UserResponse, error) {
+	return u.find(ctx, inTrash(bson.M{}))
+}
+
+// ListDeletedBefore retrieves the users trashed before the given time
+func (u *MongoUserStore) ListDeletedBefore(ctx context.Context, before time.Time) ([]*types.UserResponse, error) {
+	return u.find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+}
+
+// GetTrashed retrieves a single trashed user by ID
+func (u *MongoUserStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	var user types.UserResponse
+	err := u.collection.FindOne(ctx, inTrash(bson.M{"_id": id})).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore takes a user out of the trash and returns the restored user
+func (u *MongoUserStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+	}
+	return u.findOneAndUpdate(ctx, inTrash(bson.M{"_id": id}), update)
+}
+
+// Purge permanently removes a trashed user and returns the removed user
+func (u *MongoUserStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	var user types.UserResponse
+	err := u.collection.FindOneAndDelete(ctx, inTrash(bson.M{"_id": id})).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// duplicateEmail tells whether the user already holding email is trashed
+func (u *MongoUserStore) duplicateEmail(ctx context.Context, email string) error {
+	count, err := u.collection.CountDocuments(ctx, inTrash(bson.M{"email": email}))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailInTrash
+	}
+	return ErrDuplicateEmail
+}
+
+func (u *MongoUserStore) find(ctx context.Context, filter bson.M) ([]*types.UserResponse, error) {
+	cursor, err := u.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var users []*types.UserResponse
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (u *MongoUserStore) findOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*types.UserResponse, error) {
+	var user types.UserResponse
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := u.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLUserStore struct {
+	db *sqlDB
+}
+
+func (u *SQLUserStore) FindByEmail(email string) (*types.User, error) {
+	var user types.User
+	err := u.db.findDoc(context.TODO(), &user, "SELECT data FROM users WHERE email = ? AND deleted_at IS NULL", normalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// List retrieves all users from the database
+func (u *SQLUserStore) List(ctx context.Context) ([]*types.UserResponse, error) {
+	return listDocs[types.UserResponse](ctx, u.db, "SELECT data FROM users WHERE deleted_at IS NULL ORDER BY id")
+}
+
+// ListPage retrieves one page of users matching the query
+func (u *SQLUserStore) ListPage(ctx context.Context, query *types.ListQuery) ([]*types.UserResponse, *types.Pagination, error) {
+	req, err := resolveListQuery(query, userSortFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	page := &sqlPage{}
+	page.add("deleted_at IS NULL")
+	page.createdRange(req)
+	return listPage[types.UserResponse](ctx, u.db, "users", page, req)
+}
+
+// Get retrieves a single user by ID
+func (u *SQLUserStore) Get(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	var user types.UserResponse
+	err := u.db.findDoc(ctx, &user, "SELECT data FROM users WHERE id = ? AND deleted_at IS NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create creates a new user and returns added user and error
+func (u *SQLUserStore) Create(ctx context.Context, user *types.UserCreate) (*types.UserResponse, error) {
+	newUser := types.User{
+		Id:        primitive.NewObjectID(),
+		Name:      user.Name,
+		Email:     normalizeEmail(user.Email),
+		Password:  user.Password,
+		Role:      user.Role,
+		CreatedAt: timestamp(),
+		CreatedBy: actorFrom(ctx),
+	}
+	newUser.UpdatedAt = newUser.CreatedAt
+	newUser.UpdatedBy = newUser.CreatedBy
+
+	data, err := marshalDoc(newUser)
+	if err != nil {
+		return nil, err
+	}
+	_, err = u.db.exec(ctx, "INSERT INTO users (id, email, created_at, updated_at, data) VALUES (?, ?, ?, ?, ?)",
+		newUser.Id.Hex(), newUser.Email, sqlTime(newUser.CreatedAt), sqlTime(newUser.UpdatedAt), data)
+	if isUniqueViolation(err) {
+		return nil, u.duplicateEmail(ctx, newUser.Email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userResponse := types.UserResponse{
+		Name:      newUser.Name,
+		Email:     newUser.Email,
+		Id:        newUser.Id,
+		CreatedAt: newUser.CreatedAt,
+		UpdatedAt: newUser.UpdatedAt,
+		CreatedBy: newUser.CreatedBy,
+		UpdatedBy: newUser.UpdatedBy,
+	}
+	return &userResponse, nil
+}
+
+// Delete moves a user to the trash and returns the trashed user
+func (u *SQLUserStore) Delete(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	user, err := u.getUser(ctx, id, "deleted_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	deletedAt := timestamp()
+	user.DeletedAt = &deletedAt
+	if err := u.save(ctx, user); err != nil {
+		return nil, err
+	}
+	return u.GetTrashed(ctx, id)
+}
+
+// Update modifies an existing user
+func (u *SQLUserStore) Update(ctx context.Context, id primitive.ObjectID, updateData *types.UserUpdate) (*types.UserResponse, error) {
+	user, err := u.getUser(ctx, id, "deleted_at IS NULL")
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("no user found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.Name = updateData.Name
+	user.Email = normalizeEmail(updateData.Email)
+	user.ProfilePicture = updateData.ProfilePicture
+	user.SocialMedia = updateData.SocialMedia
+	user.UpdatedAt = timestamp()
+	user.UpdatedBy = actorFrom(ctx)
+
+	if err := u.save(ctx, user); err != nil {
+		return nil, err
+	}
+	return u.Get(ctx, id)
+}
+
+// ListTrash retrieves every trashed user
+func (u *SQLUserStore) ListTrash(ctx context.Context) ([]*types.UserResponse, error) {
+	return listDocs[types.UserResponse](ctx, u.db, "SELECT data FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC")
+}
+
+// ListDeletedBefore retrieves the users trashed before the given time
+func (u *SQLUserStore) ListDeletedBefore(ctx context.Context, before time.Time) ([]*types.UserResponse, error) {
+	return listDocs[types.UserResponse](ctx, u.db, "SELECT data FROM users WHERE deleted_at < ? ORDER BY deleted_at", sqlTime(before))
+}
+
+// GetTrashed retrieves a single trashed user by ID
+func (u *SQLUserStore) GetTrashed(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	var user types.UserResponse
+	err := u.db.findDoc(ctx, &user, "SELECT data FROM users WHERE id = ? AND deleted_at IS NOT NULL", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore takes a user out of the trash and returns the restored user
+func (u *SQLUserStore) Restore(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	user, err := u.getUser(ctx, id, "deleted_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	user.DeletedAt = nil
+	if err := u.save(ctx, user); err != nil {
+		return nil, err
+	}
+	return u.Get(ctx, id)
+}
+
+// Purge permanently removes a trashed user and returns the removed user
+func (u *SQLUserStore) Purge(ctx context.Context, id primitive.ObjectID) (*types.UserResponse, error) {
+	user, err := u.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = u.db.exec(ctx, "DELETE FROM users WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// getUser loads the full user document, including the password hash
+func (u *SQLUserStore) getUser(ctx context.Context, id primitive.ObjectID, condition string) (*types.User, error) {
+	var user types.User
+	err := u.db.findDoc(ctx, &user, "SELECT data FROM users WHERE id = ? AND "+condition, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// save writes the user back, keeping the indexed columns in sync with the document
+func (u *SQLUserStore) save(ctx context.Context, user *types.User) error {
+	data, err := marshalDoc(user)
+	if err != nil {
+		return err
+	}
+	_, err = u.db.exec(ctx, "UPDATE users SET email = ?, deleted_at = ?, updated_at = ?, data = ? WHERE id = ?",
+		user.Email, nullableTime(user.DeletedAt), sqlTime(user.UpdatedAt), data, user.Id.Hex())
+	if isUniqueViolation(err) {
+		return u.duplicateEmail(ctx, user.Email)
+	}
+	return err
+}
+
+// duplicateEmail tells whether the user already holding email is trashed
+func (u *SQLUserStore) duplicateEmail(ctx context.Context, email string) error {
+	var count int
+	err := u.db.queryRow(ctx, "SELECT COUNT(*) FROM users WHERE email = ? AND deleted_at IS NOT NULL", email).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailInTrash
+	}
+	return ErrDuplicateEmail
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+	"testing"
+)
+
+func TestUserEmailsIgnoreCase(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	created, err := store.User.Create(ctx, &types.UserCreate{Name: "Ada", Email: " Ada@Example.com ", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Email != "ada@example.com" {
+		t.Errorf("created user has email %q, want ada@example.com", created.Email)
+	}
+	if _, err := store.User.FindByEmail("ADA@example.com"); err != nil {
+		t.Errorf("finding the user by email in another case returned %v", err)
+	}
+	if _, err := store.User.Create(ctx, &types.UserCreate{Name: "Ada", Email: "ada@EXAMPLE.com", Password: "password"}); err != ErrDuplicateEmail {
+		t.Errorf("creating a user with the same email in another case returned %v, want ErrDuplicateEmail", err)
+	}
+}
+
+func TestUserEmailInTrash(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	trashed := createTestUser(t, store, "ada")
+	other := createTestUser(t, store, "grace")
+	if _, err := store.User.Delete(ctx, trashed.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.User.Create(ctx, &types.UserCreate{Name: "Ada", Email: "ada@example.com", Password: "password"}); err != ErrEmailInTrash {
+		t.Errorf("signing up with the email of a trashed user returned %v, want ErrEmailInTrash", err)
+	}
+	update := &types.UserUpdate{Name: "Grace", Email: "ADA@example.com"}
+	if _, err := store.User.Update(ctx, other.Id, update); err != ErrEmailInTrash {
+		t.Errorf("taking the email of a trashed user returned %v, want ErrEmailInTrash", err)
+	}
+	if _, err := store.User.Restore(ctx, trashed.Id); err != nil {
+		t.Fatalf("restoring the user returned %v", err)
+	}
+	if _, err := store.User.Create(ctx, &types.UserCreate{Name: "Ada", Email: "ada@example.com", Password: "password"}); err != ErrDuplicateEmail {
+		t.Errorf("signing up with the email of a restored user returned %v, want ErrDuplicateEmail", err)
+	}
+}
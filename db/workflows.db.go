@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoWorkflowsStore struct {
+	collection *mongo.Collection
+}
+
+// Get retrieves the custom workflow of a user
+func (w *MongoWorkflowsStore) Get(ctx context.Context, userId primitive.ObjectID) (*types.Workflow, error) {
+	var workflow types.Workflow
+	if err := w.collection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&workflow); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// Upsert saves the workflow of a user, replacing the one they had
+func (w *MongoWorkflowsStore) Upsert(ctx context.Context, workflow *types.Workflow) (*types.Workflow, error) {
+	now, actor := timestamp(), actorFrom(ctx)
+	update := bson.M{
+		"$set": bson.M{
+			"initial":     workflow.Initial,
+			"statuses":    workflow.Statuses,
+			"transitions": workflow.Transitions,
+			"done":        workflow.Done,
+			"wip_limits":  workflow.WIPLimits,
+			"updated_at":  now,
+			"updated_by":  actor,
+		},
+		"$setOnInsert": bson.M{"created_at": now, "created_by": actor},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved types.Workflow
+	if err := w.collection.FindOneAndUpdate(ctx, bson.M{"user_id": workflow.UserID}, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteByUser removes the custom workflow of a user, if any
+func (w *MongoWorkflowsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := w.collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
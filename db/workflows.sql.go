@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"golang-auth/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SQLWorkflowsStore struct {
+	db *sqlDB
+}
+
+// Get retrieves the custom workflow of a user
+func (w *SQLWorkflowsStore) Get(ctx context.Context, userId primitive.ObjectID) (*types.Workflow, error) {
+	var workflow types.Workflow
+	if err := w.db.findDoc(ctx, &workflow, "SELECT data FROM workflows WHERE user_id = ?", userId.Hex()); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// Upsert saves the workflow of a user, replacing the one they had
+func (w *SQLWorkflowsStore) Upsert(ctx context.Context, workflow *types.Workflow) (*types.Workflow, error) {
+	now, actor := timestamp(), actorFrom(ctx)
+	existing, err := w.Get(ctx, workflow.UserID)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		existing.Initial = workflow.Initial
+		existing.Statuses = workflow.Statuses
+		existing.Transitions = workflow.Transitions
+		existing.Done = workflow.Done
+		existing.WIPLimits = workflow.WIPLimits
+		existing.UpdatedAt = now
+		existing.UpdatedBy = actor
+		data, err := marshalDoc(existing)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.db.exec(ctx, "UPDATE workflows SET updated_at = ?, data = ? WHERE id = ?", sqlTime(now), data, existing.Id.Hex())
+		if err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	newWorkflow := types.Workflow{
+		Id:          primitive.NewObjectID(),
+		UserID:      workflow.UserID,
+		Initial:     workflow.Initial,
+		Statuses:    workflow.Statuses,
+		Transitions: workflow.Transitions,
+		Done:        workflow.Done,
+		WIPLimits:   workflow.WIPLimits,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   actor,
+		UpdatedBy:   actor,
+	}
+	data, err := marshalDoc(newWorkflow)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.db.exec(ctx, "INSERT INTO workflows (id, user_id, updated_at, data) VALUES (?, ?, ?, ?)",
+		newWorkflow.Id.Hex(), newWorkflow.UserID.Hex(), sqlTime(now), data)
+	if err != nil {
+		return nil, err
+	}
+	return &newWorkflow, nil
+}
+
+// DeleteByUser removes the custom workflow of a user, if any
+func (w *SQLWorkflowsStore) DeleteByUser(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	result, err := w.db.exec(ctx, "DELETE FROM workflows WHERE user_id = ?", userId.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"golang-auth/db"
+	"golang-auth/utils"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultTrashRetentionDays is used when TRASH_RETENTION_DAYS is not set.
+const defaultTrashRetentionDays = 30
+
+// trashPurgeInterval is how often the trash is checked for expired items.
+const trashPurgeInterval = time.Hour
+
+// startTrashPurger permanently removes items that stayed in the trash longer
+// than TRASH_RETENTION_DAYS. Setting it to 0 disables the purge.
+func startTrashPurger(store *db.Store) {
+	retentionDays := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			log.Fatalf("Invalid TRASH_RETENTION_DAYS %q", value)
+		}
+		retentionDays = days
+	}
+	if retentionDays == 0 {
+		log.Println("Trash purge disabled")
+		return
+	}
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+
+	go func() {
+		for {
+			purgeTrash(store, retention)
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+}
+
+func purgeTrash(store *db.Store, retention time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	report, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Println("Trash purge failed:", err)
+		return
+	}
+	for _, userReport := range report.Users {
+		userReport.AvatarRemoved = utils.RemoveUserAvatar(userReport.User.ProfilePicture)
+	}
+	if len(report.Users) > 0 || report.Notes > 0 || report.Tasks > 0 {
+		log.Printf("Trash purge removed %d users, %d notes and %d tasks", len(report.Users), report.Notes, report.Tasks)
+	}
+}
+
+// recurrenceInterval is how often recurring tasks are checked for
+// occurrences whose time has come.
+const recurrenceInterval = 5 * time.Minute
+
+// startRecurrenceWorker creates the next occurrence of recurring tasks once
+// it is scheduled, for the series whose current occurrence was not completed.
+func startRecurrenceWorker(store *db.Store) {
+	go func() {
+		for {
+			createDueOccurrences(store)
+			time.Sleep(recurrenceInterval)
+		}
+	}()
+}
+
+func createDueOccurrences(store *db.Store) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	created, err := store.CreateDueOccurrences(ctx)
+	if err != nil {
+		log.Println("Creating task occurrences failed:", err)
+	}
+	if created > 0 {
+		log.Printf("Created %d task occurrences", created)
+	}
+}
+
+// noteRevisionLimit reads NOTE_REVISION_LIMIT, the number of earlier versions
+// kept per note. Setting it to 0 keeps every version.
+func noteRevisionLimit() int {
+	value := os.Getenv("NOTE_REVISION_LIMIT")
+	if value == "" {
+		return db.DefaultNoteRevisionLimit
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		log.Fatalf("Invalid NOTE_REVISION_LIMIT %q", value)
+	}
+	return limit
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"golang-auth/db"
 	"log"
 	"os"
+	"time"
+	_ "time/tzdata" // Task due dates and filters use IANA zones, even where the system has none
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -21,18 +24,46 @@ func main() {
 			log.Println("Error loading .env file")
 		}
 	}
+	// Initialize database and store
+	store := db.NewStore()
+	store.NoteRevisionLimit = noteRevisionLimit()
+
+	// `migrate` applies pending migrations and exits without serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(store, os.Args[2:])
+		return
+	}
+
+	// Blob storage and upload limits for attachments
+	configureAttachments(store)
+
+	// Apply pending migrations on startup unless they are run separately
+	if os.Getenv("DB_SKIP_MIGRATIONS") != "true" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		err := store.Migrate(ctx)
+		cancel()
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	}
+
+	// Permanently remove items that stayed in the trash past the retention period
+	startTrashPurger(store)
+
+	// Create the occurrences of recurring tasks whose time has come
+	startRecurrenceWorker(store)
+
 	// Initialize Fiber
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit: bodyLimit(store),
+	})
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "http://localhost:3000, https://tasksphile.netlify.app", // Frontend origin
-		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",                // Allowed methods
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",           // Allow Authorization header
-		AllowCredentials: true,                                                    // If using credentials like cookies or authorization
+		AllowOrigins:     "http://localhost:3000, https://tasksphile.netlify.app",        // Frontend origin
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",                       // Allowed methods
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Link-Password", // Allow Authorization and public link password headers
+		AllowCredentials: true,                                                           // If using credentials like cookies or authorization
 	}))
 
-	// Initialize database and store
-	store := db.NewStore()
-
 	// Define routes from routes.go
 	SetupRoutes(app, store)
 
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"golang-auth/db"
 	"log"
 	"os"
 	"strings"
@@ -9,23 +10,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var JWTSecret []byte
 
 func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
+	// The environment may set JWT_SECRET without a .env file, as main allows
+	godotenv.Load()
 
 	JWTSecret = []byte(os.Getenv("JWT_SECRET"))
 	if len(JWTSecret) == 0 {
-		log.Fatal("JWT_SECRET not set in .env")
+		log.Fatal("JWT_SECRET not set in the environment or .env")
 	}
 }
 
-func AuthMiddleware(c *fiber.Ctx) error {
+// AuthMiddleware lets through requests with a valid token of a user who is
+// not in the trash.
+func AuthMiddleware(c *fiber.Ctx, store *db.Store) error {
 	authHeader := c.Get("Authorization")
 
 	if authHeader == "" {
@@ -66,6 +68,24 @@ func AuthMiddleware(c *fiber.Ctx) error {
 			})
 		}
 
+		// Tokens outlive the accounts they were issued for
+		userId, _ := claims["userId"].(string)
+		id, err := primitive.ObjectIDFromHex(userId)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+		if _, err := store.User.Get(c.Context(), id); err == db.ErrNotFound {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Account no longer exists",
+			})
+		} else if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check account",
+			})
+		}
+
 		// Attach user information (from token claims) to the context
 		c.Locals("userId", claims["userId"])
 		c.Locals("email", claims["email"])
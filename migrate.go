@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"golang-auth/db"
+	"log"
+	"time"
+)
+
+// runMigrateCommand handles `go run . migrate [status]`, applying pending
+// migrations (or only listing the applied ones) and exiting afterwards.
+func runMigrateCommand(store *db.Store, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if len(args) == 0 || args[0] != "status" {
+		if err := store.Migrate(ctx); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	}
+
+	applied, err := store.AppliedMigrations(ctx)
+	if err != nil {
+		log.Fatal("Failed to read applied migrations: ", err)
+	}
+	for _, m := range applied {
+		fmt.Printf("%4d  %-45s %s\n", m.Version, m.Name, m.AppliedAt.Format(time.RFC3339))
+	}
+}
@@ -12,11 +12,20 @@ import (
 func SetupRoutes(app *fiber.App, store *db.Store) {
 
 	setupAuthRoutes(app, store)
-	app.Use(middleware.AuthMiddleware)
+	setupPublicRoutes(app, store)
+	app.Use(func(c *fiber.Ctx) error {
+		return middleware.AuthMiddleware(c, store)
+	})
 
 	setupLoggedInUserRoutes(app, store)
 	setupNoteRoutes(app, store)
 	setupTasksRoutes(app, store)
+	setupWorkflowRoutes(app, store)
+	setupSearchRoutes(app, store)
+	setupTagRoutes(app, store)
+	setupShareRoutes(app, store)
+	setupNotificationRoutes(app, store)
+	setupTimeRoutes(app, store)
 
 	// app.Use(middleware.AdminMiddleware)
 	setupAdminRoutes(app, store)
@@ -35,6 +44,77 @@ func setupAuthRoutes(app *fiber.App, store *db.Store) {
 		return api.CreateUser(c, store)
 	})
 }
+
+// Setup routes that work without logging in
+func setupPublicRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/public/notes/:token", func(c *fiber.Ctx) error {
+		return api.GetPublicNote(c, store)
+	})
+}
+
+func setupSearchRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/search", func(c *fiber.Ctx) error {
+		return api.Search(c, store)
+	})
+}
+
+func setupWorkflowRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/workflow", func(c *fiber.Ctx) error {
+		return api.GetWorkflow(c, store)
+	})
+	app.Put("/workflow", func(c *fiber.Ctx) error {
+		return api.UpdateWorkflow(c, store)
+	})
+	app.Delete("/workflow", func(c *fiber.Ctx) error {
+		return api.ResetWorkflow(c, store)
+	})
+}
+
+func setupShareRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/shared", func(c *fiber.Ctx) error {
+		return api.GetSharedWithMe(c, store)
+	})
+}
+
+func setupNotificationRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/notifications", func(c *fiber.Ctx) error {
+		return api.GetNotifications(c, store)
+	})
+	app.Post("/notifications/read", func(c *fiber.Ctx) error {
+		return api.ReadAllNotifications(c, store)
+	})
+	app.Post("/notifications/:id/read", func(c *fiber.Ctx) error {
+		return api.ReadNotification(c, store)
+	})
+}
+
+func setupTimeRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/timer", func(c *fiber.Ctx) error {
+		return api.GetRunningTimer(c, store)
+	})
+	app.Get("/time/report", func(c *fiber.Ctx) error {
+		return api.GetTimeReport(c, store)
+	})
+}
+
+func setupTagRoutes(app *fiber.App, store *db.Store) {
+	app.Get("/tags", func(c *fiber.Ctx) error {
+		return api.GetTags(c, store)
+	})
+	app.Post("/tags", func(c *fiber.Ctx) error {
+		return api.CreateTag(c, store)
+	})
+	app.Patch("/tags/:id", func(c *fiber.Ctx) error {
+		return api.UpdateTag(c, store)
+	})
+	app.Delete("/tags/:id", func(c *fiber.Ctx) error {
+		return api.DeleteTag(c, store)
+	})
+	app.Post("/tags/:id/merge", func(c *fiber.Ctx) error {
+		return api.MergeTag(c, store)
+	})
+}
+
 func setupAdminRoutes(app *fiber.App, store *db.Store) {
 	app.Use(middleware.AdminMiddleware)
 
@@ -42,6 +122,16 @@ func setupAdminRoutes(app *fiber.App, store *db.Store) {
 		return api.GetAllUsers(c, store)
 	})
 
+	app.Get("/users/trash", func(c *fiber.Ctx) error {
+		return api.GetUsersTrash(c, store)
+	})
+	app.Post("/users/trash/:id/restore", func(c *fiber.Ctx) error {
+		return api.RestoreUser(c, store)
+	})
+	app.Delete("/users/trash/:id", func(c *fiber.Ctx) error {
+		return api.PurgeUser(c, store)
+	})
+
 	app.Delete("/users/:id", func(c *fiber.Ctx) error {
 		return api.DeleteUser(c, store)
 	})
@@ -63,6 +153,9 @@ func setupLoggedInUserRoutes(app *fiber.App, store *db.Store) {
 	app.Patch("/loggedinuser", func(c *fiber.Ctx) error {
 		return api.UpdateLoggedInUser(c, store)
 	})
+	app.Post("/loggedinuser/avatar", func(c *fiber.Ctx) error {
+		return api.UploadAvatar(c, store)
+	})
 	app.Get("/allavatar", func(c *fiber.Ctx) error {
 		return api.GetAllAvatar(c, store)
 	})
@@ -77,6 +170,16 @@ func setupNoteRoutes(app *fiber.App, store *db.Store) {
 		return api.GetAllNotesForUserById(c, store)
 	})
 
+	app.Get("/notes/trash", func(c *fiber.Ctx) error {
+		return api.GetNotesTrash(c, store)
+	})
+	app.Post("/notes/trash/:id/restore", func(c *fiber.Ctx) error {
+		return api.RestoreNote(c, store)
+	})
+	app.Delete("/notes/trash/:id", func(c *fiber.Ctx) error {
+		return api.PurgeNote(c, store)
+	})
+
 	app.Get("/notes/:id", func(c *fiber.Ctx) error {
 		return api.GetSingleNote(c, store)
 	})
@@ -93,6 +196,65 @@ func setupNoteRoutes(app *fiber.App, store *db.Store) {
 		return api.DeleteNote(c, store)
 	})
 
+	app.Get("/notes/:id/revisions", func(c *fiber.Ctx) error {
+		return api.GetNoteRevisions(c, store)
+	})
+	app.Get("/notes/:id/revisions/:version", func(c *fiber.Ctx) error {
+		return api.GetNoteRevision(c, store)
+	})
+	app.Post("/notes/:id/revisions/:version/restore", func(c *fiber.Ctx) error {
+		return api.RestoreNoteRevision(c, store)
+	})
+	app.Get("/notes/:id/diff", func(c *fiber.Ctx) error {
+		return api.GetNoteDiff(c, store)
+	})
+
+	app.Get("/notes/:id/shares", func(c *fiber.Ctx) error {
+		return api.GetNoteShares(c, store)
+	})
+	app.Post("/notes/:id/shares", func(c *fiber.Ctx) error {
+		return api.ShareNote(c, store)
+	})
+	app.Delete("/notes/:id/shares/:userId", func(c *fiber.Ctx) error {
+		return api.UnshareNote(c, store)
+	})
+
+	app.Get("/notes/:id/links", func(c *fiber.Ctx) error {
+		return api.GetNoteLinks(c, store)
+	})
+	app.Post("/notes/:id/links", func(c *fiber.Ctx) error {
+		return api.CreateNoteLink(c, store)
+	})
+	app.Delete("/notes/:id/links/:linkId", func(c *fiber.Ctx) error {
+		return api.DeleteNoteLink(c, store)
+	})
+
+	app.Get("/notes/:id/attachments", func(c *fiber.Ctx) error {
+		return api.GetNoteAttachments(c, store)
+	})
+	app.Post("/notes/:id/attachments", func(c *fiber.Ctx) error {
+		return api.UploadNoteAttachment(c, store)
+	})
+	app.Get("/notes/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
+		return api.DownloadNoteAttachment(c, store)
+	})
+	app.Delete("/notes/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
+		return api.DeleteNoteAttachment(c, store)
+	})
+
+	app.Get("/notes/:id/comments", func(c *fiber.Ctx) error {
+		return api.GetNoteComments(c, store)
+	})
+	app.Post("/notes/:id/comments", func(c *fiber.Ctx) error {
+		return api.AddNoteComment(c, store)
+	})
+	app.Patch("/notes/:id/comments/:commentId", func(c *fiber.Ctx) error {
+		return api.UpdateNoteComment(c, store)
+	})
+	app.Delete("/notes/:id/comments/:commentId", func(c *fiber.Ctx) error {
+		return api.DeleteNoteComment(c, store)
+	})
+
 }
 func setupTasksRoutes(app *fiber.App, store *db.Store) {
 
@@ -103,6 +265,26 @@ func setupTasksRoutes(app *fiber.App, store *db.Store) {
 		return api.GetAllTasksForUserById(c, store)
 	})
 
+	app.Get("/tasks/analytics", func(c *fiber.Ctx) error {
+		return api.GetTasksAnalytics(c, store)
+	})
+	app.Get("/tasks/dependencies", func(c *fiber.Ctx) error {
+		return api.GetDependencyGraph(c, store)
+	})
+	app.Get("/tasks/board", func(c *fiber.Ctx) error {
+		return api.GetTaskBoard(c, store)
+	})
+
+	app.Get("/tasks/trash", func(c *fiber.Ctx) error {
+		return api.GetTasksTrash(c, store)
+	})
+	app.Post("/tasks/trash/:id/restore", func(c *fiber.Ctx) error {
+		return api.RestoreTask(c, store)
+	})
+	app.Delete("/tasks/trash/:id", func(c *fiber.Ctx) error {
+		return api.PurgeTask(c, store)
+	})
+
 	app.Get("/task/:id", func(c *fiber.Ctx) error {
 		return api.GetSingleTask(c, store)
 	})
@@ -119,4 +301,111 @@ func setupTasksRoutes(app *fiber.App, store *db.Store) {
 		return api.DeleteTask(c, store)
 	})
 
+	app.Get("/tasks/:id/analytics", func(c *fiber.Ctx) error {
+		return api.GetTaskAnalytics(c, store)
+	})
+
+	app.Get("/tasks/:id/shares", func(c *fiber.Ctx) error {
+		return api.GetTaskShares(c, store)
+	})
+	app.Post("/tasks/:id/shares", func(c *fiber.Ctx) error {
+		return api.ShareTask(c, store)
+	})
+	app.Delete("/tasks/:id/shares/:userId", func(c *fiber.Ctx) error {
+		return api.UnshareTask(c, store)
+	})
+
+	app.Get("/tasks/:id/subtasks", func(c *fiber.Ctx) error {
+		return api.GetSubtasks(c, store)
+	})
+	app.Post("/tasks/:id/subtasks", func(c *fiber.Ctx) error {
+		return api.CreateSubtask(c, store)
+	})
+	app.Post("/tasks/:id/move", func(c *fiber.Ctx) error {
+		return api.MoveTask(c, store)
+	})
+
+	app.Get("/tasks/:id/dependencies", func(c *fiber.Ctx) error {
+		return api.GetTaskDependencies(c, store)
+	})
+	app.Post("/tasks/:id/dependencies", func(c *fiber.Ctx) error {
+		return api.AddTaskDependency(c, store)
+	})
+	app.Delete("/tasks/:id/dependencies/:blockerId", func(c *fiber.Ctx) error {
+		return api.RemoveTaskDependency(c, store)
+	})
+
+	app.Post("/tasks/:id/skip", func(c *fiber.Ctx) error {
+		return api.SkipOccurrence(c, store)
+	})
+
+	app.Post("/tasks/:id/position", func(c *fiber.Ctx) error {
+		return api.MoveTaskOnBoard(c, store)
+	})
+
+	app.Post("/tasks/:id/checklist", func(c *fiber.Ctx) error {
+		return api.AddChecklistItem(c, store)
+	})
+	app.Patch("/tasks/:id/checklist/:itemId", func(c *fiber.Ctx) error {
+		return api.UpdateChecklistItem(c, store)
+	})
+	app.Delete("/tasks/:id/checklist/:itemId", func(c *fiber.Ctx) error {
+		return api.DeleteChecklistItem(c, store)
+	})
+
+	app.Get("/tasks/:id/assignees", func(c *fiber.Ctx) error {
+		return api.GetTaskAssignees(c, store)
+	})
+	app.Post("/tasks/:id/assignees", func(c *fiber.Ctx) error {
+		return api.AssignTask(c, store)
+	})
+	app.Delete("/tasks/:id/assignees/:userId", func(c *fiber.Ctx) error {
+		return api.UnassignTask(c, store)
+	})
+
+	app.Get("/tasks/:id/attachments", func(c *fiber.Ctx) error {
+		return api.GetTaskAttachments(c, store)
+	})
+	app.Post("/tasks/:id/attachments", func(c *fiber.Ctx) error {
+		return api.UploadTaskAttachment(c, store)
+	})
+	app.Get("/tasks/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
+		return api.DownloadTaskAttachment(c, store)
+	})
+	app.Delete("/tasks/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
+		return api.DeleteTaskAttachment(c, store)
+	})
+
+	app.Get("/tasks/:id/comments", func(c *fiber.Ctx) error {
+		return api.GetTaskComments(c, store)
+	})
+	app.Post("/tasks/:id/comments", func(c *fiber.Ctx) error {
+		return api.AddTaskComment(c, store)
+	})
+	app.Patch("/tasks/:id/comments/:commentId", func(c *fiber.Ctx) error {
+		return api.UpdateTaskComment(c, store)
+	})
+	app.Delete("/tasks/:id/comments/:commentId", func(c *fiber.Ctx) error {
+		return api.DeleteTaskComment(c, store)
+	})
+
+	app.Get("/tasks/:id/time", func(c *fiber.Ctx) error {
+		return api.GetTaskTime(c, store)
+	})
+	app.Post("/tasks/:id/time", func(c *fiber.Ctx) error {
+		return api.AddTimeEntry(c, store)
+	})
+	app.Patch("/tasks/:id/time/:entryId", func(c *fiber.Ctx) error {
+		return api.UpdateTimeEntry(c, store)
+	})
+	app.Delete("/tasks/:id/time/:entryId", func(c *fiber.Ctx) error {
+		return api.DeleteTimeEntry(c, store)
+	})
+	app.Post("/tasks/:id/timer/start", func(c *fiber.Ctx) error {
+		return api.StartTimer(c, store)
+	})
+	app.Post("/tasks/:id/timer/stop", func(c *fiber.Ctx) error {
+		return api.StopTimer(c, store)
+	})
+
 }
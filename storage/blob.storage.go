@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultLocalDir is where blobs are written when STORAGE_DRIVER is "local"
+// or not set and STORAGE_DIR is empty.
+const DefaultLocalDir = "uploads/attachments"
+
+// ErrNotFound is returned by Open when no blob is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Blobs stores file contents under keys chosen by the caller. Keys are
+// slash-separated paths such as "note/<id>/<file id>".
+type Blobs interface {
+	// Put stores body under key, replacing any blob already there. size is
+	// the exact length of body.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Open returns the contents of the blob stored under key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv opens the blob storage selected with STORAGE_DRIVER: "local"
+// (default) writes to STORAGE_DIR, while "s3" uses the S3-compatible service
+// at S3_ENDPOINT with S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY, S3_REGION and
+// S3_USE_SSL.
+func NewFromEnv() (Blobs, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = DefaultLocalDir
+		}
+		return NewLocal(dir), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    os.Getenv("S3_USE_SSL") != "false",
+		})
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_DRIVER %q", driver)
+	}
+}
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	for key, valid := range map[string]bool{
+		"note/1/2":       true,
+		"a":              true,
+		"a/..b/c":        true,
+		"":               false,
+		"/etc/passwd":    false,
+		"../secret":      false,
+		"note/../../etc": false,
+		"note/..":        false,
+		"note/./1":       false,
+		"note//1":        false,
+		"note/1/":        false,
+		`note\..\1`:      false,
+	} {
+		if err := validKey(key); (err == nil) != valid {
+			t.Errorf("validKey(%q) returned %v, want valid %v", key, err, valid)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readBlob returns the contents of the blob under key.
+func readBlob(t *testing.T, blobs Blobs, key string) string {
+	t.Helper()
+	body, err := blobs.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open(%q) returned %v", key, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// putBlob stores contents under key.
+func putBlob(t *testing.T, blobs Blobs, key string, contents string) {
+	t.Helper()
+	if err := blobs.Put(context.Background(), key, strings.NewReader(contents), int64(len(contents)), "text/plain"); err != nil {
+		t.Fatalf("Put(%q) returned %v", key, err)
+	}
+}
+
+// testBlobs runs the checks every Blobs implementation must pass.
+func testBlobs(t *testing.T, blobs Blobs) {
+	ctx := context.Background()
+	if _, err := blobs.Open(ctx, "note/1/missing"); err != ErrNotFound {
+		t.Errorf("opening a missing blob returned %v, want ErrNotFound", err)
+	}
+	putBlob(t, blobs, "note/1/a", "first")
+	putBlob(t, blobs, "note/1/b", "second")
+	if got := readBlob(t, blobs, "note/1/a"); got != "first" {
+		t.Errorf("blob a is %q, want first", got)
+	}
+	putBlob(t, blobs, "note/1/a", "replaced")
+	if got := readBlob(t, blobs, "note/1/a"); got != "replaced" {
+		t.Errorf("blob a is %q after replacing it, want replaced", got)
+	}
+
+	if err := blobs.Delete(ctx, "note/1/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blobs.Open(ctx, "note/1/a"); err != ErrNotFound {
+		t.Errorf("opening a deleted blob returned %v, want ErrNotFound", err)
+	}
+	if got := readBlob(t, blobs, "note/1/b"); got != "second" {
+		t.Errorf("blob b is %q after deleting a, want second", got)
+	}
+	if err := blobs.Delete(ctx, "note/1/a"); err != nil {
+		t.Errorf("deleting a missing blob returned %v", err)
+	}
+
+	for _, key := range []string{"../escape", "note/../../escape", "/escape"} {
+		if err := blobs.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := blobs.Open(ctx, key); err == nil || err == ErrNotFound {
+			t.Errorf("Open(%q) returned %v, want an invalid key error", key, err)
+		}
+		if err := blobs.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "blobs")
+	testBlobs(t, NewLocal(dir))
+
+	if _, err := os.Stat(filepath.Join(parent, "escape")); !os.IsNotExist(err) {
+		t.Errorf("a key escaped the storage directory: %v", err)
+	}
+}
+
+func TestLocalDeleteRemovesEmptyDirectories(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir)
+	putBlob(t, local, "note/1/a", "a")
+	putBlob(t, local, "note/2/b", "b")
+	if err := local.Delete(context.Background(), "note/1/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "note", "1")); !os.IsNotExist(err) {
+		t.Errorf("the empty directory of the key is still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "note", "2", "b")); err != nil {
+		t.Errorf("the blob next to it is gone: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("the storage directory is gone: %v", err)
+	}
+}
+
+// failingReader returns some data and then an error, like an upload that is
+// cut off.
+type failingReader struct {
+	data io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestLocalPutKeepsBlobOnFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir)
+	putBlob(t, local, "note/1/a", "complete")
+
+	body := &failingReader{strings.NewReader("partial")}
+	if err := local.Put(context.Background(), "note/1/a", body, 100, "text/plain"); err == nil {
+		t.Fatal("Put of a failing upload succeeded")
+	}
+	if got := readBlob(t, local, "note/1/a"); got != "complete" {
+		t.Errorf("blob is %q after a failed write, want complete", got)
+	}
+	files, err := os.ReadDir(filepath.Join(dir, "note", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("directory holds %d files after a failed write, want the blob only", len(files))
+	}
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The groupings of TaskAnalyticsReport.
+const (
+	AnalyticsByCategory = "category"
+	AnalyticsByUser     = "user"
+)
+
+// TaskAnalytics is computed from the status history of a task. Durations are
+// in seconds. The time spent in the current status runs up to now.
+type TaskAnalytics struct {
+	TaskID        primitive.ObjectID `json:"task_id"`
+	UserID        primitive.ObjectID `json:"user_id"`
+	Category      string             `json:"category"`
+	CurrentStatus string             `json:"current_status"`
+	Transitions   int                `json:"transitions"` // Status changes after the first entry
+	TimeInStatus  map[string]int64   `json:"time_in_status"`
+	StartedAt     *time.Time         `json:"started_at,omitempty"`   // First move out of the initial status
+	CompletedAt   *time.Time         `json:"completed_at,omitempty"` // Set while the task is in a done status
+	LeadTime      *int64             `json:"lead_time,omitempty"`    // From creation to completion
+	CycleTime     *int64             `json:"cycle_time,omitempty"`   // From the start of work to completion
+}
+
+// TaskAnalyticsGroup aggregates the analytics of the tasks sharing a user or
+// category. Averages are in seconds; time in a status is averaged over the
+// tasks that were in it, lead and cycle times over the completed tasks.
+type TaskAnalyticsGroup struct {
+	Key                 string           `json:"key"` // The user ID or category
+	Tasks               int              `json:"tasks"`
+	Completed           int              `json:"completed"`
+	AverageTimeInStatus map[string]int64 `json:"average_time_in_status"`
+	AverageLeadTime     *int64           `json:"average_lead_time,omitempty"`
+	AverageCycleTime    *int64           `json:"average_cycle_time,omitempty"`
+}
+
+// TaskAnalyticsReport aggregates the analytics of the tasks a user can see.
+type TaskAnalyticsReport struct {
+	GroupBy string                `json:"group_by"` // AnalyticsByCategory or AnalyticsByUser
+	Overall *TaskAnalyticsGroup   `json:"overall"`
+	Groups  []*TaskAnalyticsGroup `json:"groups"`
+}
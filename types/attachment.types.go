@@ -0,0 +1,21 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Attachment describes a file uploaded to a note or task. The contents live
+// in blob storage; this is the metadata kept in the database.
+type Attachment struct {
+	Id          primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	ItemType    string              `json:"item_type" bson:"item_type"` // ShareNote or ShareTask
+	ItemID      primitive.ObjectID  `json:"item_id" bson:"item_id"`
+	Filename    string              `json:"filename" bson:"filename"`
+	ContentType string              `json:"content_type" bson:"content_type"` // Detected from the contents, not taken from the client
+	Size        int64               `json:"size" bson:"size"`
+	SHA256      string              `json:"sha256" bson:"sha256"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	CreatedBy   *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+}
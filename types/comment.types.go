@@ -0,0 +1,53 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCommentLength is the longest comment body accepted, in characters.
+const MaxCommentLength = 10000
+
+// Comment is a message left on a note or task. Replies point to the comment
+// that starts their thread, so threads are one level deep.
+type Comment struct {
+	Id        primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	ItemType  string               `json:"item_type" bson:"item_type"` // ShareNote or ShareTask
+	ItemID    primitive.ObjectID   `json:"item_id" bson:"item_id"`
+	ParentID  *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"` // Set on replies
+	UserID    primitive.ObjectID   `json:"user_id" bson:"user_id"`                         // The author
+	Body      string               `json:"body" bson:"body"`
+	Mentions  []primitive.ObjectID `json:"mentions" bson:"mentions"` // Users mentioned in the body who can see the item
+	Edits     []*CommentEdit       `json:"edits" bson:"edits"`       // Earlier bodies, oldest first
+	Replies   []*Comment           `json:"replies,omitempty" bson:"-"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// CommentEdit is a body a comment had before it was edited.
+type CommentEdit struct {
+	Body     string    `json:"body" bson:"body"`
+	EditedAt time.Time `json:"edited_at" bson:"edited_at"` // When this body was replaced
+}
+
+// CommentRequest creates or edits a comment. ParentID is ignored on edits.
+type CommentRequest struct {
+	Body     string              `json:"body"`
+	ParentID *primitive.ObjectID `json:"parent_id,omitempty"`
+}
+
+// Validate trims the body and checks its length.
+func (r *CommentRequest) Validate() error {
+	r.Body = strings.TrimSpace(r.Body)
+	if r.Body == "" {
+		return fmt.Errorf("body must not be empty")
+	}
+	if utf8.RuneCountInString(r.Body) > MaxCommentLength {
+		return fmt.Errorf("body must be at most %d characters", MaxCommentLength)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NoteLink is a public, read-only link to a note that works without an
+// account. Whoever knows the token can read the note, unless the link is
+// protected by a password or has expired.
+type NoteLink struct {
+	Id             primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	NoteID         primitive.ObjectID  `json:"note_id" bson:"note_id"`
+	Token          string              `json:"token" bson:"token"`
+	PasswordHash   string              `json:"password_hash,omitempty" bson:"password_hash,omitempty"` // Cleared before links are sent to clients
+	Protected      bool                `json:"protected" bson:"protected"`                             // A password is needed to open the link
+	ExpiresAt      *time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	Views          int64               `json:"views" bson:"views"`
+	FailedAttempts int                 `json:"failed_attempts,omitempty" bson:"failed_attempts,omitempty"` // Wrong passwords since the last view or lockout
+	LockedUntil    *time.Time          `json:"locked_until,omitempty" bson:"locked_until,omitempty"`       // Passwords are not checked before then
+	LastViewedAt   *time.Time          `json:"last_viewed_at,omitempty" bson:"last_viewed_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	CreatedBy      *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+}
+
+// Expired reports whether the link stopped working at now.
+func (l *NoteLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Locked reports whether the link refuses passwords at now.
+func (l *NoteLink) Locked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
+
+// NoteLinkRequest creates a public link. Both fields are optional.
+type NoteLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Password  string     `json:"password"`
+}
+
+// PublicNote is what a public link shows of a note.
+type PublicNote struct {
+	Title     string    `json:"title"`
+	Category  string    `json:"category"`
+	Note      string    `json:"note"`
+	Format    string    `json:"format"`
+	HTML      string    `json:"html"` // The note body rendered as sanitized HTML
+	UpdatedAt time.Time `json:"updated_at"`
+	Views     int64     `json:"views"`
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListQuery describes one page of a list endpoint: the filters to apply,
+// the sort order and where the previous page stopped.
+type ListQuery struct {
+	Limit       int                  // Maximum number of items to return
+	Cursor      string               // Opaque cursor returned as next_cursor by the previous page
+	SortBy      string               // Whitelisted sort field, e.g. "created_at"
+	Desc        bool                 // Sort in descending order
+	Category    string               // Only items in this category
+	Scope       string               // Which tasks of the user to list, see ScopeOwned
+	Status      string               // Only tasks whose latest status matches
+	NotStatuses []string             // Only tasks whose latest status is none of these
+	DueFrom     *time.Time           // Only tasks due at or after this time
+	DueTo       *time.Time           // Only tasks due before this time
+	Tags        []primitive.ObjectID // Only items carrying every one of these tags
+	From        *time.Time           // Only items created at or after this time
+	To          *time.Time           // Only items created before this time
+}
+
+// Pagination is returned next to the data of paginated list endpoints.
+type Pagination struct {
+	Limit      int    `json:"limit"`                 // Page size that was applied
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as ?cursor= to fetch the next page
+	Total      *int64 `json:"total,omitempty"`       // Total matching items, only on the first page
+}
+
+// The scopes of a task list, relative to the user whose tasks are listed.
+const (
+	ScopeOwned    = "owned"    // Tasks the user owns, the default
+	ScopeAssigned = "assigned" // Tasks assigned to the user, whoever owns them
+	ScopeCreated  = "created"  // Tasks the user created, whoever owns them now
+)
@@ -1,29 +1,97 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The formats a note body can be written in.
+const (
+	NoteFormatPlain    = "plain"
+	NoteFormatMarkdown = "markdown"
+)
 
 type Notes struct {
-	Id       primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Title    string             `json:"title" `
-	Category string             `json:"category"`
-	Note     string             `json:"note"`
-	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Id           primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Title        string               `json:"title" `
+	Category     string               `json:"category"`
+	Note         string               `json:"note"`
+	Format       string               `json:"format" bson:"format"` // NoteFormatPlain or NoteFormatMarkdown
+	Tags         []primitive.ObjectID `json:"tags" bson:"tags"`
+	Version      int                  `json:"version" bson:"version"`
+	UserID       primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	CommentCount *int64               `json:"comment_count,omitempty" bson:"-"` // Only computed where documented
+	DeletedAt    *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	CreatedAt    time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at" bson:"updated_at"`
+	CreatedBy    *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy    *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
 }
 
 type NotesUpdate struct {
-	Title    string `json:"title" `
-	Category string `json:"category"`
-	Note     string `json:"note"`
+	Title     string               `json:"title" `
+	Category  string               `json:"category"`
+	Note      string               `json:"note"`
+	Format    string               `json:"format" bson:"format"`
+	Tags      []primitive.ObjectID `json:"tags" bson:"tags"`
+	UpdatedAt time.Time            `json:"-" bson:"updated_at"`
+	UpdatedBy *primitive.ObjectID  `json:"-" bson:"updated_by,omitempty"`
 }
 
 type NotesCreate struct {
-	Title    string             `json:"title" `
-	Category string             `json:"category"`
-	Note     string             `json:"note"`
-	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Title     string               `json:"title" `
+	Category  string               `json:"category"`
+	Note      string               `json:"note"`
+	Format    string               `json:"format" bson:"format"`
+	Tags      []primitive.ObjectID `json:"tags" bson:"tags"`
+	Version   int                  `json:"-" bson:"version"`
+	UserID    primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time            `json:"-" bson:"created_at"`
+	UpdatedAt time.Time            `json:"-" bson:"updated_at"`
+	CreatedBy *primitive.ObjectID  `json:"-" bson:"created_by,omitempty"`
+	UpdatedBy *primitive.ObjectID  `json:"-" bson:"updated_by,omitempty"`
 }
 type NotesRequest struct {
-	Title    string `json:"title" `
-	Category string `json:"category"`
-	Note     string `json:"note"`
+	Title    string               `json:"title" `
+	Category string               `json:"category"`
+	Note     string               `json:"note"`
+	Format   string               `json:"format"`
+	Tags     []primitive.ObjectID `json:"tags"`
+}
+
+// RenderedNote is a note together with its body rendered for display, sent
+// when a client asks for ?render=html.
+type RenderedNote struct {
+	*Notes
+	NoteRendering
+}
+
+// NoteRendering is the display form of a note body.
+type NoteRendering struct {
+	HTML      string    `json:"html"` // Sanitized HTML, safe to insert into a page
+	TOC       []Heading `json:"toc"`
+	Checklist Checklist `json:"checklist"`
+}
+
+// Heading is an entry of a note's table of contents. ID is the anchor of the
+// heading in the rendered HTML.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// Checklist gathers the "- [ ]" and "- [x]" items of a note.
+type Checklist struct {
+	Items []ChecklistItem `json:"items"`
+	Done  int             `json:"done"`
+	Total int             `json:"total"`
+}
+
+// ChecklistItem is one checkbox line; Line is 1-based.
+type ChecklistItem struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	Line    int    `json:"line"`
 }
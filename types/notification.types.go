@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The kinds of notifications.
+const (
+	NotificationTaskAssigned = "task_assigned"
+	NotificationMentioned    = "mentioned"
+)
+
+// Notification tells a user about something another user did to one of the
+// items they work on.
+type Notification struct {
+	Id        primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID  `json:"user_id" bson:"user_id"` // The recipient
+	Type      string              `json:"type" bson:"type"`
+	ItemType  string              `json:"item_type" bson:"item_type"` // ShareNote or ShareTask
+	ItemID    primitive.ObjectID  `json:"item_id" bson:"item_id"`
+	ActorID   *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"` // The user who caused it
+	Message   string              `json:"message" bson:"message"`
+	ReadAt    *time.Time          `json:"read_at,omitempty" bson:"read_at,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
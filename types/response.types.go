@@ -13,10 +13,11 @@ type ErrorResponse struct {
 
 // APIResponse represents a structured response for all API endpoints.
 type APIResponse struct {
-	Error   bool        `json:"error"`          // Indicates if there was an error
-	Code    int         `json:"code"`           // HTTP status code
-	Message string      `json:"message"`        // Success or error message
-	Data    interface{} `json:"data,omitempty"` // Optional data (for success responses)
+	Error      bool        `json:"error"`                // Indicates if there was an error
+	Code       int         `json:"code"`                 // HTTP status code
+	Message    string      `json:"message"`              // Success or error message
+	Data       interface{} `json:"data,omitempty"`       // Optional data (for success responses)
+	Pagination *Pagination `json:"pagination,omitempty"` // Set by paginated list endpoints
 }
 
 // CreateSuccessResponse generates a success response.
@@ -29,6 +30,13 @@ func CreateSuccessResponse(message string, statusCode int, data interface{}) API
 	}
 }
 
+// CreatePaginatedResponse generates a success response for one page of a list.
+func CreatePaginatedResponse(message string, statusCode int, data interface{}, pagination *Pagination) APIResponse {
+	response := CreateSuccessResponse(message, statusCode, data)
+	response.Pagination = pagination
+	return response
+}
+
 // CreateErrorResponse generates an error response.
 func CreateErrorResponse(message string, statusCode int, data interface{}) APIResponse {
 	return APIResponse{
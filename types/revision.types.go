@@ -0,0 +1,49 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NoteRevision is an earlier version of a note, saved each time the note is
+// updated.
+type NoteRevision struct {
+	Id        primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	NoteID    primitive.ObjectID   `json:"note_id" bson:"note_id"`
+	Version   int                  `json:"version" bson:"version"`
+	Title     string               `json:"title"`
+	Category  string               `json:"category"`
+	Note      string               `json:"note"`
+	Format    string               `json:"format" bson:"format"`
+	Tags      []primitive.ObjectID `json:"tags" bson:"tags"`
+	EditedAt  time.Time            `json:"edited_at" bson:"edited_at"`                     // When this version was written
+	EditedBy  *primitive.ObjectID  `json:"edited_by,omitempty" bson:"edited_by,omitempty"` // Who wrote this version
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`                   // When it was replaced by a newer version
+}
+
+// DiffLine is one line of a line-level diff. Op is "equal", "insert" or
+// "delete"; OldLine and NewLine are 1-based and zero on the side the line
+// does not exist.
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// FieldChange is the before and after value of a single-line field.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NoteDiff compares two versions of a note.
+type NoteDiff struct {
+	NoteID   primitive.ObjectID `json:"note_id"`
+	From     int                `json:"from"`
+	To       int                `json:"to"`
+	Title    *FieldChange       `json:"title,omitempty"`
+	Category *FieldChange       `json:"category,omitempty"`
+	Lines    []DiffLine         `json:"lines"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// SearchQuery describes a full-text search over the caller's notes and tasks.
+type SearchQuery struct {
+	Text     string     // Words to look for in the title, body and category
+	Type     string     // "notes", "tasks" or empty for both
+	Category string     // Only items in this category
+	From     *time.Time // Only items created at or after this time
+	To       *time.Time // Only items created before this time
+	Limit    int        // Maximum number of results
+}
+
+// SearchResult is one note or task matching a search, best matches first.
+type SearchResult struct {
+	Type       string            `json:"type"`                 // "note" or "task"
+	Score      float64           `json:"score"`                // Relevance, higher is better
+	Highlights map[string]string `json:"highlights,omitempty"` // Matching snippets by field, terms wrapped in <mark>
+	Note       *Notes            `json:"note,omitempty"`
+	Task       *Tasks            `json:"task,omitempty"`
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission is the access a user has to a note or task.
+type Permission string
+
+const (
+	PermissionView  Permission = "view"  // Read the item
+	PermissionEdit  Permission = "edit"  // Read and update the item
+	PermissionOwner Permission = "owner" // Everything, including deleting and sharing the item
+)
+
+// Allows reports whether p grants at least the access of required.
+func (p Permission) Allows(required Permission) bool {
+	rank := map[Permission]int{PermissionView: 1, PermissionEdit: 2, PermissionOwner: 3}
+	return rank[p] > 0 && rank[p] >= rank[required]
+}
+
+// The kinds of items that can be shared.
+const (
+	ShareNote = "note"
+	ShareTask = "task"
+)
+
+// Share grants a user access to a note or task owned by someone else.
+type Share struct {
+	Id         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	ItemType   string              `json:"item_type" bson:"item_type"` // ShareNote or ShareTask
+	ItemID     primitive.ObjectID  `json:"item_id" bson:"item_id"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"` // The user the item is shared with
+	Permission Permission          `json:"permission" bson:"permission"`
+	User       *UserResponse       `json:"user,omitempty" bson:"-"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+	CreatedBy  *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy  *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+// ShareRequest shares an item with the user owning the email. Sharing again
+// with the same user changes the permission.
+type ShareRequest struct {
+	Email      string     `json:"email"`
+	Permission Permission `json:"permission"`
+}
+
+// SharedItem is a note or task another user shared with the caller.
+type SharedItem struct {
+	Type       string     `json:"type"` // ShareNote or ShareTask
+	Permission Permission `json:"permission"`
+	SharedAt   time.Time  `json:"shared_at"`
+	Note       *Notes     `json:"note,omitempty"`
+	Task       *Tasks     `json:"task,omitempty"`
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tag is a user-defined label that can be attached to any number of notes
+// and tasks. Items reference tags by ID, so renaming a tag renames it
+// everywhere it is used.
+type Tag struct {
+	Id         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name       string              `json:"name"`
+	Color      string              `json:"color"`
+	Key        string              `json:"-" bson:"key"` // Normalized name, unique per user
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	NotesCount *int64              `json:"notes_count,omitempty" bson:"-"`
+	TasksCount *int64              `json:"tasks_count,omitempty" bson:"-"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+	CreatedBy  *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy  *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+type TagCreate struct {
+	Name      string              `json:"name"`
+	Color     string              `json:"color"`
+	Key       string              `json:"-" bson:"key"`
+	UserID    primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time           `json:"-" bson:"created_at"`
+	UpdatedAt time.Time           `json:"-" bson:"updated_at"`
+	CreatedBy *primitive.ObjectID `json:"-" bson:"created_by,omitempty"`
+	UpdatedBy *primitive.ObjectID `json:"-" bson:"updated_by,omitempty"`
+}
+
+type TagUpdate struct {
+	Name      string              `json:"name"`
+	Color     string              `json:"color"`
+	Key       string              `json:"-" bson:"key"`
+	UpdatedAt time.Time           `json:"-" bson:"updated_at"`
+	UpdatedBy *primitive.ObjectID `json:"-" bson:"updated_by,omitempty"`
+}
+
+type TagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TagMergeRequest struct {
+	Into primitive.ObjectID `json:"into"` // Tag that replaces the merged one
+}
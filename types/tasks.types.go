@@ -1,38 +1,314 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Tasks struct {
-	Id            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Title         string             `json:"title" `
-	Category      string             `json:"category"`
-	Task          string             `json:"task"`
-	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
-	StatusHistory []*Status          `json:"status_history"`
+	Id            primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Title         string               `json:"title" `
+	Category      string               `json:"category"`
+	Task          string               `json:"task"`
+	UserID        primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Assignees     []primitive.ObjectID `json:"assignees" bson:"assignees"`                     // Users working on the task besides its owner
+	ParentID      *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"` // Set on subtasks
+	Ancestors     []primitive.ObjectID `json:"ancestors" bson:"ancestors"`                     // From the top-level task down to the parent
+	Checklist     []*TaskChecklistItem `json:"checklist" bson:"checklist"`
+	AutoComplete  bool                 `json:"auto_complete" bson:"auto_complete"` // Complete the task once all its subtasks and checklist items are done
+	Progress      *TaskProgress        `json:"progress,omitempty" bson:"-"`        // Only computed where documented
+	BlockedBy     []primitive.ObjectID `json:"blocked_by" bson:"blocked_by"`       // Tasks that must be done before this one starts
+	Blocked       bool                 `json:"blocked,omitempty" bson:"-"`         // Some of BlockedBy is not done; only computed where documented
+	CommentCount  *int64               `json:"comment_count,omitempty" bson:"-"`   // Only computed where documented
+	TimeSpent     *int64               `json:"time_spent,omitempty" bson:"-"`      // Seconds tracked; only computed where documented
+	Recurrence    *TaskRecurrence      `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	Rank          string               `json:"rank" bson:"rank"`                     // Position on the owner's board, compared as a string
+	CurrentStatus string               `json:"current_status" bson:"current_status"` // Always the last entry of StatusHistory
+	StatusHistory []*Status            `json:"status_history"`
+	Tags          []primitive.ObjectID `json:"tags" bson:"tags"`
+	Priority      Priority             `json:"priority" bson:"priority"`
+	DueAt         *time.Time           `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	DueTimezone   string               `json:"due_timezone,omitempty" bson:"due_timezone,omitempty"` // IANA zone the due time was set in
+	StartAt       *time.Time           `json:"start_at,omitempty" bson:"start_at,omitempty"`
+	DueSort       time.Time            `json:"-" bson:"due_sort"` // DueAt, or NoDueDate, so tasks can be sorted and filtered on it
+	DeletedAt     *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	CreatedAt     time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at" bson:"updated_at"`
+	CreatedBy     *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
 }
 
 type TasksUpdate struct {
-	Title         string    `json:"title" `
-	Category      string    `json:"category"`
-	Task          string    `json:"task"`
-	StatusHistory []*Status `json:"status_history"`
+	Title           string               `json:"title" `
+	Category        string               `json:"category"`
+	Task            string               `json:"task"`
+	CurrentStatus   string               `json:"-" bson:"current_status,omitempty"` // Set from Status by the store
+	Status          *Status              `json:"-" bson:"-"`                        // Appended to the status history when set
+	Tags            []primitive.ObjectID `json:"tags" bson:"tags"`
+	Priority        Priority             `json:"priority" bson:"priority"`
+	DueAt           *time.Time           `json:"due_at" bson:"due_at"`
+	DueTimezone     string               `json:"due_timezone" bson:"due_timezone"`
+	StartAt         *time.Time           `json:"start_at" bson:"start_at"`
+	DueSort         time.Time            `json:"-" bson:"due_sort"`
+	AutoComplete    bool                 `json:"auto_complete" bson:"auto_complete"`
+	Recurrence      *TaskRecurrence      `json:"recurrence" bson:"recurrence,omitempty"` // Replaces the recurrence when set
+	ClearRecurrence bool                 `json:"-" bson:"-"`                             // Removes the recurrence
+	UpdatedAt       time.Time            `json:"-" bson:"updated_at"`
+	UpdatedBy       *primitive.ObjectID  `json:"-" bson:"updated_by,omitempty"`
 }
 
 type TasksCreate struct {
-	Title         string             `json:"title" `
-	Category      string             `json:"category"`
-	Task          string             `json:"task"`
-	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
-	StatusHistory []*Status          `json:"status_history"`
+	Id            primitive.ObjectID   `json:"-" bson:"_id,omitempty"` // Generated unless set
+	Title         string               `json:"title" `
+	Category      string               `json:"category"`
+	Task          string               `json:"task"`
+	UserID        primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Assignees     []primitive.ObjectID `json:"assignees" bson:"assignees"`
+	ParentID      *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Ancestors     []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+	Checklist     []*TaskChecklistItem `json:"checklist" bson:"checklist"`
+	AutoComplete  bool                 `json:"auto_complete" bson:"auto_complete"`
+	BlockedBy     []primitive.ObjectID `json:"blocked_by" bson:"blocked_by"`
+	Recurrence    *TaskRecurrence      `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	Rank          string               `json:"-" bson:"rank"` // After every task of the owner unless set
+	CurrentStatus string               `json:"current_status" bson:"current_status"`
+	StatusHistory []*Status            `json:"status_history"`
+	Tags          []primitive.ObjectID `json:"tags" bson:"tags"`
+	Priority      Priority             `json:"priority" bson:"priority"`
+	DueAt         *time.Time           `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	DueTimezone   string               `json:"due_timezone,omitempty" bson:"due_timezone,omitempty"`
+	StartAt       *time.Time           `json:"start_at,omitempty" bson:"start_at,omitempty"`
+	DueSort       time.Time            `json:"-" bson:"due_sort"`
+	CreatedAt     time.Time            `json:"-" bson:"created_at"`
+	UpdatedAt     time.Time            `json:"-" bson:"updated_at"`
+	CreatedBy     *primitive.ObjectID  `json:"-" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID  `json:"-" bson:"updated_by,omitempty"`
 }
 type TasksRequest struct {
-	Title    string `json:"title" `
-	Category string `json:"category"`
-	Task     string `json:"task"`
-	Status   string `json:"status"`
+	Title         string               `json:"title" `
+	Category      string               `json:"category"`
+	Task          string               `json:"task"`
+	Status        string               `json:"status"`         // Must be allowed by the workflow of the task's owner
+	StatusComment string               `json:"status_comment"` // Recorded with the status change
+	Tags          []primitive.ObjectID `json:"tags"`
+	Priority      string               `json:"priority"`     // One of the PriorityNames
+	DueAt         *string              `json:"due_at"`       // RFC 3339 time, or a date meaning the end of that day; "" clears it
+	DueTimezone   string               `json:"due_timezone"` // IANA zone of due_at and start_at, UTC by default
+	StartAt       *string              `json:"start_at"`     // RFC 3339 time, or a date meaning the start of that day; "" clears it
+	AutoComplete  *bool                `json:"auto_complete"`
+	Recurrence    *string              `json:"recurrence"` // RRULE such as FREQ=WEEKLY;BYDAY=MO; "" stops the series at this task
+}
+
+// OnlyStatus reports whether the request changes nothing but the status, which
+// is all the assignees of a task may do.
+func (r *TasksRequest) OnlyStatus() bool {
+	return r.Status != "" && r.Title == "" && r.Category == "" && r.Task == "" && r.Tags == nil &&
+		r.Priority == "" && r.DueAt == nil && r.DueTimezone == "" && r.StartAt == nil && r.AutoComplete == nil && r.Recurrence == nil
+}
+
+// TaskBoard shows the tasks of a user as columns, one per status.
+type TaskBoard struct {
+	Columns []*BoardColumn `json:"columns"`
+}
+
+// BoardColumn holds the tasks in one status, in board order. Columns for
+// statuses the workflow no longer has come after the others, and take no
+// tasks in.
+type BoardColumn struct {
+	Status    string   `json:"status"`
+	Done      bool     `json:"done"`
+	Known     bool     `json:"known"`                // The status is part of the workflow
+	WIPLimit  int      `json:"wip_limit,omitempty"`  // Most tasks the column takes in; 0 means no limit
+	OverLimit bool     `json:"over_limit,omitempty"` // The column holds more tasks than its limit
+	Count     int      `json:"count"`
+	Tasks     []*Tasks `json:"tasks"`
+}
+
+// BoardMoveRequest moves a task on the board of its owner, to another column
+// when status is set, right after the task after_id or right before the task
+// before_id of that column. Without either the task goes to the end of the
+// column.
+type BoardMoveRequest struct {
+	Status        string              `json:"status"`
+	StatusComment string              `json:"status_comment"`
+	AfterID       *primitive.ObjectID `json:"after_id"`
+	BeforeID      *primitive.ObjectID `json:"before_id"`
+}
+
+// MaxTaskAssignees is the most users a task can be assigned to.
+const MaxTaskAssignees = 20
+
+// AssignRequest assigns a task to the user owning the email.
+type AssignRequest struct {
+	Email string `json:"email"`
+}
+
+// MaxSubtaskDepth is how many levels subtasks can nest below a top-level task.
+const MaxSubtaskDepth = 3
+
+// MoveTaskRequest moves a task, with its subtasks, under another task of the
+// same owner. A null parent_id makes it a top-level task.
+type MoveTaskRequest struct {
+	ParentID *primitive.ObjectID `json:"parent_id"`
+}
+
+// TaskRecurrence makes a task one occurrence of a series that repeats by an
+// RRULE, in the time zone of the task. Each occurrence is a task of its own,
+// created when the one before it is completed or when its time comes.
+// Occurrences missed while a series was behind are skipped. Rescheduling an
+// occurrence through its due_at moves that occurrence only: the series keeps
+// following the rule from At.
+type TaskRecurrence struct {
+	Rule       string              `json:"rule" bson:"rule"`                           // Canonical RRULE, such as FREQ=WEEKLY;BYDAY=MO
+	Start      time.Time           `json:"start" bson:"start"`                         // When the first occurrence was scheduled
+	Occurrence int                 `json:"occurrence" bson:"occurrence"`               // Position of this occurrence in the series, from 1
+	At         time.Time           `json:"at" bson:"at"`                               // When the rule scheduled this occurrence
+	NextAt     *time.Time          `json:"next_at,omitempty" bson:"next_at,omitempty"` // When the next occurrence is scheduled; unset once the series ends
+	NextID     *primitive.ObjectID `json:"next_id,omitempty" bson:"next_id,omitempty"` // The next occurrence once it is created
+}
+
+// MaxTaskBlockers is the most tasks a task can be blocked by.
+const MaxTaskBlockers = 50
+
+// DependencyRequest makes a task wait for the task in blocker_id.
+type DependencyRequest struct {
+	BlockerID primitive.ObjectID `json:"blocker_id"`
+}
+
+// TaskDependencies are the tasks a task waits for and the tasks waiting for it.
+type TaskDependencies struct {
+	Blocked   bool     `json:"blocked"`
+	BlockedBy []*Tasks `json:"blocked_by"`
+	Blocks    []*Tasks `json:"blocks"`
+}
+
+// DependencyGraph is the dependency graph of the tasks of a user. Tasks lists
+// the tasks with dependencies in topological order, blockers first, and each
+// edge goes from a blocker to the task it blocks.
+type DependencyGraph struct {
+	Tasks []*Tasks          `json:"tasks"`
+	Edges []*DependencyEdge `json:"edges"`
+}
+
+// DependencyEdge says that the From task blocks the To task.
+type DependencyEdge struct {
+	From primitive.ObjectID `json:"from"`
+	To   primitive.ObjectID `json:"to"`
+}
+
+// TaskProgress is how far along a task is, computed from its direct subtasks
+// and checklist items. Percent counts a checklist item as done or not, and a
+// subtask as done in a done status or by its own progress otherwise.
+type TaskProgress struct {
+	Subtasks       int `json:"subtasks"`
+	SubtasksDone   int `json:"subtasks_done"`
+	ChecklistItems int `json:"checklist_items"`
+	ChecklistDone  int `json:"checklist_done"`
+	Percent        int `json:"percent"` // 0 to 100
+}
+
+// Done reports whether every subtask and checklist item is done. Tasks
+// without either are never done by their progress.
+func (p *TaskProgress) Done() bool {
+	return p.Subtasks+p.ChecklistItems > 0 && p.SubtasksDone == p.Subtasks && p.ChecklistDone == p.ChecklistItems
+}
+
+// HasAssignee reports whether the task is assigned to the user.
+func (t *Tasks) HasAssignee(userId primitive.ObjectID) bool {
+	return slices.Contains(t.Assignees, userId)
+}
+
+// NoDueDate is the due sort value of tasks without a due date, so they come
+// after every dated task.
+var NoDueDate = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// Priority ranks tasks, from PriorityNone to PriorityUrgent. It is stored as
+// a number so tasks sort by it, and written as its name in JSON.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+// PriorityNames lists the names of the priorities, lowest first.
+var PriorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority returns the priority with the given name.
+func ParsePriority(name string) (Priority, error) {
+	for i, priority := range PriorityNames {
+		if name == priority {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("priority must be one of %s", strings.Join(PriorityNames, ", "))
+}
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(PriorityNames) {
+		return PriorityNames[PriorityNone]
+	}
+	return PriorityNames[p]
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON accepts a priority name or its number.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var rank int
+		if err := json.Unmarshal(data, &rank); err != nil {
+			return err
+		}
+		*p = Priority(rank)
+		return nil
+	}
+	priority, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = priority
+	return nil
+}
+
+// MaxChecklistItems is the most checklist items a task can have, and
+// MaxChecklistTextLen the longest text of an item.
+const (
+	MaxChecklistItems   = 100
+	MaxChecklistTextLen = 200
+)
+
+// TaskChecklistItem is a step of a task too small to be a subtask.
+type TaskChecklistItem struct {
+	Id     primitive.ObjectID  `json:"id" bson:"_id"`
+	Text   string              `json:"text" bson:"text"`
+	Done   bool                `json:"done" bson:"done"`
+	DoneAt *time.Time          `json:"done_at,omitempty" bson:"done_at,omitempty"`
+	DoneBy *primitive.ObjectID `json:"done_by,omitempty" bson:"done_by,omitempty"`
+}
+
+// TaskChecklistItemRequest adds a checklist item, or changes the fields it sets.
+type TaskChecklistItemRequest struct {
+	Text *string `json:"text"`
+	Done *bool   `json:"done"`
 }
 
+// Status is one entry of the status history of a task.
 type Status struct {
-	Status string `json:"status"`
-	UserId string `json:"user_id" bson:"user_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty" bson:"previous_status,omitempty"` // Empty on the first entry
+	Comment        string    `json:"comment,omitempty" bson:"comment,omitempty"`
+	UserId         string    `json:"user_id" bson:"user_id"`
+	ChangedAt      time.Time `json:"changed_at" bson:"changed_at"`
 }
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxTimeEntryDuration is the longest manual time entry accepted.
+	MaxTimeEntryDuration = 24 * time.Hour
+
+	// MaxTimeReportRange is the longest date range a time report covers.
+	MaxTimeReportRange = 366 * 24 * time.Hour
+
+	// MaxTimeEntryNoteLength is the longest note kept with a time entry, in
+	// characters.
+	MaxTimeEntryNoteLength = 500
+)
+
+// TimeEntry is time a user spent on a task, either timed with a timer or
+// entered by hand. Durations are in seconds.
+type TimeEntry struct {
+	Id        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TaskID    primitive.ObjectID `json:"task_id" bson:"task_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"` // Who spent the time
+	StartedAt time.Time          `json:"started_at" bson:"started_at"`
+	EndedAt   *time.Time         `json:"ended_at,omitempty" bson:"ended_at,omitempty"` // Unset while the timer runs
+	Running   bool               `json:"running" bson:"running"`                       // A user has at most one running timer
+	Duration  int64              `json:"duration" bson:"duration"`                     // 0 while the timer runs
+	Manual    bool               `json:"manual" bson:"manual"`                         // Entered by hand rather than timed
+	Note      string             `json:"note" bson:"note"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// TimerRequest starts or stops a timer. A note given when stopping replaces
+// the one given when starting.
+type TimerRequest struct {
+	Note *string `json:"note"`
+}
+
+// TimeEntryRequest enters time by hand, or changes an entry. Times are RFC
+// 3339; the end can be given as a duration in seconds instead.
+type TimeEntryRequest struct {
+	StartedAt string  `json:"started_at"`
+	EndedAt   string  `json:"ended_at"`
+	Duration  int64   `json:"duration"`
+	Note      *string `json:"note"`
+}
+
+// TaskTime is the time spent on a task: its entries, oldest first, and the
+// total of those that ended.
+type TaskTime struct {
+	Total   int64        `json:"total"`
+	Entries []*TimeEntry `json:"entries"`
+}
+
+// The formats of a time report.
+const (
+	TimeReportJSON = "json"
+	TimeReportCSV  = "csv"
+)
+
+// TimeReportQuery selects the time entries of a report: those that ended and
+// started in [From, To), optionally of one user or one task category.
+type TimeReportQuery struct {
+	From     time.Time
+	To       time.Time
+	UserID   *primitive.ObjectID
+	Category *string
+}
+
+// TimeReport adds up the time spent per user and task category.
+type TimeReport struct {
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Total   int64            `json:"total"` // Seconds
+	Hours   float64          `json:"hours"`
+	Entries int              `json:"entries"`
+	Rows    []*TimeReportRow `json:"rows"`
+}
+
+// TimeReportRow is the time a user spent on the tasks of a category.
+type TimeReportRow struct {
+	UserID    primitive.ObjectID `json:"user_id"`
+	UserName  string             `json:"user_name"`
+	UserEmail string             `json:"user_email"`
+	Category  string             `json:"category"`
+	Total     int64              `json:"total"` // Seconds
+	Hours     float64            `json:"hours"` // Rounded to hundredths
+	Entries   int                `json:"entries"`
+}
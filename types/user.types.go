@@ -1,6 +1,10 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Login struct {
 	Email    string `json:"email"`
@@ -8,41 +12,69 @@ type Login struct {
 }
 
 type User struct {
-	Id             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name           string             `json:"name" `
-	Email          string             `json:"email"`
-	Password       string             `json:"password"`
-	Role           string             `json:"role"`
-	Notes          []*Notes           `json:"notes"`
-	Tasks          []*Tasks           `json:"tasks"`
-	ProfilePicture string             `json:"profile_picture" bson:"profile_picture"`
-	SocialMedia    SocialMedia        `json:"social_media"    bson:"social_media"`
+	Id             primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name           string              `json:"name" `
+	Email          string              `json:"email"`
+	Password       string              `json:"password"`
+	Role           string              `json:"role"`
+	Notes          []*Notes            `json:"notes,omitempty" bson:"-"`
+	Tasks          []*Tasks            `json:"tasks,omitempty" bson:"-"`
+	NotesCount     *int64              `json:"notes_count,omitempty" bson:"-"`
+	TasksCount     *int64              `json:"tasks_count,omitempty" bson:"-"`
+	ProfilePicture string              `json:"profile_picture" bson:"profile_picture"`
+	SocialMedia    SocialMedia         `json:"social_media"    bson:"social_media"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at" bson:"updated_at"`
+	CreatedBy      *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy      *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
 }
 type UserUpdate struct {
-	Name           string      `json:"name" `
-	Email          string      `json:"email"`
-	ProfilePicture string      `json:"profile_picture" bson:"profile_picture"`
-	SocialMedia    SocialMedia `json:"social_media"    bson:"social_media"`
+	Name           string              `json:"name" `
+	Email          string              `json:"email"`
+	ProfilePicture string              `json:"profile_picture" bson:"profile_picture"`
+	SocialMedia    SocialMedia         `json:"social_media"    bson:"social_media"`
+	UpdatedAt      time.Time           `json:"-" bson:"updated_at"`
+	UpdatedBy      *primitive.ObjectID `json:"-" bson:"updated_by,omitempty"`
 }
 type UserResponse struct {
-	Id             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name           string             `json:"name" `
-	Email          string             `json:"email"`
-	Notes          []*Notes           `json:"notes"`
-	Tasks          []*Tasks           `json:"tasks"`
-	ProfilePicture string             `json:"profile_picture" bson:"profile_picture"`
-	SocialMedia    SocialMedia        `json:"social_media"    bson:"social_media"`
+	Id             primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name           string              `json:"name" `
+	Email          string              `json:"email"`
+	Notes          []*Notes            `json:"notes,omitempty" bson:"-"`
+	Tasks          []*Tasks            `json:"tasks,omitempty" bson:"-"`
+	NotesCount     *int64              `json:"notes_count,omitempty" bson:"-"`
+	TasksCount     *int64              `json:"tasks_count,omitempty" bson:"-"`
+	ProfilePicture string              `json:"profile_picture" bson:"profile_picture"`
+	SocialMedia    SocialMedia         `json:"social_media"    bson:"social_media"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at" bson:"updated_at"`
+	CreatedBy      *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy      *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+// UserInclude selects the related documents embedded in user responses.
+// Users always carry their note and task counts.
+type UserInclude struct {
+	Notes bool
+	Tasks bool
 }
+
 type UserRequest struct {
 	Name     string `json:"name" `
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 type UserCreate struct {
-	Name     string `json:"name" `
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	Name      string              `json:"name" `
+	Email     string              `json:"email"`
+	Password  string              `json:"password"`
+	Role      string              `json:"role"`
+	CreatedAt time.Time           `json:"-" bson:"created_at"`
+	UpdatedAt time.Time           `json:"-" bson:"updated_at"`
+	CreatedBy *primitive.ObjectID `json:"-" bson:"created_by,omitempty"`
+	UpdatedBy *primitive.ObjectID `json:"-" bson:"updated_by,omitempty"`
 	// ProfilePicture string      `json:"profile_picture" bson:"profile_picture"`
 	// SocialMedia    SocialMedia `json:"social_media"    bson:"social_media"`
 }
@@ -59,3 +91,30 @@ type SocialMedia struct {
 	Discord   string `json:"discord"   bson:"discord"`
 	Website   string `json:"website"   bson:"website"`
 }
+
+// AvatarUpload is the result of uploading an avatar. Variants maps each
+// generated size, in pixels, to its profile picture value.
+type AvatarUpload struct {
+	User     *UserResponse  `json:"user"`
+	Variants map[int]string `json:"variants"`
+}
+
+// UserDeletionReport summarises what was trashed, removed or reassigned when a
+// user was deleted or purged.
+type UserDeletionReport struct {
+	User             *UserResponse       `json:"user"`
+	NotesDeleted     int64               `json:"notes_deleted"`
+	TasksDeleted     int64               `json:"tasks_deleted"`
+	TransferredTo    *primitive.ObjectID `json:"transferred_to,omitempty"`
+	NotesTransferred int64               `json:"notes_transferred"`
+	TasksTransferred int64               `json:"tasks_transferred"`
+	AvatarRemoved    bool                `json:"avatar_removed"`
+}
+
+// TrashPurgeReport summarises a purge of items that stayed in the trash past the retention period.
+type TrashPurgeReport struct {
+	Users  []*UserDeletionReport `json:"users"`
+	Notes  int64                 `json:"notes"`
+	Tasks  int64                 `json:"tasks"`
+	Before time.Time             `json:"before"`
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxWorkflowStatuses is the most statuses a workflow can have.
+const MaxWorkflowStatuses = 30
+
+// workflowStatus is the shape of a status name. Dots and dollar signs are
+// left out because statuses are used as document keys.
+var workflowStatus = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]{0,39}$`)
+
+// Workflow is the set of statuses the tasks of a user move through.
+// Transitions lists, for each status, the statuses a task may move to next;
+// any other move is rejected.
+type Workflow struct {
+	Id          primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID  `json:"user_id" bson:"user_id"` // The owner of the tasks it applies to
+	Initial     string              `json:"initial" bson:"initial"` // Status of tasks created without one
+	Statuses    []string            `json:"statuses" bson:"statuses"`
+	Transitions map[string][]string `json:"transitions" bson:"transitions"`
+	Done        []string            `json:"done" bson:"done"`                                 // Statuses in which a task counts as completed
+	WIPLimits   map[string]int      `json:"wip_limits,omitempty" bson:"wip_limits,omitempty"` // Most tasks a board column takes in, by status
+	Default     bool                `json:"default" bson:"-"`                                 // The built-in workflow, in use until the user sets their own
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+	CreatedBy   *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy   *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+// WorkflowRequest replaces the workflow of the logged-in user. Initial
+// defaults to the first status and Done to the statuses a task cannot move
+// out of, or the last status when there are none.
+type WorkflowRequest struct {
+	Initial     string              `json:"initial"`
+	Statuses    []string            `json:"statuses"`
+	Transitions map[string][]string `json:"transitions"`
+	Done        []string            `json:"done"`
+	WIPLimits   map[string]int      `json:"wip_limits"`
+}
+
+// Validate checks that the statuses are unique and well formed, and that the
+// initial status, every transition and every WIP limit only refer to them.
+func (w *Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return fmt.Errorf("statuses must not be empty")
+	}
+	if len(w.Statuses) > MaxWorkflowStatuses {
+		return fmt.Errorf("a workflow can have at most %d statuses", MaxWorkflowStatuses)
+	}
+	for i, status := range w.Statuses {
+		if !workflowStatus.MatchString(status) {
+			return fmt.Errorf("status %q must be 1 to 40 letters, digits, spaces, dashes or underscores", status)
+		}
+		if slices.Contains(w.Statuses[:i], status) {
+			return fmt.Errorf("status %q is listed twice", status)
+		}
+	}
+	if !w.HasStatus(w.Initial) {
+		return fmt.Errorf("initial status %q is not one of the statuses", w.Initial)
+	}
+	for i, status := range w.Done {
+		if !w.HasStatus(status) {
+			return fmt.Errorf("done status %q is not one of the statuses", status)
+		}
+		if slices.Contains(w.Done[:i], status) {
+			return fmt.Errorf("done status %q is listed twice", status)
+		}
+	}
+	for from, targets := range w.Transitions {
+		if !w.HasStatus(from) {
+			return fmt.Errorf("transitions from %q: not one of the statuses", from)
+		}
+		for i, to := range targets {
+			switch {
+			case !w.HasStatus(to):
+				return fmt.Errorf("transition from %q to %q: %q is not one of the statuses", from, to, to)
+			case to == from:
+				return fmt.Errorf("transition from %q to itself is not allowed", from)
+			case slices.Contains(targets[:i], to):
+				return fmt.Errorf("transition from %q to %q is listed twice", from, to)
+			}
+		}
+	}
+	for status, limit := range w.WIPLimits {
+		if !w.HasStatus(status) {
+			return fmt.Errorf("WIP limit of %q: not one of the statuses", status)
+		}
+		if limit < 1 {
+			return fmt.Errorf("WIP limit of %q must be at least 1", status)
+		}
+	}
+	return nil
+}
+
+// HasStatus reports whether status is part of the workflow.
+func (w *Workflow) HasStatus(status string) bool {
+	return slices.Contains(w.Statuses, status)
+}
+
+// IsDone reports whether a task in status counts as completed.
+func (w *Workflow) IsDone(status string) bool {
+	return slices.Contains(w.Done, status)
+}
+
+// IsInProgress reports whether a task in status is being worked on, that is
+// neither in the initial status nor done.
+func (w *Workflow) IsInProgress(status string) bool {
+	return status != w.Initial && !w.IsDone(status)
+}
+
+// CheckStatus returns an error naming the valid statuses when status is not
+// part of the workflow.
+func (w *Workflow) CheckStatus(status string) error {
+	if !w.HasStatus(status) {
+		return fmt.Errorf("unknown status %q; valid statuses: %s", status, strings.Join(w.Statuses, ", "))
+	}
+	return nil
+}
+
+// CheckTransition returns an error explaining why a task cannot move from one
+// status to another. Tasks whose status is not part of the workflow, such as
+// tasks created before it was changed, may move to any of its statuses.
+func (w *Workflow) CheckTransition(from, to string) error {
+	if err := w.CheckStatus(to); err != nil {
+		return err
+	}
+	if from == to || !w.HasStatus(from) {
+		return nil
+	}
+	allowed := w.Transitions[from]
+	if slices.Contains(allowed, to) {
+		return nil
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("cannot move task from %q to %q; %q is a final status", from, to, from)
+	}
+	return fmt.Errorf("cannot move task from %q to %q; allowed: %s", from, to, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UserAvatarDir holds avatars uploaded by individual users. Stock avatars live
+// directly in uploads/avatar, are shared by everyone and are never removed.
+const UserAvatarDir = "uploads/avatar/users"
+
+// avatarVariant matches the files written by SaveUserAvatar.
+var avatarVariant = regexp.MustCompile(`^([0-9a-f]{32})-\d+\.(jpg|png)$`)
+
+// IsUserAvatar reports whether a profile picture is an uploaded avatar rather
+// than a stock one.
+func IsUserAvatar(profilePicture string) bool {
+	return strings.HasPrefix(profilePicture, "users/")
+}
+
+// RemoveUserAvatar deletes an uploaded avatar ("users/<file>") from disk,
+// along with the other sizes generated from the same upload, and reports
+// whether a file was removed.
+func RemoveUserAvatar(profilePicture string) bool {
+	name := strings.TrimPrefix(profilePicture, "users/")
+	if name == profilePicture || name == "" || name != filepath.Base(name) {
+		return false
+	}
+	files := []string{name}
+	if match := avatarVariant.FindStringSubmatch(name); match != nil {
+		variants, _ := filepath.Glob(filepath.Join(UserAvatarDir, match[1]+"-*."+match[2]))
+		files = files[:0]
+		for _, variant := range variants {
+			files = append(files, filepath.Base(variant))
+		}
+	}
+	removed := false
+	for _, file := range files {
+		if os.Remove(filepath.Join(UserAvatarDir, file)) == nil {
+			removed = true
+		}
+	}
+	return removed
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// AvatarSizes are the widths, in pixels, of the square variants generated for
+// an uploaded avatar. The largest one becomes the profile picture.
+var AvatarSizes = []int{64, 128, 256}
+
+// MaxAvatarBytes is the largest avatar upload accepted.
+const MaxAvatarBytes = 5 << 20
+
+// maxAvatarPixels bounds the decoded size of an avatar, so a small file that
+// claims huge dimensions cannot exhaust memory.
+const maxAvatarPixels = 25_000_000
+
+var (
+	ErrAvatarType       = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	ErrAvatarDimensions = errors.New("avatar image dimensions are too large")
+)
+
+// SaveUserAvatar checks that an upload is an image by looking at its contents,
+// crops it to a square and writes one variant per entry of AvatarSizes to
+// UserAvatarDir. Re-encoding drops EXIF and other metadata, after applying
+// the EXIF orientation of JPEG photos. Files are named after a hash of the
+// user and the upload ("<hash>-<size>.<ext>"). It returns the profile picture
+// value ("users/<file>") of every size.
+func SaveUserAvatar(userId string, data []byte) (map[int]string, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return nil, ErrAvatarType
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+	if config.Width*config.Height > maxAvatarPixels {
+		return nil, ErrAvatarDimensions
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+
+	// Scale the centered square once to the largest size; the smaller sizes
+	// are scaled down from that
+	largest := AvatarSizes[len(AvatarSizes)-1]
+	bounds := source.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(bounds.Min).Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	square := image.NewRGBA(image.Rect(0, 0, largest, largest))
+	draw.CatmullRom.Scale(square, square.Bounds(), source, crop, draw.Src, nil)
+	if format == "jpeg" {
+		square = orient(square, jpegOrientation(data))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(userId))
+	hash.Write(data)
+	name := hex.EncodeToString(hash.Sum(nil))[:32]
+	ext := "png"
+	if format == "jpeg" {
+		ext = "jpg"
+	}
+
+	if err := os.MkdirAll(UserAvatarDir, 0o755); err != nil {
+		return nil, err
+	}
+	pictures := map[int]string{}
+	for _, size := range AvatarSizes {
+		variant := square
+		if size != largest {
+			variant = image.NewRGBA(image.Rect(0, 0, size, size))
+			draw.CatmullRom.Scale(variant, variant.Bounds(), square, square.Bounds(), draw.Src, nil)
+		}
+		var buf bytes.Buffer
+		if ext == "jpg" {
+			err = jpeg.Encode(&buf, variant, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, variant)
+		}
+		if err != nil {
+			return nil, err
+		}
+		file := fmt.Sprintf("%s-%d.%s", name, size, ext)
+		if err := os.WriteFile(filepath.Join(UserAvatarDir, file), buf.Bytes(), 0o644); err != nil {
+			return nil, err
+		}
+		pictures[size] = "users/" + file
+	}
+	return pictures, nil
+}
+
+// jpegOrientation reads the EXIF orientation (1 to 8) of a JPEG file, or 1
+// when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		marker := data[i+1]
+		if data[i] != 0xFF || marker == 0xDA || marker == 0xD9 {
+			// The metadata segments all come before the image data
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF block.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 0 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient turns a square image the way its EXIF orientation asks for, so it
+// displays upright once the metadata is gone.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 {
+		return img
+	}
+	n := img.Bounds().Dx() - 1
+	oriented := image.NewRGBA(img.Bounds())
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Flip horizontally
+				sx, sy = n-x, y
+			case 3: // Rotate 180°
+				sx, sy = n-x, n-y
+			case 4: // Flip vertically
+				sx, sy = x, n-y
+			case 5: // Transpose
+				sx, sy = y, x
+			case 6: // Rotate 90° clockwise
+				sx, sy = y, n-x
+			case 7: // Transverse
+				sx, sy = n-y, n-x
+			case 8: // Rotate 90° counterclockwise
+				sx, sy = n-y, x
+			}
+			oriented.SetRGBA(x, y, img.RGBAAt(sx, sy))
+		}
+	}
+	return oriented
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"golang-auth/types"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to align lines. Bigger
+// changes are reported as the old text removed and the new text inserted.
+const maxDiffCells = 4_000_000
+
+// DiffLines compares two texts line by line and returns the lines kept,
+// removed and added, in order.
+func DiffLines(oldText string, newText string) []types.DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// Lines shared at the start and end need no alignment
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	diff := []types.DiffLine{}
+	for i := 0; i < prefix; i++ {
+		diff = append(diff, types.DiffLine{Op: "equal", Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	diff = append(diff, alignLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		oldIndex, newIndex := len(a)-suffix+i, len(b)-suffix+i
+		diff = append(diff, types.DiffLine{Op: "equal", Text: a[oldIndex], OldLine: oldIndex + 1, NewLine: newIndex + 1})
+	}
+	return diff
+}
+
+// DiffNote compares two versions of a note: the title and category as a
+// whole and the body line by line.
+func DiffNote(older *types.NoteRevision, newer *types.NoteRevision) *types.NoteDiff {
+	diff := &types.NoteDiff{
+		NoteID: newer.NoteID,
+		From:   older.Version,
+		To:     newer.Version,
+		Lines:  DiffLines(older.Note, newer.Note),
+	}
+	if older.Title != newer.Title {
+		diff.Title = &types.FieldChange{From: older.Title, To: newer.Title}
+	}
+	if older.Category != newer.Category {
+		diff.Category = &types.FieldChange{From: older.Category, To: newer.Category}
+	}
+	return diff
+}
+
+// alignLines diffs the middle part of two texts using the longest common
+// subsequence of their lines. oldOffset and newOffset are the number of lines
+// before a and b, for line numbering.
+func alignLines(a []string, b []string, oldOffset int, newOffset int) []types.DiffLine {
+	n, m := len(a), len(b)
+	diff := []types.DiffLine{}
+	if n*m > maxDiffCells {
+		for i, line := range a {
+			diff = append(diff, types.DiffLine{Op: "delete", Text: line, OldLine: oldOffset + i + 1})
+		}
+		for j, line := range b {
+			diff = append(diff, types.DiffLine{Op: "insert", Text: line, NewLine: newOffset + j + 1})
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			diff = append(diff, types.DiffLine{Op: "equal", Text: a[i], OldLine: oldOffset + i + 1, NewLine: newOffset + j + 1})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, types.DiffLine{Op: "delete", Text: a[i], OldLine: oldOffset + i + 1})
+			i++
+		default:
+			diff = append(diff, types.DiffLine{Op: "insert", Text: b[j], NewLine: newOffset + j + 1})
+			j++
+		}
+	}
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"golang-auth/types"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	equal := func(text string, oldLine, newLine int) types.DiffLine {
+		return types.DiffLine{Op: "equal", Text: text, OldLine: oldLine, NewLine: newLine}
+	}
+	insert := func(text string, newLine int) types.DiffLine {
+		return types.DiffLine{Op: "insert", Text: text, NewLine: newLine}
+	}
+	remove := func(text string, oldLine int) types.DiffLine {
+		return types.DiffLine{Op: "delete", Text: text, OldLine: oldLine}
+	}
+	tests := []struct {
+		name     string
+		old, new string
+		want     []types.DiffLine
+	}{
+		{"both empty", "", "", []types.DiffLine{}},
+		{"from empty", "", "a\nb", []types.DiffLine{insert("a", 1), insert("b", 2)}},
+		{"to empty", "a\nb", "", []types.DiffLine{remove("a", 1), remove("b", 2)}},
+		{"unchanged", "a\nb", "a\nb", []types.DiffLine{equal("a", 1, 1), equal("b", 2, 2)}},
+		{"line endings", "a\r\nb", "a\nb", []types.DiffLine{equal("a", 1, 1), equal("b", 2, 2)}},
+		{"insert in the middle", "a\nc", "a\nb\nc", []types.DiffLine{equal("a", 1, 1), insert("b", 2), equal("c", 2, 3)}},
+		{"append", "a", "a\nb", []types.DiffLine{equal("a", 1, 1), insert("b", 2)}},
+		{"delete in the middle", "a\nb\nc", "a\nc", []types.DiffLine{equal("a", 1, 1), remove("b", 2), equal("c", 3, 2)}},
+		{"replace", "a\nb\nc", "a\nx\nc", []types.DiffLine{equal("a", 1, 1), remove("b", 2), insert("x", 2), equal("c", 3, 3)}},
+		{"move", "a\nb\nc", "b\nc\na", []types.DiffLine{remove("a", 1), equal("b", 2, 1), equal("c", 3, 2), insert("a", 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffLines(tt.old, tt.new)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %+v, want %+v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesTooBig checks that changes too big to align are reported as
+// the old text removed and the new text inserted.
+func TestDiffLinesTooBig(t *testing.T) {
+	var a, b []string
+	for i := 0; i <= 2000; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	got := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if len(got) != len(a)+len(b) {
+		t.Fatalf("diff has %d lines, want %d", len(got), len(a)+len(b))
+	}
+	for i, line := range got {
+		if want := i < len(a); (line.Op == "delete") != want {
+			t.Fatalf("line %d is %+v, want the deletions first", i, line)
+		}
+	}
+}
+
+func TestDiffNote(t *testing.T) {
+	older := &types.NoteRevision{Version: 1, Title: "Plan", Category: "work", Note: "a"}
+	newer := &types.NoteRevision{Version: 2, Title: "Final plan", Category: "work", Note: "a\nb"}
+	diff := DiffNote(older, newer)
+	if diff.From != 1 || diff.To != 2 {
+		t.Errorf("diff goes from %d to %d, want 1 to 2", diff.From, diff.To)
+	}
+	if diff.Title == nil || *diff.Title != (types.FieldChange{From: "Plan", To: "Final plan"}) {
+		t.Errorf("title change is %+v", diff.Title)
+	}
+	if diff.Category != nil {
+		t.Errorf("unchanged category reported as %+v", diff.Category)
+	}
+	if len(diff.Lines) != 2 || diff.Lines[1].Op != "insert" {
+		t.Errorf("body diff is %+v, want a line inserted", diff.Lines)
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"html"
+	"strings"
+)
+
+// RenderTextHTML turns plain text into HTML that is safe to embed in a page:
+// everything is escaped, blank lines separate paragraphs and single line
+// breaks become <br>.
+func RenderTextHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>") + "</p>\n")
+	}
+	return b.String()
+}
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"log"
+	"errors"
 	"os"
 	"time"
 
@@ -12,17 +12,16 @@ import (
 var JWTSecret []byte
 
 func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
+	// The environment may set JWT_SECRET without a .env file. The server
+	// does not start without it, since the auth middleware stops there;
+	// packages that only import utils, and their tests, do not need it.
+	godotenv.Load()
 	JWTSecret = []byte(os.Getenv("JWT_SECRET"))
-	if len(JWTSecret) == 0 {
-		log.Fatal("JWT_SECRET not set in .env")
-	}
 }
 func GenerateJWT(userId string, email string, role string) (string, error) {
+	if len(JWTSecret) == 0 {
+		return "", errors.New("JWT_SECRET not set in the environment or .env")
+	}
 
 	// Set token expiration time
 	expirationtime := time.Now().Add(24 * time.Hour)
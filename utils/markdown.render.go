@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"golang-auth/types"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// markdown parses GitHub flavored markdown and gives every heading an id so
+// the table of contents can link to it. Raw HTML in the source is dropped.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// htmlPolicy is applied to all rendered markdown on top of goldmark's own
+// escaping. It keeps the usual formatting, heading anchors and the disabled
+// checkboxes of task lists, and removes scripts, event handlers and
+// javascript: links.
+var htmlPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}()
+
+// checklistLine matches a "- [ ] item" line of a plain text note.
+var checklistLine = regexp.MustCompile(`^\s*[-*+] \[([ xX])\]\s+(.*)$`)
+
+// RenderNote renders a note body for display. Markdown notes get a table of
+// contents built from their headings; plain notes are escaped and keep their
+// line breaks. Checklist items are gathered for both formats.
+func RenderNote(format string, body string) types.NoteRendering {
+	if format != types.NoteFormatMarkdown {
+		return types.NoteRendering{
+			HTML:      RenderTextHTML(body),
+			TOC:       []types.Heading{},
+			Checklist: plainChecklist(body),
+		}
+	}
+
+	source := []byte(body)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	rendering := types.NoteRendering{
+		TOC:       []types.Heading{},
+		Checklist: types.Checklist{Items: []types.ChecklistItem{}},
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		// Rendering only fails when writing to buf fails; fall back to plain text
+		rendering.HTML = RenderTextHTML(body)
+	} else {
+		rendering.HTML = htmlPolicy.Sanitize(buf.String())
+	}
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			heading := types.Heading{Level: node.Level, Text: nodeText(node, source)}
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					heading.ID = string(b)
+				}
+			}
+			rendering.TOC = append(rendering.TOC, heading)
+		case *east.TaskCheckBox:
+			block := node.Parent()
+			item := types.ChecklistItem{Text: nodeText(block, source), Checked: node.IsChecked}
+			if lines := block.Lines(); lines.Len() > 0 {
+				item.Line = bytes.Count(source[:lines.At(0).Start], []byte("\n")) + 1
+			}
+			rendering.Checklist.Items = append(rendering.Checklist.Items, item)
+		}
+		return ast.WalkContinue, nil
+	})
+	countChecklist(&rendering.Checklist)
+	return rendering
+}
+
+func plainChecklist(body string) types.Checklist {
+	checklist := types.Checklist{Items: []types.ChecklistItem{}}
+	for i, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		match := checklistLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		checklist.Items = append(checklist.Items, types.ChecklistItem{
+			Text:    strings.TrimSpace(match[2]),
+			Checked: match[1] != " ",
+			Line:    i + 1,
+		})
+	}
+	countChecklist(&checklist)
+	return checklist
+}
+
+func countChecklist(checklist *types.Checklist) {
+	checklist.Total = len(checklist.Items)
+	checklist.Done = 0
+	for _, item := range checklist.Items {
+		if item.Checked {
+			checklist.Done++
+		}
+	}
+}
+
+// nodeText returns the text of an inline node tree without its markup.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			b.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(node.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"golang-auth/types"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestHTMLPolicy feeds raw HTML to the policy directly, since goldmark
+// already drops the raw HTML of markdown notes before the policy runs.
+func TestHTMLPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		absent  []string
+		present []string
+	}{
+		{name: "script", html: `<p>hi</p><script>alert(1)</script>`, absent: []string{"<script", "alert(1)"}, present: []string{"<p>hi</p>"}},
+		{name: "onerror", html: `<img src="x.png" onerror="alert(1)">`, absent: []string{"onerror", "alert"}},
+		{name: "onclick", html: `<a href="https://example.com" onclick="alert(1)">x</a>`, absent: []string{"onclick"}, present: []string{`href="https://example.com"`}},
+		{name: "onmouseover", html: `<p onmouseover="alert(1)">x</p>`, absent: []string{"onmouseover"}, present: []string{"<p>x</p>"}},
+		{name: "javascript href", html: `<a href="javascript:alert(1)">x</a>`, absent: []string{"javascript:"}},
+		{name: "mixed case javascript href", html: `<a href="JaVaScRiPt:alert(1)">x</a>`, absent: []string{"alert"}},
+		{name: "data href", html: `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, absent: []string{"data:"}},
+		{name: "iframe", html: `<iframe src="https://example.com"></iframe>`, absent: []string{"<iframe"}},
+		{name: "style", html: `<style>body{display:none}</style><p>x</p>`, absent: []string{"<style", "display:none"}},
+		{name: "style attribute", html: `<p style="position:fixed">x</p>`, absent: []string{"style="}},
+		{name: "heading id", html: `<h2 id="setup">Setup</h2>`, present: []string{`<h2 id="setup">`}},
+		{name: "heading id with quotes", html: `<h2 id="a&quot; onclick=&quot;x">Setup</h2>`, absent: []string{`onclick="`}},
+		{name: "checkbox", html: `<input type="checkbox" checked="" disabled="">`, present: []string{`type="checkbox"`, "checked", "disabled"}},
+		{name: "text input", html: `<input type="text" value="x">`, absent: []string{`type="text"`, "value"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := htmlPolicy.Sanitize(tt.html)
+			for _, s := range tt.absent {
+				if strings.Contains(got, s) {
+					t.Errorf("Sanitize(%q) = %q, which keeps %q", tt.html, got, s)
+				}
+			}
+			for _, s := range tt.present {
+				if !strings.Contains(got, s) {
+					t.Errorf("Sanitize(%q) = %q, which lost %q", tt.html, got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderNoteMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		absent []string
+	}{
+		{name: "raw script", body: "Hello\n\n<script>alert(1)</script>", absent: []string{"<script", "alert(1)"}},
+		{name: "inline event handler", body: `<img src="x.png" onerror="alert(1)">`, absent: []string{"onerror"}},
+		{name: "raw iframe", body: `<iframe src="https://example.com"></iframe>`, absent: []string{"<iframe"}},
+		{name: "raw style", body: "<style>p{display:none}</style>\n\ntext", absent: []string{"<style"}},
+		{name: "javascript link", body: "[x](javascript:alert(1))", absent: []string{"javascript:"}},
+		{name: "javascript autolink", body: "<javascript:alert(1)>", absent: []string{`href="javascript:`}},
+		{name: "data link", body: "[x](data:text/html;base64,PHNjcmlwdD4=)", absent: []string{"data:"}},
+		{name: "data image", body: "![x](data:image/svg+xml;base64,PHN2Zz4=)", absent: []string{"data:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderNote(types.NoteFormatMarkdown, tt.body).HTML
+			for _, s := range tt.absent {
+				if strings.Contains(got, s) {
+					t.Errorf("rendering %q gave %q, which keeps %q", tt.body, got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderNoteTOC(t *testing.T) {
+	rendering := RenderNote(types.NoteFormatMarkdown, "# Plan\n\nIntro\n\n## First *step*\n\n### Done\n")
+	want := []types.Heading{
+		{Level: 1, Text: "Plan", ID: "plan"},
+		{Level: 2, Text: "First step", ID: "first-step"},
+		{Level: 3, Text: "Done", ID: "done"},
+	}
+	if !slices.Equal(rendering.TOC, want) {
+		t.Errorf("TOC is %+v, want %+v", rendering.TOC, want)
+	}
+	for _, heading := range want {
+		if !strings.Contains(rendering.HTML, `id="`+heading.ID+`"`) {
+			t.Errorf("HTML %q lost the anchor of %q", rendering.HTML, heading.Text)
+		}
+	}
+}
+
+func TestRenderNoteChecklist(t *testing.T) {
+	body := "Shopping\n\n- [x] milk\n- [ ] eggs\n- [X] bread\n- plain item\n"
+	want := []types.ChecklistItem{
+		{Text: "milk", Checked: true, Line: 3},
+		{Text: "eggs", Checked: false, Line: 4},
+		{Text: "bread", Checked: true, Line: 5},
+	}
+	for _, format := range []string{types.NoteFormatMarkdown, types.NoteFormatPlain} {
+		t.Run(format, func(t *testing.T) {
+			checklist := RenderNote(format, body).Checklist
+			if !slices.Equal(checklist.Items, want) || checklist.Done != 2 || checklist.Total != 3 {
+				t.Errorf("checklist is %+v, want %+v with 2 of 3 done", checklist, want)
+			}
+		})
+	}
+
+	html := RenderNote(types.NoteFormatMarkdown, body).HTML
+	if strings.Count(html, `type="checkbox"`) != 3 || strings.Count(html, "checked") != 2 {
+		t.Errorf("HTML %q does not keep the 3 checkboxes, 2 of them checked", html)
+	}
+}
+
+func TestRenderNotePlain(t *testing.T) {
+	body := "# Not a heading\n<script>alert(1)</script>\n\n<b>bold</b> & [link](javascript:x)"
+	rendering := RenderNote(types.NoteFormatPlain, body)
+	want := "<p># Not a heading<br>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n<p>&lt;b&gt;bold&lt;/b&gt; &amp; [link](javascript:x)</p>\n"
+	if rendering.HTML != want {
+		t.Errorf("plain note rendered as %q, want %q", rendering.HTML, want)
+	}
+	if len(rendering.TOC) != 0 {
+		t.Errorf("plain note has headings %+v", rendering.TOC)
+	}
+}
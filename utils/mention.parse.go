@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// mentionPattern finds an @ that starts a word followed by an email or a
+// name. The @ inside an email address is not the start of a word, so plain
+// addresses in the text are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.+@-])@([\p{L}\p{N}_.+-]+(?:@[\p{L}\p{N}-]+(?:\.[\p{L}\p{N}-]+)+)?)`)
+
+// Mention is a user mentioned in a text, by email or by name.
+type Mention struct {
+	Email string // Lowercased
+	Name  string // As MentionName normalizes it
+}
+
+// ParseMentions returns the @email and @name mentions of text, in the order
+// they first appear and without duplicates. A name is written without spaces,
+// so @janedoe, @jane.doe and @Jane_Doe all mention Jane Doe.
+func ParseMentions(text string) []Mention {
+	var mentions []Mention
+	seen := map[Mention]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// Punctuation ending a sentence is not part of the mention
+		token := strings.TrimRight(match[1], ".-+")
+		var mention Mention
+		if strings.Contains(token, "@") {
+			mention.Email = strings.ToLower(token)
+		} else if mention.Name = MentionName(token); mention.Name == "" {
+			continue
+		}
+		if !seen[mention] {
+			seen[mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+	return mentions
+}
+
+// MentionName normalizes a user name the way mentions refer to it: lowercased,
+// keeping only letters and digits.
+func MentionName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
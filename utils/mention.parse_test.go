@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	name := func(n string) Mention { return Mention{Name: n} }
+	email := func(e string) Mention { return Mention{Email: e} }
+	tests := []struct {
+		name string
+		text string
+		want []Mention
+	}{
+		{"name", "@jane can you look?", []Mention{name("jane")}},
+		{"email", "cc @jane@example.com", []Mention{email("jane@example.com")}},
+		{"email is lowercased", "cc @Jane@Example.COM", []Mention{email("jane@example.com")}},
+		{"plain email address", "write to a@b.com or jane.doe@example.com", nil},
+		{"inside a word", "x@jane and user@jane", nil},
+		{"start of a line", "done\n@jane", []Mention{name("jane")}},
+		{"name then full stop", "thanks @jane.", []Mention{name("jane")}},
+		{"email then full stop", "ask @jane@example.com.", []Mention{email("jane@example.com")}},
+		{"trailing punctuation", "@bob! @carol? @dave, @erin; @frank: @gina-", []Mention{name("bob"), name("carol"), name("dave"), name("erin"), name("frank"), name("gina")}},
+		{"in brackets", "(@bob) [@carol]", []Mention{name("bob"), name("carol")}},
+		{"spellings of one name", "@Jane_Doe, @jane.doe and @janedoe", []Mention{name("janedoe")}},
+		{"duplicates keep first order", "@bob @jane @bob", []Mention{name("bob"), name("jane")}},
+		{"name and email of one user", "@jane @jane@example.com", []Mention{name("jane"), email("jane@example.com")}},
+		{"lone at", "meet @ noon, @. or @@", nil},
+		{"double at", "@@jane", nil},
+		{"non-latin name", "@José", []Mention{name("josé")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Jane Doe":  "janedoe",
+		"jane.doe":  "janedoe",
+		"O'Brien 2": "obrien2",
+		"Ünal":      "ünal",
+		"  -- ":     "",
+	} {
+		if got := MentionName(name); got != want {
+			t.Errorf("MentionName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
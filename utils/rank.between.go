@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// Ranks order items by plain string comparison, so moving an item between two
+// others only changes its own rank. A rank is an integer part, whose first
+// character gives its length (a to z for 2 to 27 characters counting up, Z to
+// A for the same counting down), followed by a fraction without trailing
+// zeros. Appending keeps ranks short by incrementing the integer part;
+// inserting between two ranks extends the fraction.
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// smallestRankInteger is the lowest integer part; no rank may equal it, so
+// there is always room before any rank.
+var smallestRankInteger = "A" + strings.Repeat("0", 26)
+
+// ErrInvalidRank is returned for ranks that RankBetween did not produce, or
+// when the lower bound is not below the upper one.
+var ErrInvalidRank = errors.New("invalid rank")
+
+// RankBetween returns a rank that sorts after before and ahead of after. An
+// empty before means the start and an empty after the end.
+func RankBetween(before string, after string) (string, error) {
+	if before != "" && !validRank(before) || after != "" && !validRank(after) {
+		return "", ErrInvalidRank
+	}
+	if before != "" && after != "" && before >= after {
+		return "", ErrInvalidRank
+	}
+
+	if before == "" {
+		if after == "" {
+			return "a0", nil
+		}
+		integer := after[:rankIntegerLength(after[0])]
+		if integer == smallestRankInteger {
+			return integer + rankMidpoint("", after[len(integer):]), nil
+		}
+		if integer < after {
+			return integer, nil
+		}
+		if previous, ok := decrementRankInteger(integer); ok {
+			if previous == smallestRankInteger {
+				return previous + rankMidpoint("", ""), nil
+			}
+			return previous, nil
+		}
+		return "", ErrInvalidRank
+	}
+
+	integer := before[:rankIntegerLength(before[0])]
+	fraction := before[len(integer):]
+	if after == "" {
+		if next, ok := incrementRankInteger(integer); ok {
+			return next, nil
+		}
+		return integer + rankMidpoint(fraction, ""), nil
+	}
+	afterInteger := after[:rankIntegerLength(after[0])]
+	if integer == afterInteger {
+		return integer + rankMidpoint(fraction, after[len(afterInteger):]), nil
+	}
+	next, ok := incrementRankInteger(integer)
+	if !ok {
+		return "", ErrInvalidRank
+	}
+	if next < after {
+		return next, nil
+	}
+	return integer + rankMidpoint(fraction, ""), nil
+}
+
+// rankMidpoint returns a fraction between a and b, an empty b meaning 1.
+func rankMidpoint(a string, b string) string {
+	if b != "" {
+		// Keep the digits both fractions share
+		n := 0
+		for n < len(b) && rankDigit(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(a[min(n, len(a)):], b[n:])
+		}
+	}
+	low, high := 0, len(rankDigits)
+	if a != "" {
+		low = strings.IndexByte(rankDigits, a[0])
+	}
+	if b != "" {
+		high = strings.IndexByte(rankDigits, b[0])
+	}
+	if high-low > 1 {
+		return string(rankDigits[(low+high+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(rankDigits[low]) + rankMidpoint(rest, "")
+}
+
+// rankDigit returns the nth digit of a fraction, 0 past its end.
+func rankDigit(fraction string, n int) byte {
+	if n < len(fraction) {
+		return fraction[n]
+	}
+	return rankDigits[0]
+}
+
+// rankIntegerLength is the length of the integer part starting with head, 0
+// when head cannot start one.
+func rankIntegerLength(head byte) int {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2
+	}
+	return 0
+}
+
+// ValidRank reports whether rank is one RankBetween could have produced.
+func ValidRank(rank string) bool {
+	return rank != "" && validRank(rank)
+}
+
+// validRank reports whether rank is an integer part followed by a fraction
+// without trailing zeros.
+func validRank(rank string) bool {
+	length := rankIntegerLength(rank[0])
+	if length == 0 || len(rank) < length || rank == smallestRankInteger {
+		return false
+	}
+	for i := 1; i < len(rank); i++ {
+		if strings.IndexByte(rankDigits, rank[i]) < 0 {
+			return false
+		}
+	}
+	return len(rank) == length || rank[len(rank)-1] != rankDigits[0]
+}
+
+// incrementRankInteger returns the integer part after integer, or false when
+// integer is the largest one.
+func incrementRankInteger(integer string) (string, bool) {
+	head, digits := integer[0], []byte(integer[1:])
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digit := strings.IndexByte(rankDigits, digits[i]) + 1; digit < len(rankDigits) {
+			digits[i] = rankDigits[digit]
+			return string(head) + string(digits), true
+		}
+		digits[i] = rankDigits[0]
+	}
+	switch head {
+	case 'Z':
+		return "a0", true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digits = append(digits, rankDigits[0])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// decrementRankInteger returns the integer part before integer, or false
+// when integer is the smallest one.
+func decrementRankInteger(integer string) (string, bool) {
+	head, digits := integer[0], []byte(integer[1:])
+	last := rankDigits[len(rankDigits)-1]
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digit := strings.IndexByte(rankDigits, digits[i]) - 1; digit >= 0 {
+			digits[i] = rankDigits[digit]
+			return string(head) + string(digits), true
+		}
+		digits[i] = last
+	}
+	switch head {
+	case 'a':
+		return "Z" + string(last), true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digits = append(digits, last)
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	smallest := "A" + strings.Repeat("0", 26)
+	largest := "z" + strings.Repeat("z", 26)
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{"first rank", "", "", "a0"},
+		{"append", "a0", "", "a1"},
+		{"append carries", "az", "", "b00"},
+		{"prepend", "", "a0", "Zz"},
+		{"prepend crosses zero", "Zz", "a0", "ZzV"},
+		{"between integers", "a0", "a1", "a0V"},
+		{"between fractions", "a0", "a0V", "a0G"},
+		{"append after largest integer", largest, "", largest + "V"},
+		{"prepend before smallest integer", "", smallest + "1", smallest + "0V"},
+		{"prepend onto smallest integer", "", "A" + strings.Repeat("0", 25) + "1", smallest + "V"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RankBetween(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("RankBetween(%q, %q) returned %v", tt.before, tt.after, err)
+			}
+			if got != tt.want {
+				t.Errorf("RankBetween(%q, %q) = %q, want %q", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankBetweenInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{"equal ranks", "a0", "a0"},
+		{"reversed ranks", "a1", "a0"},
+		{"integer too short", "x", ""},
+		{"trailing zero", "a00", ""},
+		{"unknown digit", "a-", ""},
+		{"smallest integer", "A" + strings.Repeat("0", 26), ""},
+		{"invalid after", "", "b0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := RankBetween(tt.before, tt.after); err != ErrInvalidRank {
+				t.Errorf("RankBetween(%q, %q) = %q, %v, want ErrInvalidRank", tt.before, tt.after, got, err)
+			}
+		})
+	}
+}
+
+// TestRankBetweenKeepsOrder inserts at random places in a list and checks
+// that every rank stays valid and the list stays strictly sorted.
+func TestRankBetweenKeepsOrder(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var ranks []string
+	for i := 0; i < 2000; i++ {
+		at := random.Intn(len(ranks) + 1)
+		// Favour the ends and one hot spot, which make ranks grow fastest
+		switch random.Intn(4) {
+		case 0:
+			at = 0
+		case 1:
+			at = len(ranks)
+		case 2:
+			at = min(len(ranks), 1)
+		}
+		var before, after string
+		if at > 0 {
+			before = ranks[at-1]
+		}
+		if at < len(ranks) {
+			after = ranks[at]
+		}
+		rank, err := RankBetween(before, after)
+		if err != nil {
+			t.Fatalf("RankBetween(%q, %q) returned %v", before, after, err)
+		}
+		if !ValidRank(rank) || before != "" && rank <= before || after != "" && rank >= after {
+			t.Fatalf("RankBetween(%q, %q) = %q, not a valid rank between them", before, after, rank)
+		}
+		ranks = slices.Insert(ranks, at, rank)
+	}
+	if !slices.IsSorted(ranks) {
+		t.Error("ranks are out of order")
+	}
+}
+
+func TestValidRank(t *testing.T) {
+	for rank, want := range map[string]bool{
+		"":    false,
+		"a0":  true,
+		"a0V": true,
+		"b00": true,
+		"Zz":  true,
+		"a":   false,
+		"a0 ": false,
+		"a00": false,
+	} {
+		if got := ValidRank(rank); got != want {
+			t.Errorf("ValidRank(%q) = %v, want %v", rank, got, want)
+		}
+	}
+}
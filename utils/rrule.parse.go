@@ -0,0 +1,381 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRRulePeriods bounds how many days, weeks, months or years Next walks
+// through, so that rules which never match again cannot loop forever.
+const maxRRulePeriods = 100_000
+
+// RRule is a recurrence rule in the iCalendar syntax of RFC 5545. The parts
+// supported are FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, COUNT,
+// UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST. Yearly rules only take BYDAY
+// along with BYMONTH, and then apply it to each of those months.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int        // 0 means no limit
+	Until      *time.Time // Inclusive
+	UntilDate  bool       // Until is a whole day in the location of the series
+	UntilLocal bool       // Until is a time in the location of the series
+	ByDay      []RRuleDay
+	ByMonthDay []int // Negative days count from the end of the month
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// RRuleDay is an entry of BYDAY: a weekday, and for monthly and yearly rules
+// its position in the month, such as 1 for the first or -1 for the last, or
+// 0 for all of them.
+type RRuleDay struct {
+	N   int
+	Day time.Weekday
+}
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRRule reads a recurrence rule such as FREQ=WEEKLY;BYDAY=MO,TH, with or
+// without the RRULE: prefix.
+func ParseRRule(rule string) (*RRule, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+	r := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("RRULE part %q must be KEY=VALUE", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("RRULE part %s is given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, value) {
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+			r.Freq = value
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 || r.Interval > 1000 {
+				return nil, fmt.Errorf("INTERVAL must be a number from 1 to 1000")
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if err := r.parseUntil(value); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, entry := range strings.Split(value, ",") {
+				day, err := parseRRuleDay(entry)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, entry := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(entry)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY must list days from 1 to 31 or -31 to -1")
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, entry := range strings.Split(value, ",") {
+				month, err := strconv.Atoi(entry)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("BYMONTH must list months from 1 to 12")
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			day := slices.Index(rruleWeekdays, value)
+			if day < 0 {
+				return nil, fmt.Errorf("WKST must be a weekday such as MO")
+			}
+			r.WeekStart = time.Weekday(day)
+		default:
+			return nil, fmt.Errorf("RRULE part %s is not supported", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE must have a FREQ")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("RRULE cannot have both COUNT and UNTIL")
+	}
+	if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if r.Freq == "YEARLY" && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYDAY needs BYMONTH with FREQ=YEARLY")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && (r.Freq == "DAILY" || r.Freq == "WEEKLY") {
+			return nil, fmt.Errorf("BYDAY positions such as 1MO need FREQ=MONTHLY or YEARLY")
+		}
+	}
+	slices.Sort(r.ByMonth)
+	return r, nil
+}
+
+// parseUntil reads UNTIL as a date, a UTC time or a time in the location of
+// the series.
+func (r *RRule) parseUntil(value string) error {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		until, err := time.Parse(layout, value)
+		if err == nil {
+			r.Until = &until
+			r.UntilDate = layout == "20060102"
+			r.UntilLocal = layout == "20060102T150405"
+			return nil
+		}
+	}
+	return fmt.Errorf("UNTIL must be a date such as 20261231 or a time such as 20261231T170000Z")
+}
+
+func parseRRuleDay(entry string) (RRuleDay, error) {
+	if len(entry) < 2 {
+		return RRuleDay{}, fmt.Errorf("BYDAY must list weekdays such as MO or 1MO")
+	}
+	day := slices.Index(rruleWeekdays, entry[len(entry)-2:])
+	if day < 0 {
+		return RRuleDay{}, fmt.Errorf("BYDAY must list weekdays such as MO or 1MO")
+	}
+	var n int
+	if position := entry[:len(entry)-2]; position != "" {
+		var err error
+		if n, err = strconv.Atoi(position); err != nil || n == 0 || n < -5 || n > 5 {
+			return RRuleDay{}, fmt.Errorf("BYDAY positions must be from 1 to 5 or -5 to -1")
+		}
+	}
+	return RRuleDay{N: n, Day: time.Weekday(day)}, nil
+}
+
+// String writes the rule back in a canonical form.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		switch {
+		case r.UntilDate:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		case r.UntilLocal:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		default:
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = rruleWeekdays[day.Day]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+rruleWeekdays[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after after of the series that starts
+// at start, and its position in the series, start being the first. ok is
+// false once the series has ended. Occurrences keep the time of day of start
+// in its location, which should be the time zone the series follows.
+func (r *RRule) Next(start time.Time, after time.Time) (next time.Time, n int, ok bool) {
+	if start.After(after) {
+		return start, 1, true
+	}
+	until := r.until(start.Location())
+	n = 1
+	for period := 0; period < maxRRulePeriods; period++ {
+		for _, t := range r.period(start, period) {
+			if !t.After(start) {
+				continue
+			}
+			if until != nil && t.After(*until) {
+				return time.Time{}, 0, false
+			}
+			n++
+			if r.Count > 0 && n > r.Count {
+				return time.Time{}, 0, false
+			}
+			if t.After(after) {
+				return t, n, true
+			}
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// until returns the last moment of the series in location.
+func (r *RRule) until(location *time.Location) *time.Time {
+	if r.Until == nil {
+		return nil
+	}
+	u := *r.Until
+	switch {
+	case r.UntilDate:
+		u = time.Date(u.Year(), u.Month(), u.Day(), 23, 59, 59, 0, location)
+	case r.UntilLocal:
+		u = time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, location)
+	}
+	return &u
+}
+
+// period lists, in order, the occurrences of the rule in the day, week,
+// month or year that comes period intervals after the one of start.
+func (r *RRule) period(start time.Time, period int) []time.Time {
+	location := start.Location()
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, location)
+	}
+	step := period * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case "DAILY":
+		day := at(start.Year(), start.Month(), start.Day()+step)
+		if r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			days = append(days, day)
+		}
+	case "WEEKLY":
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		first := start.Day() - offset + 7*step
+		for i := 0; i < 7; i++ {
+			day := at(start.Year(), start.Month(), first+i)
+			if len(r.ByDay) == 0 && day.Weekday() == start.Weekday() || len(r.ByDay) > 0 && r.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		month := at(start.Year(), start.Month()+time.Month(step), 1)
+		days = r.monthDays(start, month.Year(), month.Month(), at)
+	case "YEARLY":
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(start, start.Year()+step, month, at)...)
+		}
+	}
+
+	if len(r.ByMonth) > 0 {
+		days = slices.DeleteFunc(days, func(day time.Time) bool { return !slices.Contains(r.ByMonth, day.Month()) })
+	}
+	return days
+}
+
+// monthDays lists the occurrences of a monthly or yearly rule in a month.
+func (r *RRule) monthDays(start time.Time, year int, month time.Month, at func(int, time.Month, int) time.Time) []time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	var days []int
+	switch {
+	case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+		// Months without the day of start are skipped, as RFC 5545 requires
+		if start.Day() <= last {
+			days = []int{start.Day()}
+		}
+	case len(r.ByDay) == 0:
+		days = monthDaysOf(r.ByMonthDay, last)
+	default:
+		days = r.weekdaysOf(year, month, last)
+		if len(r.ByMonthDay) > 0 {
+			byMonthDay := monthDaysOf(r.ByMonthDay, last)
+			days = slices.DeleteFunc(days, func(day int) bool { return !slices.Contains(byMonthDay, day) })
+		}
+	}
+	slices.Sort(days)
+	days = slices.Compact(days)
+
+	times := make([]time.Time, len(days))
+	for i, day := range days {
+		times[i] = at(year, month, day)
+	}
+	return times
+}
+
+// monthDaysOf resolves BYMONTHDAY in a month of last days.
+func monthDaysOf(byMonthDay []int, last int) []int {
+	var days []int
+	for _, day := range byMonthDay {
+		if day < 0 {
+			day = last + 1 + day
+		}
+		if day >= 1 && day <= last {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// weekdaysOf resolves BYDAY in a month of last days.
+func (r *RRule) weekdaysOf(year int, month time.Month, last int) []int {
+	firstWeekday := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	var days []int
+	for _, byDay := range r.ByDay {
+		first := 1 + (int(byDay.Day)-int(firstWeekday)+7)%7
+		var matching []int
+		for day := first; day <= last; day += 7 {
+			matching = append(matching, day)
+		}
+		switch {
+		case byDay.N == 0:
+			days = append(days, matching...)
+		case byDay.N > 0 && byDay.N <= len(matching):
+			days = append(days, matching[byDay.N-1])
+		case byDay.N < 0 && -byDay.N <= len(matching):
+			days = append(days, matching[len(matching)+byDay.N])
+		}
+	}
+	return days
+}
+
+func (r *RRule) matchesWeekday(day time.Time) bool {
+	return len(r.ByDay) == 0 || slices.ContainsFunc(r.ByDay, func(byDay RRuleDay) bool { return byDay.Day == day.Weekday() })
+}
+
+func (r *RRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return slices.Contains(monthDaysOf(r.ByMonthDay, last), day.Day())
+}
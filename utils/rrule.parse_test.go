@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{"", "must be KEY=VALUE"},
+		{"FREQ", "must be KEY=VALUE"},
+		{"FREQ=", "must be KEY=VALUE"},
+		{"INTERVAL=2", "must have a FREQ"},
+		{"FREQ=HOURLY", "FREQ must be"},
+		{"FREQ=DAILY;FREQ=WEEKLY", "given twice"},
+		{"FREQ=DAILY;INTERVAL=0", "INTERVAL must be"},
+		{"FREQ=DAILY;INTERVAL=1001", "INTERVAL must be"},
+		{"FREQ=DAILY;COUNT=-1", "COUNT must be"},
+		{"FREQ=DAILY;UNTIL=tomorrow", "UNTIL must be"},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20261231", "both COUNT and UNTIL"},
+		{"FREQ=WEEKLY;BYDAY=XX", "BYDAY must list"},
+		{"FREQ=MONTHLY;BYDAY=6MO", "BYDAY positions"},
+		{"FREQ=WEEKLY;BYDAY=1MO", "need FREQ=MONTHLY or YEARLY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", "BYMONTHDAY must list"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "BYMONTHDAY must list"},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", "cannot be used with FREQ=WEEKLY"},
+		{"FREQ=YEARLY;BYMONTH=13", "BYMONTH must list"},
+		{"FREQ=YEARLY;BYDAY=MO", "BYDAY needs BYMONTH"},
+		{"FREQ=WEEKLY;WKST=XX", "WKST must be"},
+		{"FREQ=DAILY;BYHOUR=9", "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := ParseRRule(tt.rule)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseRRule(%q) returned %v, want an error containing %q", tt.rule, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRRuleString(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;byday=mo,th", "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{"FREQ=WEEKLY;INTERVAL=1;WKST=MO", "FREQ=WEEKLY"},
+		{"FREQ=WEEKLY;WKST=SU;INTERVAL=2", "FREQ=WEEKLY;INTERVAL=2;WKST=SU"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=6", "FREQ=MONTHLY;COUNT=6;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYMONTHDAY=31,-1", "FREQ=MONTHLY;BYMONTHDAY=31,-1"},
+		{"FREQ=YEARLY;BYMONTH=11,3;BYDAY=2SU", "FREQ=YEARLY;BYDAY=2SU;BYMONTH=3,11"},
+		{"FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231"},
+		{"FREQ=DAILY;UNTIL=20261231T170000Z", "FREQ=DAILY;UNTIL=20261231T170000Z"},
+		{"FREQ=DAILY;UNTIL=20261231T170000", "FREQ=DAILY;UNTIL=20261231T170000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("ParseRRule(%q).String() = %q, want %q", tt.rule, got, tt.want)
+			}
+			again, err := ParseRRule(rule.String())
+			if err != nil {
+				t.Fatalf("parsing %q again returned %v", rule.String(), err)
+			}
+			if again.String() != rule.String() {
+				t.Errorf("round trip of %q gave %q", rule.String(), again.String())
+			}
+		})
+	}
+}
+
+func TestRRuleNext(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(value string) time.Time {
+		t.Helper()
+		d, err := time.ParseInLocation("2006-01-02 15:04", value, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []string // Every occurrence after start, in the location of start
+	}{
+		{
+			name:  "count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: date("2026-01-01 09:00"),
+			want:  []string{"2026-01-02 09:00", "2026-01-03 09:00"},
+		},
+		{
+			name:  "until date includes the whole day",
+			rule:  "FREQ=DAILY;UNTIL=20260104",
+			start: date("2026-01-01 23:00"),
+			want:  []string{"2026-01-02 23:00", "2026-01-03 23:00", "2026-01-04 23:00"},
+		},
+		{
+			name:  "until time",
+			rule:  "FREQ=DAILY;UNTIL=20260103T090000Z",
+			start: date("2026-01-01 09:00"),
+			want:  []string{"2026-01-02 09:00", "2026-01-03 09:00"},
+		},
+		{
+			name:  "interval",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5",
+			start: date("2026-01-05 08:30"),
+			want:  []string{"2026-01-08 08:30", "2026-01-19 08:30", "2026-01-22 08:30", "2026-02-02 08:30"},
+		},
+		{
+			name:  "31st skips shorter months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=4",
+			start: date("2026-01-31 12:00"),
+			want:  []string{"2026-03-31 12:00", "2026-05-31 12:00", "2026-07-31 12:00"},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			start: date("2026-01-31 12:00"),
+			want:  []string{"2026-02-28 12:00", "2026-03-31 12:00", "2026-04-30 12:00"},
+		},
+		{
+			name:  "last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=4",
+			start: date("2026-01-30 17:00"),
+			want:  []string{"2026-02-27 17:00", "2026-03-27 17:00", "2026-04-24 17:00"},
+		},
+		{
+			name:  "monthly without the day of start",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: date("2026-01-30 10:00"),
+			want:  []string{"2026-03-30 10:00", "2026-04-30 10:00"},
+		},
+		{
+			name:  "leap day",
+			rule:  "FREQ=YEARLY;COUNT=2",
+			start: date("2024-02-29 10:00"),
+			want:  []string{"2028-02-29 10:00"},
+		},
+		{
+			name:  "yearly by month and weekday",
+			rule:  "FREQ=YEARLY;BYMONTH=3,11;BYDAY=2SU;COUNT=4",
+			start: date("2026-03-08 10:00"),
+			want:  []string{"2026-11-08 10:00", "2027-03-14 10:00", "2027-11-14 10:00"},
+		},
+		{
+			name:  "keeps the local time across DST",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: time.Date(2026, time.March, 28, 9, 0, 0, 0, paris),
+			want:  []string{"2026-03-29 09:00", "2026-03-30 09:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			after := tt.start
+			for i := 0; i < len(tt.want)+1; i++ {
+				next, n, ok := rule.Next(tt.start, after)
+				if !ok {
+					break
+				}
+				if n != i+2 {
+					t.Errorf("occurrence %s is at position %d, want %d", next, n, i+2)
+				}
+				got = append(got, next.Format("2006-01-02 15:04"))
+				after = next
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("occurrences are %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRRuleNextAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule, err := ParseRRule("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.March, 28, 9, 0, 0, 0, paris)
+	next, _, _ := rule.Next(start, start)
+	if got := next.UTC().Hour(); got != 7 {
+		t.Errorf("the occurrence after clocks go forward is at %d:00 UTC, want 7:00", got)
+	}
+	if got := next.Sub(start); got != 23*time.Hour {
+		t.Errorf("the day clocks go forward lasts %s, want 23h", got)
+	}
+}
+
+// TestRRuleNextUntilLocal checks that an UNTIL without Z is read in the time
+// zone of the series rather than in UTC.
+func TestRRuleNextUntilLocal(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.January, 1, 9, 30, 0, 0, paris)
+	for rule, want := range map[string]int{
+		// 09:30 in Paris is 08:30 UTC, before 09:00 UTC
+		"FREQ=DAILY;UNTIL=20260102T090000Z": 2,
+		// but after 09:00 in Paris
+		"FREQ=DAILY;UNTIL=20260102T090000": 1,
+	} {
+		r, err := ParseRRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 1
+		for after := start; ; count++ {
+			next, _, ok := r.Next(start, after)
+			if !ok {
+				break
+			}
+			after = next
+		}
+		if count != want {
+			t.Errorf("%s has %d occurrences, want %d", rule, count, want)
+		}
+	}
+}
+
+func TestRRuleNextFromStart(t *testing.T) {
+	rule, err := ParseRRule("FREQ=DAILY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)
+	if next, n, ok := rule.Next(start, start.Add(-time.Hour)); !ok || n != 1 || !next.Equal(start) {
+		t.Errorf("Next before the start = %s, %d, %v, want the start at position 1", next, n, ok)
+	}
+	if _, _, ok := rule.Next(start, start.AddDate(0, 0, 5)); ok {
+		t.Error("Next after the last occurrence returned one")
+	}
+}